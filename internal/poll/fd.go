@@ -13,13 +13,16 @@
 // runtime scheduler.
 package poll
 
-import "errors"
+import (
+	"errors"
+	"net"
+	"os"
+)
 
 // ErrNetClosing is returned when a network descriptor is used after
-// it has been closed. Keep this string consistent because of issue
-// #4373: since historically programs have not been able to detect
-// this error, they look for the string.
-var ErrNetClosing = errors.New("use of closed network connection")
+// it has been closed. It is net.ErrClosed, so callers can test for it
+// with errors.Is the same way they do for the net package.
+var ErrNetClosing = net.ErrClosed
 
 // ErrFileClosing is returned when a file descriptor is used after it
 // has been closed.
@@ -48,3 +51,8 @@ func (e *TimeoutError) Timeout() bool { return true }
 
 // Temporary return if it is temprary error
 func (e *TimeoutError) Temporary() bool { return true }
+
+// Is reports whether target is os.ErrDeadlineExceeded, so that an
+// expired deadline can be detected with errors.Is as with the os and
+// net packages.
+func (e *TimeoutError) Is(target error) bool { return target == os.ErrDeadlineExceeded }
@@ -9,7 +9,9 @@
 package poll
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 )
 
 type fdMutex struct {
@@ -17,22 +19,49 @@ type fdMutex struct {
 	wlock sync.Mutex
 }
 
-func (fdmu *fdMutex) init() {
-	fdmu.rlock = sync.Mutex{}
-	fdmu.wlock = sync.Mutex{}
-}
-
+// readLock locks fd for reading, failing once fd is being closed.
 func (fd *FD) readLock() error {
 	fd.fdmu.rlock.Lock()
+	if atomic.LoadInt32(&fd.closing) != 0 {
+		fd.fdmu.rlock.Unlock()
+		return errClosing()
+	}
 	return nil
 }
 
+// readLockContext locks fd for reading like readLock, but gives up and
+// returns ctx.Err() once ctx is done. A lock acquired after that is
+// released right away.
+func (fd *FD) readLockContext(ctx context.Context) error {
+	if ctx.Done() == nil {
+		return fd.readLock()
+	}
+	locked := make(chan error, 1)
+	go func() { locked <- fd.readLock() }()
+	select {
+	case err := <-locked:
+		return err
+	case <-ctx.Done():
+		go func() {
+			if err := <-locked; err == nil {
+				fd.readUnlock()
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
 func (fd *FD) readUnlock() {
 	fd.fdmu.rlock.Unlock()
 }
 
+// writeLock locks fd for writing, failing once fd is being closed.
 func (fd *FD) writeLock() error {
 	fd.fdmu.wlock.Lock()
+	if atomic.LoadInt32(&fd.closing) != 0 {
+		fd.fdmu.wlock.Unlock()
+		return errClosing()
+	}
 	return nil
 }
 
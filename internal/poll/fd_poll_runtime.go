@@ -9,6 +9,7 @@
 package poll
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -80,6 +81,31 @@ func (pd *pollDesc) waitRead() error {
 	return pd.wait('r')
 }
 
+// waitReadContext waits like waitRead, but returns ctx.Err() once ctx
+// is done.
+func (pd *pollDesc) waitReadContext(ctx context.Context) error {
+	if ctx.Done() == nil {
+		return pd.waitRead()
+	}
+	if pd.runtimeCtx == nil {
+		return errors.New("waiting for unsupported file type")
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func(rc runtime.PollDesc) {
+		select {
+		case <-ctx.Done():
+			rc.Wake('r')
+		case <-stop:
+		}
+	}(pd.runtimeCtx)
+	err := pd.waitRead()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
 func (pd *pollDesc) waitWrite() error {
 	return pd.wait('w')
 }
@@ -116,8 +142,16 @@ func (fd *FD) SetWriteDeadline(t time.Time) error {
 	return setDeadlineImpl(fd, t, 'w')
 }
 
+// setDeadlineImpl sets the deadline of mode to t. The zero time
+// clears it rather than setting it in the past, and a deadline due
+// now counts as expired.
 func setDeadlineImpl(fd *FD, t time.Time, mode int) error {
-	d := time.Until(t)
+	var d time.Duration
+	if !t.IsZero() {
+		if d = time.Until(t); d == 0 {
+			d = -1
+		}
+	}
 	if fd.pd.runtimeCtx == nil {
 		return ErrNoDeadline
 	}
@@ -9,7 +9,9 @@
 package poll
 
 import (
+	"context"
 	"io"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/openfresh/gosrt/srtapi"
@@ -21,6 +23,15 @@ type FD struct {
 	// Lock sysfd and serialize access to Read and Write methods.
 	fdmu fdMutex
 
+	// closing is set once Close has been called; atomic.
+	closing int32
+
+	// readNonblock and writeNonblock are set when reads and writes
+	// are to fail with EASYNCRCV and EASYNCSND rather than wait;
+	// atomic.
+	readNonblock  int32
+	writeNonblock int32
+
 	// System file descriptor. Immutable until Close.
 	Sysfd int
 
@@ -34,7 +45,6 @@ type FD struct {
 // or "file".
 // Set pollable to true if fd should be managed by runtime netpoll.
 func (fd *FD) Init(net string, pollable bool) error {
-	fd.fdmu.init()
 	return fd.pd.init(fd)
 }
 
@@ -55,8 +65,17 @@ func (fd *FD) Close() error {
 	// the final decref will close fd.sysfd. This should happen
 	// fairly quickly, since all the I/O is non-blocking, and any
 	// attempts to block in the pollDesc will return errClosing(fd.isFile).
+	if !atomic.CompareAndSwapInt32(&fd.closing, 0, 1) {
+		return errClosing()
+	}
 	fd.pd.evict()
 
+	// Wait for the operations in progress, which the eviction made
+	// return, so that none of them uses Sysfd after destroy.
+	fd.fdmu.rlock.Lock()
+	defer fd.fdmu.rlock.Unlock()
+	fd.fdmu.wlock.Lock()
+	defer fd.fdmu.wlock.Unlock()
 	return fd.destroy()
 }
 
@@ -69,7 +88,15 @@ const maxRW = 1 << 30
 
 // Read implements io.Reader.
 func (fd *FD) Read(p []byte) (int, error) {
-	if err := fd.readLock(); err != nil {
+	return fd.ReadContext(context.Background(), p)
+}
+
+// ReadContext reads like Read, but gives up and returns ctx.Err() once
+// ctx is done, whether it is waiting for data or for another Read in
+// progress to return. Nothing is consumed then, so fd can be read
+// from again.
+func (fd *FD) ReadContext(ctx context.Context, p []byte) (int, error) {
+	if err := fd.readLockContext(ctx); err != nil {
 		return 0, err
 	}
 	defer fd.readUnlock()
@@ -87,12 +114,95 @@ func (fd *FD) Read(p []byte) (int, error) {
 	if len(p) > maxRW {
 		p = p[:maxRW]
 	}
+	// Data is only ever copied into p by a successful srt_recv, after
+	// which we return right away, so an expiring deadline can never
+	// discard bytes that were already handed over: the deadline only
+	// ends a wait in which nothing was available.
 	for {
 		n, err := srtapi.Read(fd.Sysfd, p)
 		if err != nil {
 			n = 0
 			if err == srtapi.EASYNCRCV && fd.pd.pollable() {
-				if err = fd.pd.waitRead(); err == nil {
+				if err = fd.waitRead(ctx); err == nil {
+					continue
+				}
+			}
+		}
+		err = fd.eofError(n, err)
+		return n, err
+	}
+}
+
+// SetBlocking sets whether reads and writes wait for fd to be ready.
+// Those that do not fail with EASYNCRCV and EASYNCSND instead.
+func (fd *FD) SetBlocking(read, write bool) {
+	atomic.StoreInt32(&fd.readNonblock, nonblock(read))
+	atomic.StoreInt32(&fd.writeNonblock, nonblock(write))
+}
+
+func nonblock(blocking bool) int32 {
+	if blocking {
+		return 0
+	}
+	return 1
+}
+
+// waitRead waits for fd to be readable, unless reads are set not to
+// block, or ctx is done.
+func (fd *FD) waitRead(ctx context.Context) error {
+	if atomic.LoadInt32(&fd.readNonblock) != 0 {
+		return srtapi.EASYNCRCV
+	}
+	return fd.pd.waitReadContext(ctx)
+}
+
+// waitWrite waits for fd to be writable, unless writes are set not to
+// block.
+func (fd *FD) waitWrite() error {
+	if atomic.LoadInt32(&fd.writeNonblock) != 0 {
+		return srtapi.EASYNCSND
+	}
+	return fd.pd.waitWrite()
+}
+
+// ReadNoWait reads into p what is available, without waiting for more.
+// It returns 0 and a nil error when nothing is.
+func (fd *FD) ReadNoWait(p []byte) (int, error) {
+	if err := fd.readLock(); err != nil {
+		return 0, err
+	}
+	defer fd.readUnlock()
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(p) > maxRW {
+		p = p[:maxRW]
+	}
+	n, err := srtapi.Read(fd.Sysfd, p)
+	if err != nil {
+		if err == srtapi.EASYNCRCV {
+			return 0, nil
+		}
+		n = 0
+	}
+	return n, fd.eofError(n, err)
+}
+
+// ReadMsg reads one message into p, filling mc with its control data.
+func (fd *FD) ReadMsg(p []byte, mc *srtapi.MsgCtrl) (int, error) {
+	if err := fd.readLock(); err != nil {
+		return 0, err
+	}
+	defer fd.readUnlock()
+	if err := fd.pd.prepareRead(); err != nil {
+		return 0, err
+	}
+	for {
+		n, err := srtapi.ReadMsg2(fd.Sysfd, p, mc)
+		if err != nil {
+			n = 0
+			if err == srtapi.EASYNCRCV && fd.pd.pollable() {
+				if err = fd.waitRead(context.Background()); err == nil {
 					continue
 				}
 			}
@@ -102,7 +212,44 @@ func (fd *FD) Read(p []byte) (int, error) {
 	}
 }
 
-// Write implements io.Writer.
+// ReadBuffers reads into the buffers of bufs in turn, each with one
+// srt_recv call, and truncates every buffer it used to the number of
+// bytes read into it. It waits for the first buffer only, and stops
+// as soon as nothing more is available. It returns the number of
+// buffers filled.
+func (fd *FD) ReadBuffers(bufs [][]byte) (int, error) {
+	if err := fd.readLock(); err != nil {
+		return 0, err
+	}
+	defer fd.readUnlock()
+	if err := fd.pd.prepareRead(); err != nil {
+		return 0, err
+	}
+	var n int
+	for n < len(bufs) {
+		m, err := srtapi.Read(fd.Sysfd, bufs[n])
+		if err == nil && m > 0 {
+			bufs[n] = bufs[n][:m]
+			n++
+			continue
+		}
+		if n > 0 {
+			// Report what was read; a persistent error shows up
+			// again on the next call.
+			return n, nil
+		}
+		if err == srtapi.EASYNCRCV && fd.pd.pollable() {
+			if err = fd.waitRead(context.Background()); err == nil {
+				continue
+			}
+		}
+		return 0, fd.eofError(0, err)
+	}
+	return n, nil
+}
+
+// Write implements io.Writer. It holds the write lock until all of p
+// is written, so that concurrent writes are not interleaved.
 func (fd *FD) Write(p []byte) (int, error) {
 	if err := fd.writeLock(); err != nil {
 		return 0, err
@@ -111,6 +258,12 @@ func (fd *FD) Write(p []byte) (int, error) {
 	if err := fd.pd.prepareWrite(); err != nil {
 		return 0, err
 	}
+	return fd.write(p)
+}
+
+// write writes p, waiting for room in the send buffer as needed. It
+// returns once all of p is written, or on the first error.
+func (fd *FD) write(p []byte) (int, error) {
 	var nn int
 	for {
 		max := len(p)
@@ -125,7 +278,7 @@ func (fd *FD) Write(p []byte) (int, error) {
 			return nn, err
 		}
 		if err == srtapi.EASYNCSND && fd.pd.pollable() {
-			if err = fd.pd.waitWrite(); err == nil {
+			if err = fd.waitWrite(); err == nil {
 				continue
 			}
 		}
@@ -138,9 +291,77 @@ func (fd *FD) Write(p []byte) (int, error) {
 	}
 }
 
+// WriteBuffers writes the contents of bufs, coalesced into chunks of
+// up to size bytes, each written with one srt_send call, and consumes
+// what it wrote from bufs. In message mode every chunk is one message,
+// so size must not exceed the largest message the socket takes. It
+// returns the number of bytes written.
+func (fd *FD) WriteBuffers(bufs *[][]byte, size int) (int64, error) {
+	if err := fd.writeLock(); err != nil {
+		return 0, err
+	}
+	defer fd.writeUnlock()
+	if err := fd.pd.prepareWrite(); err != nil {
+		return 0, err
+	}
+	var nn int64
+	// The chunk need not be larger than all there is to write.
+	total := 0
+	for _, b := range *bufs {
+		if total += len(b); total >= size {
+			total = size
+			break
+		}
+	}
+	chunk := make([]byte, 0, total)
+	for len(*bufs) > 0 {
+		chunk = chunk[:0]
+		for _, b := range *bufs {
+			if len(b) > size-len(chunk) {
+				b = b[:size-len(chunk)]
+			}
+			chunk = append(chunk, b...)
+			if len(chunk) == size {
+				break
+			}
+		}
+		if len(chunk) == 0 {
+			*bufs = (*bufs)[:0]
+			break
+		}
+		n, err := fd.write(chunk)
+		nn += int64(n)
+		consume(bufs, int64(n))
+		if err != nil {
+			return nn, err
+		}
+	}
+	return nn, nil
+}
+
+// consume removes data from a slice of byte slices, for writev.
+func consume(v *[][]byte, n int64) {
+	for len(*v) > 0 {
+		ln0 := int64(len((*v)[0]))
+		if ln0 > n {
+			(*v)[0] = (*v)[0][n:]
+			return
+		}
+		n -= ln0
+		*v = (*v)[1:]
+	}
+}
+
 // Accept wraps the accept network call.
 func (fd *FD) Accept() (int, syscall.Sockaddr, string, error) {
-	if err := fd.readLock(); err != nil {
+	return fd.AcceptContext(context.Background())
+}
+
+// AcceptContext accepts like Accept, but gives up and returns
+// ctx.Err() once ctx is done, whether it is waiting for a connection
+// or for another Accept in progress to return.
+func (fd *FD) AcceptContext(ctx context.Context) (int, syscall.Sockaddr, string, error) {
+	if err := fd.readLockContext(ctx); err != nil {
 		return -1, nil, "", err
 	}
 	defer fd.readUnlock()
@@ -156,7 +377,7 @@ func (fd *FD) Accept() (int, syscall.Sockaddr, string, error) {
 		switch err {
 		case srtapi.EASYNCRCV:
 			if fd.pd.pollable() {
-				if err = fd.pd.waitRead(); err == nil {
+				if err = fd.pd.waitReadContext(ctx); err == nil {
 					continue
 				}
 			}
@@ -170,6 +391,28 @@ func (fd *FD) Accept() (int, syscall.Sockaddr, string, error) {
 	}
 }
 
+// AcceptNoWait accepts a connection if one is ready, without waiting
+// for one. It returns -1 and a nil error when none is.
+func (fd *FD) AcceptNoWait() (int, syscall.Sockaddr, string, error) {
+	if err := fd.readLock(); err != nil {
+		return -1, nil, "", err
+	}
+	defer fd.readUnlock()
+	for {
+		s, rsa, errcall, err := accept(fd.Sysfd)
+		switch err {
+		case nil:
+			return s, rsa, "", nil
+		case srtapi.EASYNCRCV:
+			return -1, nil, "", nil
+		case srtapi.ECONNLOST:
+			// As in AcceptContext.
+			continue
+		}
+		return -1, nil, errcall, err
+	}
+}
+
 // WaitWrite waits until data can be read from fd.
 func (fd *FD) WaitWrite() error {
 	return fd.pd.waitWrite()
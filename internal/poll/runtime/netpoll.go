@@ -10,6 +10,7 @@ package runtime
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,23 +21,25 @@ type PollDesc interface {
 	Reset(mode int) int
 	SetDeadline(d time.Duration, mode int)
 	Unblock()
+	Wake(mode int)
 }
 
 type pollDesc struct {
 	lock    sync.Mutex // protects the following fields
 	fd      int
-	closing bool
-	seq     int // protects from stale timers and ready notifications
+	closing int32 // atomic, so that waiters can check it under rl or wl
+	rseq    int   // protects from stale read timers
 	rrdy    bool
 	rl      sync.Mutex
 	rc      *sync.Cond
 	rt      *time.Timer   // read deadline timer
-	rd      time.Duration // read deadline
+	rd      time.Duration // read deadline; atomic
+	wseq    int           // protects from stale write timers
 	wrdy    bool
 	wl      sync.Mutex
 	wc      *sync.Cond
 	wt      *time.Timer   // write deadline timer
-	wd      time.Duration // write deadline
+	wd      time.Duration // write deadline; atomic
 }
 
 // PollServerInit initialize the poller
@@ -58,8 +61,6 @@ func PollServerDescriptor() int {
 func PollOpen(fd int) (PollDesc, error) {
 	pd := pollDesc{}
 	pd.fd = fd
-	pd.closing = false
-	pd.seq++
 	pd.rl = sync.Mutex{}
 	pd.rc = sync.NewCond(&pd.rl)
 	pd.wl = sync.Mutex{}
@@ -90,50 +91,50 @@ func (pd *pollDesc) Reset(mode int) int {
 	return 0
 }
 
+// SetDeadline sets the deadline of mode, 'r', 'w' or both, to d from
+// now; 0 clears it, and a negative d expires it at once. The deadline
+// of the other mode is left running. Waiters already blocked in mode
+// are held to the new deadline, and woken at once if it is past.
 func (pd *pollDesc) SetDeadline(d time.Duration, mode int) {
 	pd.lock.Lock()
 	defer pd.lock.Unlock()
-	if pd.closing {
+	if pd.isClosing() {
 		return
 	}
-	pd.seq++ // invalidate current timers
-	// Reset current timers.
-	if pd.rt != nil {
-		pd.rt.Stop()
-		pd.rt = nil
-	}
-	if pd.wt != nil {
-		pd.wt.Stop()
-		pd.wt = nil
-	}
 	if d < 0 {
 		d = -1
 	}
-	// Setup new timers.
 	if mode == 'r' || mode == 'r'+'w' {
-		pd.rd = d
-	}
-	if mode == 'w' || mode == 'r'+'w' {
-		pd.wd = d
-	}
-	if pd.rd > 0 && pd.rd == pd.wd {
-		pd.rt = time.AfterFunc(pd.rd, func() {
-			netpollDeadline(pd, pd.seq)
-		})
-	} else {
-		seq := pd.seq
-		if pd.rd > 0 {
-			pd.rt = time.AfterFunc(pd.rd, func() {
+		pd.rseq++ // invalidate the current timer
+		if pd.rt != nil {
+			pd.rt.Stop()
+			pd.rt = nil
+		}
+		pd.setRD(d)
+		if d > 0 {
+			seq := pd.rseq
+			pd.rt = time.AfterFunc(d, func() {
 				netpollReadDeadline(pd, seq)
 			})
 		}
-		if pd.wd > 0 {
-			pd.wt = time.AfterFunc(pd.wd, func() {
+	}
+	if mode == 'w' || mode == 'r'+'w' {
+		pd.wseq++
+		if pd.wt != nil {
+			pd.wt.Stop()
+			pd.wt = nil
+		}
+		pd.setWD(d)
+		if d > 0 {
+			seq := pd.wseq
+			pd.wt = time.AfterFunc(d, func() {
 				netpollWriteDeadline(pd, seq)
 			})
 		}
 	}
-	// If we set the new deadline in the past, unblock currently pending IO if any.
+	// Wake the waiters only if the new deadline is past, so that they
+	// fail at once; otherwise they keep waiting, and the timer set
+	// above wakes them at the new deadline, if any.
 	if pd.rd < 0 {
 		netpollunblock(pd, 'r', false)
 	}
@@ -142,14 +143,17 @@ func (pd *pollDesc) SetDeadline(d time.Duration, mode int) {
 	}
 }
 
+// Unblock marks pd as closing and wakes up its waiters. Later calls
+// do nothing.
 func (pd *pollDesc) Unblock() {
 	pd.lock.Lock()
 	defer pd.lock.Unlock()
-	if pd.closing {
-		panic("runtime: unblock on closing polldesc")
+	if pd.isClosing() {
+		return
 	}
-	pd.closing = true
-	pd.seq++
+	atomic.StoreInt32(&pd.closing, 1)
+	pd.rseq++
+	pd.wseq++
 	netpollunblock(pd, 'r', false)
 	netpollunblock(pd, 'w', false)
 	if pd.rt != nil {
@@ -162,6 +166,14 @@ func (pd *pollDesc) Unblock() {
 	}
 }
 
+// Wake makes the current or next Wait for mode return as if pd were
+// ready, so that the waiter can check for a reason of its own to stop.
+// The waiter retries its I/O otherwise, which fails again if pd was
+// not ready after all.
+func (pd *pollDesc) Wake(mode int) {
+	netpollunblock(pd, mode, true)
+}
+
 func netpollready(pd *pollDesc, mode int) {
 	if mode == 'r' || mode == 'r'+'w' {
 		netpollunblock(pd, 'r', true)
@@ -171,11 +183,23 @@ func netpollready(pd *pollDesc, mode int) {
 	}
 }
 
+func (pd *pollDesc) isClosing() bool {
+	return atomic.LoadInt32(&pd.closing) != 0
+}
+
+func (pd *pollDesc) setRD(d time.Duration) {
+	atomic.StoreInt64((*int64)(&pd.rd), int64(d))
+}
+
+func (pd *pollDesc) setWD(d time.Duration) {
+	atomic.StoreInt64((*int64)(&pd.wd), int64(d))
+}
+
 func netpollcheckerr(pd *pollDesc, mode int) int {
-	if pd.closing {
+	if pd.isClosing() {
 		return 1 // errClosing
 	}
-	if (mode == 'r' && pd.rd < 0) || (mode == 'w' && pd.wd < 0) {
+	if (mode == 'r' && atomic.LoadInt64((*int64)(&pd.rd)) < 0) || (mode == 'w' && atomic.LoadInt64((*int64)(&pd.wd)) < 0) {
 		return 2 // errTimeout
 	}
 	return 0
@@ -193,7 +217,10 @@ func netpollblock(pd *pollDesc, mode int) {
 
 	c.L.Lock()
 	defer c.L.Unlock()
-	if !*rdy {
+	// Check again for closing and expired deadlines now that c.L is
+	// held: Unblock and the deadline timers broadcast under c.L after
+	// setting them, so their wakeup cannot be missed from here on.
+	if !*rdy && netpollcheckerr(pd, mode) == 0 {
 		c.Wait()
 	}
 	*rdy = false
@@ -218,31 +245,30 @@ func netpollunblock(pd *pollDesc, mode int, ioready bool) {
 func netpolldeadlineimpl(pd *pollDesc, seq int, read, write bool) {
 	pd.lock.Lock()
 	defer pd.lock.Unlock()
-	if seq != pd.seq {
-		return
-	}
 	if read {
+		if seq != pd.rseq {
+			return
+		}
 		if pd.rd <= 0 || pd.rt == nil {
 			panic("runtime: inconsistent read deadline")
 		}
-		pd.rd = -1
+		pd.setRD(-1)
 		pd.rt = nil
 		netpollunblock(pd, 'r', false)
 	}
 	if write {
-		if pd.wd <= 0 || pd.wt == nil && !read {
+		if seq != pd.wseq {
+			return
+		}
+		if pd.wd <= 0 || pd.wt == nil {
 			panic("runtime: inconsistent write deadline")
 		}
-		pd.wd = -1
+		pd.setWD(-1)
 		pd.wt = nil
 		netpollunblock(pd, 'w', false)
 	}
 }
 
-func netpollDeadline(pd *pollDesc, seq int) {
-	netpolldeadlineimpl(pd, seq, true, true)
-}
-
 func netpollReadDeadline(pd *pollDesc, seq int) {
 	netpolldeadlineimpl(pd, seq, true, false)
 }
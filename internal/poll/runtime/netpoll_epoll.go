@@ -77,7 +77,7 @@ func run() {
 
 	defer func() {
 		for s, pd := range pds {
-			if !pd.closing {
+			if !pd.isClosing() {
 				srtapi.Close(s)
 			}
 		}
@@ -120,7 +120,7 @@ func run() {
 
 	defer func() {
 		for s, pd := range pds {
-			if !pd.closing {
+			if !pd.isClosing() {
 				srtapi.Close(s)
 			}
 		}
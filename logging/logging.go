@@ -12,6 +12,7 @@ package logging
 import "C"
 import (
 	"fmt"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -22,30 +23,69 @@ import (
 // HandlerFunc logging handler function type
 type HandlerFunc func(level int, file string, line int, area string, message string)
 
-var handler HandlerFunc
+type record struct {
+	level   int
+	file    string
+	line    int
+	area    string
+	message string
+}
+
+var (
+	mu      sync.Mutex // guards handler and the installation of logHandler
+	handler HandlerFunc
+
+	// records carries log lines from the libsrt threads to deliver.
+	// libsrt holds its logger lock while calling logHandler, so the
+	// handler must not run there: a handler calling back into
+	// libsrt, even just to log, would deadlock.
+	records   = make(chan record, 1024)
+	startOnce sync.Once
+)
 
 //export logHandler
 func logHandler(opaque unsafe.Pointer, level C.int, file *C.char, line C.int, area *C.char, message *C.char) {
-	if handler != nil {
-		handler(int(level), C.GoString(file), int(line), C.GoString(area), C.GoString(message))
-	} else {
-		now := time.Now()
-		buf := fmt.Sprintf("[%v, %s:%d(%s)]{%d} %s", now, C.GoString(file), line, C.GoString(area), level, C.GoString(message))
-		println(buf)
+	r := record{int(level), C.GoString(file), int(line), C.GoString(area), C.GoString(message)}
+	select {
+	case records <- r:
+	default:
+		// The handler is not keeping up. Drop the line rather than
+		// stall the libsrt thread.
 	}
 }
 
+func deliver() {
+	for r := range records {
+		mu.Lock()
+		h := handler
+		mu.Unlock()
+		if h != nil {
+			h(r.level, r.file, r.line, r.area, r.message)
+		} else if conf.SystemConf().LogInternal() {
+			now := time.Now()
+			buf := fmt.Sprintf("[%v, %s:%d(%s)]{%d} %s", now, r.file, r.line, r.area, r.level, r.message)
+			println(buf)
+		}
+	}
+}
+
+// install routes the libsrt logs to logHandler. mu must be held.
+func install() {
+	startOnce.Do(func() { go deliver() })
+	srtapi.SetLogFlags(0 | srtapi.LogFlagDisableTime | srtapi.LogFlagDisableSeverity | srtapi.LogFlagDisableThreadname | srtapi.LogFlagDisableEOF)
+	C.srt_setloghandler(nil, (*C.SRT_LOG_HANDLER_FN)(C.logHandler_cgo))
+}
+
 // Init initialize logging function
 func Init() {
 	srtapi.SetLogLevel(conf.SystemConf().LogLevel())
 	for fa := range conf.SystemConf().LogFAs() {
 		srtapi.AddLogFA(fa)
 	}
-	NAME := C.CString("SRTLIB")
-	defer C.free(unsafe.Pointer(NAME))
-	if conf.SystemConf().LogInternal() {
-		srtapi.SetLogFlags(0 | srtapi.LogFlagDisableTime | srtapi.LogFlagDisableSeverity | srtapi.LogFlagDisableThreadname | srtapi.LogFlagDisableEOF)
-		C.srt_setloghandler(unsafe.Pointer(NAME), (*C.SRT_LOG_HANDLER_FN)(C.logHandler_cgo))
+	mu.Lock()
+	defer mu.Unlock()
+	if conf.SystemConf().LogInternal() || handler != nil {
+		install()
 	} else if logFile := conf.SystemConf().LogFile(); logFile != "" {
 		p := C.CString(logFile)
 		defer C.free(unsafe.Pointer(p))
@@ -53,7 +93,44 @@ func Init() {
 	}
 }
 
-// SetHandler set handler
+// SetHandler sets the handler of the libsrt logs. A nil h removes
+// the handler, sending the logs back where the SRT_LOGINTERNAL and
+// SRT_LOGFILE environment variables direct them. It is safe to call
+// at any time, including from a handler.
+//
+// The handler runs on a goroutine of its own, in the order the lines
+// were logged. Lines logged while it lags too far behind are dropped.
 func SetHandler(h HandlerFunc) {
+	mu.Lock()
+	defer mu.Unlock()
 	handler = h
+	if h != nil || conf.SystemConf().LogInternal() {
+		install()
+	} else {
+		C.srt_setloghandler(nil, nil)
+	}
+}
+
+// Log logs message on behalf of the Go side of the package, delivering
+// it like the libsrt logs to the handler, or printing it if the
+// SRT_LOGINTERNAL environment variable is set. It is dropped
+// otherwise.
+func Log(level int, area, message string) {
+	mu.Lock()
+	deliverable := handler != nil || conf.SystemConf().LogInternal()
+	mu.Unlock()
+	if !deliverable {
+		return
+	}
+	startOnce.Do(func() { go deliver() })
+	select {
+	case records <- record{level: level, area: area, message: message}:
+	default:
+	}
+}
+
+// SetLevel sets the most verbose level of the libsrt logs, such as
+// srtapi.LogError or srtapi.LogDebug.
+func SetLevel(level int) {
+	srtapi.SetLogLevel(level)
 }
@@ -0,0 +1,194 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNoRTT is returned by AutoLatency when no round-trip time
+	// could be measured, because no data flowed on the probe
+	// connection.
+	ErrNoRTT = errors.New("no round-trip time measured")
+
+	// ErrUnstableRTT is returned by AutoLatency when the round-trip
+	// time varied too much to derive a latency from it.
+	ErrUnstableRTT = errors.New("round-trip time too unstable")
+)
+
+// An AutoLatencyConfig controls how AutoLatency measures the
+// round-trip time and derives the latency from it. The zero value
+// uses the defaults of every field.
+type AutoLatencyConfig struct {
+	// InitialLatency is the latency of the probe connection. It
+	// defaults to 1s.
+	InitialLatency time.Duration
+
+	// Samples is the number of RTT samples taken, Interval apart.
+	// They default to 10 and 100ms.
+	Samples  int
+	Interval time.Duration
+
+	// Multiplier is the ratio of the latency to the measured RTT.
+	// It defaults to 4.
+	Multiplier float64
+
+	// MinLatency is the lowest latency set, whatever the RTT. It
+	// defaults to 120ms, the libsrt default.
+	MinLatency time.Duration
+
+	// MaxSpread is the largest tolerated difference between the
+	// highest and lowest samples, relative to their median. It
+	// defaults to 0.5.
+	MaxSpread float64
+
+	// Probe, if non-nil, runs on the probe connection while it is
+	// sampled and must return once the connection is closed. libsrt
+	// only measures the RTT while data flows, so a caller that
+	// publishes rather than receives should write something here.
+	Probe func(c net.Conn)
+}
+
+func (cfg *AutoLatencyConfig) initialLatency() time.Duration {
+	if cfg.InitialLatency > 0 {
+		return cfg.InitialLatency
+	}
+	return time.Second
+}
+
+func (cfg *AutoLatencyConfig) samples() int {
+	if cfg.Samples > 0 {
+		return cfg.Samples
+	}
+	return 10
+}
+
+func (cfg *AutoLatencyConfig) interval() time.Duration {
+	if cfg.Interval > 0 {
+		return cfg.Interval
+	}
+	return 100 * time.Millisecond
+}
+
+func (cfg *AutoLatencyConfig) multiplier() float64 {
+	if cfg.Multiplier > 0 {
+		return cfg.Multiplier
+	}
+	return 4
+}
+
+func (cfg *AutoLatencyConfig) minLatency() time.Duration {
+	if cfg.MinLatency > 0 {
+		return cfg.MinLatency
+	}
+	return 120 * time.Millisecond
+}
+
+func (cfg *AutoLatencyConfig) maxSpread() float64 {
+	if cfg.MaxSpread > 0 {
+		return cfg.MaxSpread
+	}
+	return 0.5
+}
+
+// latency derives the latency from the RTT samples, returning their
+// median as the RTT.
+func (cfg *AutoLatencyConfig) latency(samples []time.Duration) (rtt, latency time.Duration, err error) {
+	if len(samples) == 0 {
+		return 0, 0, ErrNoRTT
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rtt = sorted[len(sorted)/2]
+	if spread := sorted[len(sorted)-1] - sorted[0]; float64(spread) > cfg.maxSpread()*float64(rtt) {
+		return rtt, 0, ErrUnstableRTT
+	}
+	latency = time.Duration(cfg.multiplier() * float64(rtt))
+	if min := cfg.minLatency(); latency < min {
+		latency = min
+	}
+	// Round up to the millisecond granularity of the option.
+	latency = (latency + time.Millisecond - 1) / time.Millisecond * time.Millisecond
+	return rtt, latency, nil
+}
+
+// AutoLatency connects to the address on the named network with a
+// latency suited to the round-trip time of the path. Since the
+// latency cannot change once connected, it first dials a probe
+// connection with a conservative latency, samples its RTT, closes it,
+// and dials again with the latency set to a multiple of the RTT.
+//
+// It returns the connection and the measured RTT. If the RTT cannot
+// be measured or is unstable, it returns ErrNoRTT or ErrUnstableRTT
+// without a connection, along with the RTT measured if any, so that
+// the caller can pick a latency of its own. A nil cfg uses the
+// defaults.
+func AutoLatency(ctx context.Context, d *Dialer, network, address string, cfg *AutoLatencyConfig) (net.Conn, time.Duration, error) {
+	if cfg == nil {
+		cfg = &AutoLatencyConfig{}
+	}
+	samples, err := cfg.sample(ctx, d, network, address)
+	if err != nil {
+		return nil, 0, err
+	}
+	rtt, latency, err := cfg.latency(samples)
+	if err != nil {
+		return nil, rtt, err
+	}
+	ctx = WithOptions(ctx, Options("latency", strconv.FormatInt(int64(latency/time.Millisecond), 10)))
+	c, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, rtt, err
+	}
+	return c, rtt, nil
+}
+
+// sample dials a probe connection and returns its RTT samples, only
+// keeping those taken once ACKs have flowed.
+func (cfg *AutoLatencyConfig) sample(ctx context.Context, d *Dialer, network, address string) ([]time.Duration, error) {
+	ms := strconv.FormatInt(int64(cfg.initialLatency()/time.Millisecond), 10)
+	c, err := d.DialContext(WithOptions(ctx, Options("latency", ms)), network, address)
+	if err != nil {
+		return nil, err
+	}
+	probed := make(chan struct{})
+	if cfg.Probe != nil {
+		go func() {
+			defer close(probed)
+			cfg.Probe(c)
+		}()
+	} else {
+		close(probed)
+	}
+	defer func() {
+		c.Close()
+		<-probed
+	}()
+
+	sc := c.(*SRTConn)
+	var samples []time.Duration
+	t := time.NewTicker(cfg.interval())
+	defer t.Stop()
+	for i := 0; i < cfg.samples(); i++ {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil, mapErr(ctx.Err())
+		}
+		s, err := sc.SRTStats(false)
+		if err != nil {
+			return nil, err
+		}
+		if s.PktSentACKTotal > 0 || s.PktRecvACKTotal > 0 {
+			samples = append(samples, s.RTT())
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+var autoLatencyTests = []struct {
+	cfg     AutoLatencyConfig
+	samples []time.Duration
+	rtt     time.Duration
+	latency time.Duration
+	err     error
+}{
+	{AutoLatencyConfig{}, nil, 0, 0, ErrNoRTT},
+	{AutoLatencyConfig{}, []time.Duration{time.Millisecond}, time.Millisecond, 120 * time.Millisecond, nil},
+	{AutoLatencyConfig{}, []time.Duration{60 * time.Millisecond, 50 * time.Millisecond, 55 * time.Millisecond}, 55 * time.Millisecond, 220 * time.Millisecond, nil},
+	{AutoLatencyConfig{Multiplier: 2.5, MinLatency: 20 * time.Millisecond}, []time.Duration{10100 * time.Microsecond}, 10100 * time.Microsecond, 26 * time.Millisecond, nil},
+	{AutoLatencyConfig{}, []time.Duration{20 * time.Millisecond, 50 * time.Millisecond, 30 * time.Millisecond}, 30 * time.Millisecond, 0, ErrUnstableRTT},
+	{AutoLatencyConfig{MaxSpread: 2}, []time.Duration{20 * time.Millisecond, 50 * time.Millisecond, 30 * time.Millisecond}, 30 * time.Millisecond, 120 * time.Millisecond, nil},
+}
+
+func TestAutoLatencyConfig(t *testing.T) {
+	for i, tt := range autoLatencyTests {
+		rtt, latency, err := tt.cfg.latency(tt.samples)
+		if rtt != tt.rtt || latency != tt.latency || err != tt.err {
+			t.Errorf("#%d: got %v, %v, %v; want %v, %v, %v", i, rtt, latency, err, tt.rtt, tt.latency, tt.err)
+		}
+	}
+}
+
+func TestAutoLatency(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Serve a stream, so that the RTT gets measured.
+			go func() {
+				defer c.Close()
+				b := make([]byte, 1316)
+				for {
+					if _, err := c.Write(b); err != nil {
+						return
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}()
+		}
+	}()
+
+	cfg := &AutoLatencyConfig{Samples: 5, Interval: 50 * time.Millisecond}
+	c, rtt, err := AutoLatency(context.Background(), &Dialer{}, "srt", ln.Addr().String(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if rtt <= 0 || rtt >= 100*time.Millisecond {
+		t.Errorf("got RTT %v on loopback", rtt)
+	}
+	latency, err := srtapi.GetsockoptInt(c.(*SRTConn).fd.pfd.Sysfd, 0, srtapi.OptionLatency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latency != 120 {
+		t.Errorf("got latency %dms; want 120ms", latency)
+	}
+}
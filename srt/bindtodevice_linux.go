@@ -0,0 +1,53 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// bindUDP binds the SRT socket s to the local address lsa, if any, and
+// to the network interface ifname, if set, or with SO_REUSEADDR and
+// SO_REUSEPORT if reuse is true. libsrt 1.4 has no option for either,
+// so the UDP socket is made here, set up, bound, and handed over to
+// libsrt, which closes it with s. It returns that socket.
+func bindUDP(s, family int, lsa syscall.Sockaddr, ifname string, reuse bool) (int, error) {
+	if lsa == nil {
+		if family == syscall.AF_INET6 {
+			lsa = &syscall.SockaddrInet6{}
+		} else {
+			lsa = &syscall.SockaddrInet4{}
+		}
+	}
+	udp, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, os.NewSyscallError("socket", err)
+	}
+	if ifname != "" {
+		if err := syscall.BindToDevice(udp, ifname); err != nil {
+			syscall.Close(udp)
+			return -1, os.NewSyscallError("setsockopt", err)
+		}
+	}
+	if reuse {
+		for _, opt := range []int{syscall.SO_REUSEADDR, soReusePort} {
+			if err := syscall.SetsockoptInt(udp, syscall.SOL_SOCKET, opt, 1); err != nil {
+				syscall.Close(udp)
+				return -1, os.NewSyscallError("setsockopt", err)
+			}
+		}
+	}
+	if err := syscall.Bind(udp, lsa); err != nil {
+		syscall.Close(udp)
+		return -1, os.NewSyscallError("bind", err)
+	}
+	if err := srtapi.BindPeerOf(s, udp); err != nil {
+		syscall.Close(udp)
+		return -1, os.NewSyscallError("bind", err)
+	}
+	return udp, nil
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build !linux
+
+package srt
+
+import "syscall"
+
+func bindUDP(s, family int, lsa syscall.Sockaddr, ifname string, reuse bool) (int, error) {
+	if ifname != "" {
+		return -1, errBindToDevice
+	}
+	return -1, errReusePort
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/openfresh/gosrt/internal/poll"
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// eofMarker is the message CloseSend sends in place of the half-close
+// SRT lacks. Reads in message mode take it for the end of the stream.
+var eofMarker = []byte("\xffgosrt:closesend\xff")
+
+// flushPollInterval is how often CloseSend checks the send buffer.
+const flushPollInterval = 10 * time.Millisecond
+
+// CloseSend shuts down the sending side of a connection in message
+// mode, the way CloseWrite does a TCP connection. SRT has no such
+// half-close, so CloseSend sends a reserved message, of the 17 bytes
+// "\xffgosrt:closesend\xff", after all those written before it. The
+// reads of a peer of this package that called SetHalfClose take it for
+// the end of the stream: once the messages before it are read, Read,
+// ReadMessageCtrl and the other reads return io.EOF, every time. Any
+// other peer gets it as a message.
+//
+// CloseSend then waits until the peer has acknowledged everything in
+// the send buffer, marker included, the connection breaks, or the
+// write deadline passes, as against a peer that stopped reading.
+// Writes fail with ErrSendClosed from the call on. If the marker
+// itself cannot be written, as when writes would block or time out,
+// CloseSend returns the error and can be called again. The receiving
+// side is left open, and the connection must still be closed with
+// Close.
+//
+// In live mode, with too-late packet drop on, the marker can be
+// dropped like any message, and the peer then never sees it. The
+// other messages are ordered and reliable in file mode, which suits
+// it best. CloseSend fails for a connection that uses the buffer API,
+// whose peer gets io.EOF once the connection is closed.
+func (c *SRTConn) CloseSend() error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if c.fd.streamAPI {
+		return &OpError{Op: "closesend", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: errStreamAPI}
+	}
+	if err := c.fd.closeSend(); err != nil {
+		return &OpError{Op: "closesend", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+func (fd *netFD) closeSend() error {
+	if err := fd.notConnected(); err != nil {
+		return err
+	}
+	if !atomic.CompareAndSwapInt32(&fd.sendClosed, 0, 1) {
+		return nil
+	}
+	if _, err := fd.pfd.Write(eofMarker); err != nil {
+		// The marker is not sent: let a later call try again.
+		atomic.StoreInt32(&fd.sendClosed, 0)
+		return writeErr(err)
+	}
+	return fd.drain()
+}
+
+// drain waits until the peer has acknowledged everything in the send
+// buffer of fd, the connection breaks or is closed, or the write
+// deadline of fd passes.
+func (fd *netFD) drain() error {
+	for {
+		switch state, err := getsockoptIntFunc(fd.pfd.Sysfd, 0, srtapi.OptionState); {
+		case err != nil:
+			return classifyConnError(wrapSyscallError("getsockopt", err))
+		case state == srtapi.StatusBroken:
+			return classifyConnError(srtapi.ECONNLOST)
+		case state > srtapi.StatusBroken:
+			return classifyConnError(net.ErrClosed)
+		}
+		n, err := getsockoptIntFunc(fd.pfd.Sysfd, 0, srtapi.OptionSnddata)
+		if err != nil {
+			return classifyConnError(wrapSyscallError("getsockopt", err))
+		}
+		if n == 0 {
+			return nil
+		}
+		if d := atomic.LoadInt64(&fd.writeDeadline); d != 0 && time.Now().UnixNano() >= d {
+			return poll.ErrTimeout
+		}
+		time.Sleep(flushPollInterval)
+	}
+}
+
+// setWriteDeadline records t, the write deadline of fd, for drain.
+func (fd *netFD) setWriteDeadline(t time.Time) {
+	var d int64
+	if !t.IsZero() {
+		d = t.UnixNano()
+	}
+	atomic.StoreInt64(&fd.writeDeadline, d)
+}
+
+// SetHalfClose sets whether the reads of the connection take the
+// marker CloseSend sends for the end of the stream. It is off by
+// default, so that an application message that happens to match the
+// marker is read as such; both peers of an application that uses
+// CloseSend turn it on, before they read.
+func (c *SRTConn) SetHalfClose(on bool) {
+	if !c.ok() {
+		return
+	}
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&c.fd.halfClose, v)
+}
+
+// notWritable returns the error of notConnected, or ErrSendClosed once
+// CloseSend was called.
+func (fd *netFD) notWritable() error {
+	if err := fd.notConnected(); err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&fd.sendClosed) != 0 {
+		return ErrSendClosed
+	}
+	return nil
+}
+
+// readEOF turns the marker of CloseSend, read as the message p[:n],
+// into io.EOF, which every read returns from then on, if SetHalfClose
+// turned that on.
+func (fd *netFD) readEOF(p []byte, n int, err error) (int, error) {
+	if err != nil || fd.streamAPI || atomic.LoadInt32(&fd.halfClose) == 0 || !bytes.Equal(p[:n], eofMarker) {
+		return n, err
+	}
+	atomic.StoreInt32(&fd.eofRead, 1)
+	return 0, io.EOF
+}
+
+// readBuffersEOF does for readBuffers what readEOF does for the other
+// reads, returning the buffers filled before the marker, if any.
+func (fd *netFD) readBuffersEOF(bufs [][]byte, n int, err error) (int, error) {
+	if err != nil || fd.streamAPI || atomic.LoadInt32(&fd.halfClose) == 0 {
+		return n, err
+	}
+	for i, b := range bufs[:n] {
+		if bytes.Equal(b, eofMarker) {
+			atomic.StoreInt32(&fd.eofRead, 1)
+			if i == 0 {
+				return 0, io.EOF
+			}
+			return i, nil
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+func TestReadEOFMarker(t *testing.T) {
+	// Without SetHalfClose, the marker is a message like any other.
+	fd := &netFD{}
+	p := append([]byte(nil), eofMarker...)
+	if n, err := fd.readEOF(p, len(p), nil); n != len(p) || err != nil {
+		t.Errorf("marker without half-close: got %d, %v; want %d, nil", n, err, len(p))
+	}
+
+	fd = &netFD{halfClose: 1}
+	p = []byte("message")
+	if n, err := fd.readEOF(p, len(p), nil); n != len(p) || err != nil {
+		t.Errorf("message: got %d, %v; want %d, nil", n, err, len(p))
+	}
+	if err := fd.notReadable(); err != nil {
+		t.Errorf("before the marker: got %v; want nil", err)
+	}
+	p = append([]byte(nil), eofMarker...)
+	if n, err := fd.readEOF(p, len(p), nil); n != 0 || err != io.EOF {
+		t.Errorf("marker: got %d, %v; want 0, EOF", n, err)
+	}
+	if err := fd.notReadable(); err != io.EOF {
+		t.Errorf("after the marker: got %v; want EOF", err)
+	}
+
+	// The buffer API has no messages to mark the end with.
+	fd = &netFD{streamAPI: true, halfClose: 1}
+	if n, err := fd.readEOF(p, len(p), nil); n != len(p) || err != nil {
+		t.Errorf("buffer API: got %d, %v; want %d, nil", n, err, len(p))
+	}
+
+	fd = &netFD{halfClose: 1}
+	bufs := [][]byte{[]byte("a"), []byte("b"), append([]byte(nil), eofMarker...), []byte("c")}
+	if n, err := fd.readBuffersEOF(bufs, 3, nil); n != 2 || err != nil {
+		t.Errorf("buffers: got %d, %v; want 2, nil", n, err)
+	}
+	if err := fd.notReadable(); err != io.EOF {
+		t.Errorf("after the buffers: got %v; want EOF", err)
+	}
+}
+
+func TestSRTConnCloseSend(t *testing.T) {
+	var lc ListenConfig
+	lc.SetBufferingMode(FileMode)
+	lc.SetOption("messageapi", "true")
+	ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	var d Dialer
+	d.SetBufferingMode(FileMode)
+	d.SetOption("messageapi", "true")
+	c, err := d.Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.Fatal("accept failed")
+	}
+	defer s.Close()
+	s.(*SRTConn).SetHalfClose(true)
+
+	sent := []string{"one", "two", "three"}
+	for _, m := range sent {
+		if _, err := c.Write([]byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sc := c.(*SRTConn)
+	if err := sc.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.CloseSend(); err != nil {
+		t.Errorf("second CloseSend: %v", err)
+	}
+	if _, err := c.Write([]byte("four")); !errors.Is(err, ErrSendClosed) {
+		t.Errorf("write after CloseSend: got %v; want %v", err, ErrSendClosed)
+	}
+
+	// The receiver drains the messages sent before, then reads EOF.
+	b := make([]byte, 1500)
+	for _, want := range sent {
+		n, err := s.Read(b)
+		if err != nil || string(b[:n]) != want {
+			t.Fatalf("got %q, %v; want %q", b[:n], err, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if n, _, err := s.(*SRTConn).ReadMessageCtrl(b); n != 0 || err != io.EOF {
+			t.Errorf("read %d after the marker: got %d, %v; want 0, EOF", i, n, err)
+		}
+	}
+
+	// The other direction stays open.
+	if _, err := s.Write([]byte("reply")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Read(b); err != nil || string(b[:n]) != "reply" {
+		t.Errorf("reply: got %q, %v", b[:n], err)
+	}
+}
+
+func TestDrainWriteDeadline(t *testing.T) {
+	oldGet := getsockoptIntFunc
+	defer func() { getsockoptIntFunc = oldGet }()
+	state := srtapi.StatusConnected
+	getsockoptIntFunc = func(fd, level, opt int) (int, error) {
+		switch opt {
+		case srtapi.OptionState:
+			return state, nil
+		case srtapi.OptionSnddata:
+			return 5, nil // the peer never acknowledges
+		}
+		return oldGet(fd, level, opt)
+	}
+
+	fd := &netFD{}
+	fd.setWriteDeadline(time.Now().Add(50 * time.Millisecond))
+	done := make(chan error, 1)
+	go func() { done <- fd.drain() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("got %v; want %v", err, os.ErrDeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain ignored the write deadline")
+	}
+
+	state = srtapi.StatusBroken
+	if err := fd.drain(); !errors.Is(err, srtapi.ECONNLOST) || !errors.Is(err, ErrConnectionBroken) {
+		t.Errorf("broken: got %v; want %v", err, ErrConnectionBroken)
+	}
+	state = srtapi.StatusClosed
+	if err := fd.drain(); !errors.Is(err, net.ErrClosed) || !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("closed: got %v; want %v", err, ErrConnectionClosed)
+	}
+}
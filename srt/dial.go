@@ -32,9 +32,10 @@ type Dialer struct {
 	// When using SRT and dialing a host name with multiple IP
 	// addresses, the timeout may be divided between them.
 	//
-	// With or without a timeout, the operating system may impose
-	// its own earlier timeout. For instance, SRT timeouts are
-	// often around 3 minutes.
+	// Without a timeout, libsrt gives up on the handshake after
+	// its connection timeout, 3s unless the conntimeo option says
+	// otherwise. A longer timeout or deadline extends it, unless
+	// conntimeo is set explicitly.
 	Timeout time.Duration
 
 	// Deadline is the absolute point in time after which dials
@@ -45,24 +46,97 @@ type Dialer struct {
 
 	// LocalAddr is the local address to use when dialing an
 	// address. The address must be of a compatible type for the
-	// network being dialed.
-	// If nil, a local address is automatically chosen.
+	// network being dialed, and an IPv4 address for "srt4" or an
+	// IPv6 one for "srt6".
+	// If nil, a local address is automatically chosen. If its port
+	// is 0, a port number is chosen and reported by the LocalAddr
+	// method of the connection.
+	//
+	// The socket is bound to it before the handshake starts. A bind
+	// that fails, as for a port already in use, makes the dial fail
+	// at once with an error matching both srtapi.ESOCKFAIL and the
+	// system error, such as syscall.EADDRINUSE, rather than a
+	// handshake timeout.
 	LocalAddr net.Addr
 
-	// DualStack enables RFC 6555-compliant "Happy Eyeballs"
-	// dialing when the network is "srt" and the host in the
-	// address parameter resolves to both IPv4 and IPv6 addresses.
-	// This allows a client to tolerate networks where one address
-	// family is silently broken.
+	// InterfaceName, if set, is the name of the network interface,
+	// such as "eth1", that connections are bound to, so that they
+	// go out through it whatever address it has. It combines with
+	// LocalAddr. It is applied with SO_BINDTODEVICE, so is only
+	// supported on Linux, where it may take the CAP_NET_RAW
+	// capability; dialing fails elsewhere.
+	//
+	// Connections that are to use different interfaces, such as the
+	// links of a multi-WAN setup, each need their own Dialer.
+	InterfaceName string
+
+	// ReusePort sets SO_REUSEADDR and SO_REUSEPORT on the UDP socket
+	// of the connection, so that a Dialer with a fixed LocalAddr port
+	// can bind it again as soon as the previous connection is closed,
+	// even while libsrt still holds that socket, which it releases
+	// about a second after the close. It combines with InterfaceName,
+	// and is only supported on Linux.
+	//
+	// It only makes the bind succeed, not the handshake. While the old
+	// socket is still bound, the kernel picks one of the two for each
+	// peer by a hash of the addresses, so the replies of a peer
+	// redialed from the same port may keep reaching the old socket,
+	// and the dial times out. Such a dial must be retried, with a
+	// short Timeout, until libsrt has released the old socket.
+	//
+	// A port below 1024 still takes the privilege to bind it at every
+	// dial. A process that drops its privileges can bind a UDP socket
+	// to the port beforehand and dial through it with DialSRTOnConn,
+	// as often as needed.
+	ReusePort bool
+
+	// AutoMTU makes a dial whose handshake times out retry with half
+	// the MSS, down to 576 bytes for IPv4 and 1280 for IPv6, to get
+	// through paths, such as VPNs and tunnels, whose MTU is smaller
+	// than the MSS. In live mode, it does not go below the payload
+	// size plus 44 bytes of headers, 1360 for the default payload
+	// size of 1316, as a packet must hold a whole message; to go
+	// lower, set a smaller payload size too. Unless conntimeo is set,
+	// each attempt takes an equal share of the time left before
+	// Timeout or Deadline, or the 3s libsrt default without either,
+	// so that a long Timeout still leaves room for the smaller MSSes.
+	// The ConnectionInfo method of the connection reports the MSS
+	// that went through.
+	AutoMTU bool
+
+	// DualStack previously enabled RFC 6555 Fast Fallback
+	// support, also known as "Happy Eyeballs", in which IPv4 is
+	// tried soon if IPv6 appears to be misconfigured and
+	// hanging.
+	//
+	// Deprecated: Fast Fallback is enabled by default. To
+	// disable, set FallbackDelay to a negative value.
 	DualStack bool
 
 	// FallbackDelay specifies the length of time to wait before
-	// spawning a fallback connection, when DualStack is enabled.
+	// spawning a RFC 6555 Fast Fallback connection. That is, this
+	// is the amount of time to wait for IPv6 to succeed before
+	// assuming that IPv6 is misconfigured and falling back to
+	// IPv4.
+	//
 	// If zero, a default delay of 300ms is used.
+	// A negative value disables Fast Fallback support.
 	FallbackDelay time.Duration
 
-	// Resolver optionally specifies an alternate resolver to use.
+	// Resolver optionally specifies an alternate resolver to use,
+	// such as one made by NewResolver from a custom net.Resolver.
+	// Host names are resolved with the context of the dial, so
+	// canceling it cancels the lookup.
 	Resolver *Resolver
+
+	// RequireLiteralIP makes dials fail unless the host of the
+	// address is a literal IP address, so that no host name is
+	// ever looked up, in DNS or elsewhere, and the connection
+	// cannot be redirected through name resolution. The port may
+	// still be a service name.
+	RequireLiteralIP bool
+
+	socketConfig
 }
 
 func minNonzeroTime(a, b time.Time) time.Time {
@@ -121,6 +195,8 @@ func partialDeadline(now, deadline time.Time, addrsRemaining int) (time.Time, er
 	return now.Add(timeout), nil
 }
 
+func (d *Dialer) dualStack() bool { return d.FallbackDelay >= 0 }
+
 func (d *Dialer) fallbackDelay() time.Duration {
 	if d.FallbackDelay > 0 {
 		return d.FallbackDelay
@@ -160,6 +236,9 @@ func (r *Resolver) resolveAddrList(ctx context.Context, op, network, addr string
 	case *SRTAddr:
 		srt = hint
 		wildcard = srt.isWildcard()
+		if !srt.matchNetwork(network) {
+			return nil, &net.AddrError{Err: errLocalAddrFamily.Error(), Addr: hint.String()}
+		}
 	}
 	naddrs := addrs[:0]
 	for _, addr := range addrs {
@@ -195,7 +274,10 @@ func (r *Resolver) resolveAddrList(ctx context.Context, op, network, addr string
 // The functions JoinHostPort and SplitHostPort manipulate a pair of
 // host and port in this form.
 // When using SRT, and the host resolves to multiple IP addresses,
-// Dial will try each IP address in order until one succeeds.
+// Dial will try each IP address in order until one succeeds. On the
+// "srt" network, when the host resolves to both IPv4 and IPv6
+// addresses, the two families are raced as described for
+// Dialer.FallbackDelay, and the first handshake to complete wins.
 //
 // Examples:
 //	Dial("srt", "golang.org:1024")
@@ -217,7 +299,9 @@ func Dial(network, address string) (net.Conn, error) {
 
 // DialTimeout acts like Dial but takes a timeout.
 //
-// The timeout includes name resolution, if required.
+// The timeout includes name resolution, if required, and the SRT
+// handshake, which libsrt otherwise gives up on after 3s. It behaves
+// exactly like Dialer{Timeout: timeout}.Dial(network, address).
 // When using SRT, and the host in the address parameter resolves to
 // multiple IP addresses, the timeout is spread over each consecutive
 // dial, such that each is given an appropriate fraction of the time
@@ -230,6 +314,40 @@ func DialTimeout(network, address string, timeout time.Duration) (net.Conn, erro
 	return d.Dial(network, address)
 }
 
+// interfaceNameContextKey is the type of contextKeys used for the
+// InterfaceName of a Dialer or ListenConfig.
+type interfaceNameContextKey struct{}
+
+func interfaceNameValue(ctx context.Context) string {
+	name, _ := ctx.Value(interfaceNameContextKey{}).(string)
+	return name
+}
+
+// reusePortContextKey is the type of contextKeys used for the
+// ReusePort of a Dialer.
+type reusePortContextKey struct{}
+
+func reusePortValue(ctx context.Context) bool {
+	on, _ := ctx.Value(reusePortContextKey{}).(bool)
+	return on
+}
+
+// checkLiteralIP returns an error unless the host of address is a
+// literal IP address, for RequireLiteralIP.
+func checkLiteralIP(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if parseIPv4(host) != nil {
+		return nil
+	}
+	if ip, _ := parseIPv6(host, true); ip != nil {
+		return nil
+	}
+	return &net.AddrError{Err: errNotLiteralIP.Error(), Addr: host}
+}
+
 // dialParam contains a Dial's parameters and configuration.
 type dialParam struct {
 	Dialer
@@ -266,14 +384,8 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 	if ctx == nil {
 		panic("nil context")
 	}
-	deadline := d.deadline(ctx, time.Now())
-	if !deadline.IsZero() {
-		if d, ok := ctx.Deadline(); !ok || deadline.Before(d) {
-			subCtx, cancel := context.WithDeadline(ctx, deadline)
-			defer cancel()
-			ctx = subCtx
-		}
-	}
+	ctx, cancel := d.dialContext(ctx)
+	defer cancel()
 
 	// Shadow the nettrace (if any) during resolve so Connect events don't fire for DNS lookups.
 	resolveCtx := ctx
@@ -284,6 +396,11 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 		resolveCtx = context.WithValue(resolveCtx, nettrace.TraceKey{}, &shadow)
 	}
 
+	if d.RequireLiteralIP {
+		if err := checkLiteralIP(address); err != nil {
+			return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+	}
 	addrs, err := d.resolver().resolveAddrList(resolveCtx, "dial", network, address, d.LocalAddr)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
@@ -296,7 +413,7 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 	}
 
 	var primaries, fallbacks addrList
-	if d.DualStack && network == "srt" {
+	if d.dualStack() && network == "srt" {
 		primaries, fallbacks = addrs.partition(isIPv4)
 	} else {
 		primaries = addrs
@@ -315,6 +432,58 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 	return c, nil
 }
 
+// DialSRTAddr connects to raddr, an address resolved beforehand, as
+// by ResolveSRTAddr, on the named network. It never looks a host up,
+// which spares the lookups and their allocations to code that dials
+// the same address over and over, as when redialing lost connections.
+// The settings of d apply as with DialContext; there being a single
+// address, FallbackDelay does not.
+func (d *Dialer) DialSRTAddr(ctx context.Context, network string, raddr *SRTAddr) (*SRTConn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	switch network {
+	case "srt", "srt4", "srt6":
+	default:
+		return nil, &OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: raddr.opAddr(), Err: net.UnknownNetworkError(network)}
+	}
+	if raddr == nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: nil, Err: errMissingAddress}
+	}
+	ctx, cancel := d.dialContext(ctx)
+	defer cancel()
+
+	dp := &dialParam{
+		Dialer:  *d,
+		network: network,
+		address: raddr.String(),
+	}
+	c, err := dialSingle(ctx, dp, raddr)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*SRTConn), nil
+}
+
+// dialContext returns ctx with the settings of d that apply to each
+// connection attempt, and its deadline, if any.
+func (d *Dialer) dialContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = d.context(ctx)
+	if d.InterfaceName != "" {
+		ctx = context.WithValue(ctx, interfaceNameContextKey{}, d.InterfaceName)
+	}
+	if d.ReusePort {
+		ctx = context.WithValue(ctx, reusePortContextKey{}, true)
+	}
+	deadline := d.deadline(ctx, time.Now())
+	if !deadline.IsZero() {
+		if d, ok := ctx.Deadline(); !ok || deadline.Before(d) {
+			return context.WithDeadline(ctx, deadline)
+		}
+	}
+	return ctx, func() {}
+}
+
 // dialParallel races two copies of dialSerial, giving the first a
 // head start. It returns the first established connection and
 // closes the others. Otherwise it returns an error from the first
@@ -451,7 +620,11 @@ func dialSingle(ctx context.Context, dp *dialParam, ra net.Addr) (c net.Conn, er
 	switch ra := ra.(type) {
 	case *SRTAddr:
 		la, _ := la.(*SRTAddr)
-		c, err = dialSRT(ctx, dp.network, la, ra)
+		if dp.AutoMTU {
+			c, err = dialSRTAutoMTU(ctx, dp.network, la, ra)
+		} else {
+			c, err = dialSRT(ctx, dp.network, la, ra)
+		}
 	default:
 		return nil, &OpError{Op: "dial", Net: dp.network, Source: la, Addr: ra, Err: &net.AddrError{Err: "unexpected address type", Addr: dp.address}}
 	}
@@ -461,6 +634,126 @@ func dialSingle(ctx context.Context, dp *dialParam, ra net.Addr) (c net.Conn, er
 	return c, nil
 }
 
+// ListenConfig contains options for listening to an address.
+type ListenConfig struct {
+	// Backlog is the number of connections libsrt completes and
+	// queues for Accept. Callers beyond it are rejected with a
+	// RejectError of reason RejectBacklog. Listeners facing bursts
+	// of callers, such as fleets reconnecting after an outage,
+	// should size it for the burst. If zero, the system default,
+	// the maximum listen backlog for TCP, is used.
+	Backlog int
+
+	// InterfaceName, if set, is the name of the network interface,
+	// such as "eth1", that the listener is bound to, so that it
+	// only accepts callers whose packets arrive through it. As
+	// for a Dialer, it is applied with SO_BINDTODEVICE, so is only
+	// supported on Linux; listening fails elsewhere.
+	//
+	// Note that Linux routes packets to a local address through
+	// the loopback interface, whatever interface has the address.
+	InterfaceName string
+
+	// OnAccept, if non-nil, is called for every connection the
+	// listener accepts, before Accept returns it, with the address
+	// and stream ID of the caller. The key it returns is kept as
+	// the RouteKey of the connection, so that callers that set no
+	// stream ID, such as encoders sending to a shared port, can be
+	// told apart, and their reconnects correlated, by their
+	// address. It must not block.
+	OnAccept func(raddr *SRTAddr, streamID string) string
+
+	// IPv6Only, if non-nil, sets whether a listener on an IPv6
+	// socket, such as one on "[::]:port" or a wildcard address of
+	// the "srt" network, takes IPv6 callers only, or IPv4 ones too,
+	// as IPv4-mapped addresses. It is SRTO_IPV6ONLY, applied before
+	// the socket binds; listeners on IPv4 sockets ignore it. If nil,
+	// the system default applies: net.ipv6.bindv6only on Linux,
+	// which is off unless changed, so that a single listener serves
+	// both families.
+	IPv6Only *bool
+
+	// RecvDropLimit, if set, is applied to every connection the
+	// listener accepts, as by SetRecvDropLimit, so that their reads
+	// fail once their receivers drop more packets than it allows.
+	RecvDropLimit DropLimit
+
+	// IdleTimeout, if non-zero, is applied to every connection the
+	// listener accepts, as by SetIdleTimeout, so that those that
+	// receive no data for that long are closed. Connections the
+	// application takes over opt out with SetIdleTimeout(0).
+	IdleTimeout time.Duration
+
+	socketConfig
+}
+
+// backlogContextKey is the type of contextKeys used for the Backlog
+// of a ListenConfig.
+type backlogContextKey struct{}
+
+// ipv6OnlyContextKey is the type of contextKeys used for the IPv6Only
+// setting of a ListenConfig.
+type ipv6OnlyContextKey struct{}
+
+func ipv6OnlyValue(ctx context.Context) (on, ok bool) {
+	on, ok = ctx.Value(ipv6OnlyContextKey{}).(bool)
+	return
+}
+
+func backlogValue(ctx context.Context) int {
+	if backlog, _ := ctx.Value(backlogContextKey{}).(int); backlog > 0 {
+		return backlog
+	}
+	return listenerBacklog
+}
+
+// Listen announces on the local network address.
+//
+// See func ListenContext for a description of the network and address
+// parameters.
+func (lc *ListenConfig) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	ctx = lc.context(ctx)
+	if lc.Backlog > 0 {
+		ctx = context.WithValue(ctx, backlogContextKey{}, lc.Backlog)
+	}
+	if lc.InterfaceName != "" {
+		ctx = context.WithValue(ctx, interfaceNameContextKey{}, lc.InterfaceName)
+	}
+	if lc.OnAccept != nil {
+		ctx = context.WithValue(ctx, onAcceptContextKey{}, lc.OnAccept)
+	}
+	if lc.IPv6Only != nil {
+		ctx = context.WithValue(ctx, ipv6OnlyContextKey{}, *lc.IPv6Only)
+	}
+	if lc.RecvDropLimit != (DropLimit{}) {
+		if err := lc.RecvDropLimit.validate(); err != nil {
+			return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		ctx = context.WithValue(ctx, recvDropLimitContextKey{}, lc.RecvDropLimit)
+	}
+	if lc.IdleTimeout != 0 {
+		if lc.IdleTimeout < 0 {
+			return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: negativeIdleTimeout(lc.IdleTimeout)}
+		}
+		ctx = context.WithValue(ctx, idleTimeoutContextKey{}, lc.IdleTimeout)
+	}
+	addrs, err := DefaultResolver.resolveAddrList(ctx, "listen", network, address, nil)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	var l net.Listener
+	switch la := addrs.first(isIPv4).(type) {
+	case *SRTAddr:
+		l, err = listenSRT(ctx, network, la)
+	default:
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: la, Err: &net.AddrError{Err: "unexpected address type", Addr: address}}
+	}
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: addrs.first(isIPv4), Err: err} // l is non-nil interface containing nil pointer
+	}
+	return l, nil
+}
+
 // Listen announces on the local network address.
 func Listen(network, address string) (net.Listener, error) {
 	return ListenContext(context.Background(), network, address)
@@ -485,19 +778,6 @@ func Listen(network, address string) (net.Listener, error) {
 // See func Dial for a description of the network and address
 // parameters.
 func ListenContext(ctx context.Context, network, address string) (net.Listener, error) {
-	addrs, err := DefaultResolver.resolveAddrList(ctx, "listen", network, address, nil)
-	if err != nil {
-		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
-	}
-	var l net.Listener
-	switch la := addrs.first(isIPv4).(type) {
-	case *SRTAddr:
-		l, err = listenSRT(ctx, network, la)
-	default:
-		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: la, Err: &net.AddrError{Err: "unexpected address type", Addr: address}}
-	}
-	if err != nil {
-		return nil, err // l is non-nil interface containing nil pointer
-	}
-	return l, nil
+	var lc ListenConfig
+	return lc.Listen(ctx, network, address)
 }
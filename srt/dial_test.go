@@ -9,15 +9,19 @@ package srt
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"runtime"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/openfresh/gosrt/internal/poll"
 	"github.com/openfresh/gosrt/internal/testenv"
+	"github.com/openfresh/gosrt/srtapi"
 )
 
 var prohibitionaryDialArgTests = []struct {
@@ -431,6 +435,22 @@ func TestDialerFallbackDelay(t *testing.T) {
 	}
 }
 
+func TestDialerDualStackDefault(t *testing.T) {
+	for _, tt := range []struct {
+		delay time.Duration
+		want  bool
+	}{
+		{0, true},
+		{200 * time.Millisecond, true},
+		{-1, false},
+	} {
+		d := &Dialer{FallbackDelay: tt.delay}
+		if got := d.dualStack(); got != tt.want {
+			t.Errorf("FallbackDelay %v: dualStack() = %v; want %v", tt.delay, got, tt.want)
+		}
+	}
+}
+
 func TestDialParallelSpuriousConnection(t *testing.T) {
 	if testing.Short() {
 		t.Skipf("skipping test: not supported yet")
@@ -563,3 +583,436 @@ func TestDialListenerAddr(t *testing.T) {
 	}
 	c.Close()
 }
+
+func TestSetPeerIdleTimeout(t *testing.T) {
+	var lc ListenConfig
+	if err := lc.SetPeerIdleTimeout(time.Microsecond); err == nil {
+		t.Fatal("SetPeerIdleTimeout(1µs) succeeded; want error")
+	}
+	if err := lc.SetPeerIdleTimeout(2 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+
+	var d Dialer
+	if err := d.SetPeerIdleTimeout(3 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// Options carried by the context win over those of the Dialer.
+	ctx := WithOptions(context.Background(), Options("peeridletimeo", "2500"))
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tt := range []struct {
+		fd   *netFD
+		want int
+	}{
+		{ln.(*SRTListener).fd, 2000},
+		{c.(*SRTConn).fd, 2500},
+	} {
+		ms, err := srtapi.GetsockoptInt(tt.fd.pfd.Sysfd, 0, srtapi.OptionPeeridletimeo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ms != tt.want {
+			t.Errorf("got peer idle timeout %dms; want %dms", ms, tt.want)
+		}
+	}
+}
+
+func TestDialerLocalAddr(t *testing.T) {
+	ln, err := Listen("srt4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	// Pick a port that is likely to be free.
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	pc.Close()
+	for _, laddr := range []*SRTAddr{
+		{IP: net.IPv4(127, 0, 0, 1)},
+		{IP: net.IPv4(127, 0, 0, 1), Port: port},
+	} {
+		d := &Dialer{LocalAddr: laddr}
+		c, err := d.Dial("srt4", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		la := c.LocalAddr().(*SRTAddr)
+		c.Close()
+		if !la.IP.Equal(laddr.IP) || la.Port == 0 || laddr.Port != 0 && la.Port != laddr.Port {
+			t.Errorf("got local address %v; want %v", la, laddr)
+		}
+	}
+
+	for _, tt := range []struct {
+		network string
+		laddr   *SRTAddr
+	}{
+		{"srt4", &SRTAddr{IP: net.IPv6loopback}},
+		{"srt6", &SRTAddr{IP: net.IPv4(127, 0, 0, 1)}},
+	} {
+		d := &Dialer{LocalAddr: tt.laddr}
+		c, err := d.Dial(tt.network, ln.Addr().String())
+		if err == nil {
+			c.Close()
+			t.Errorf("%s from %v succeeded; want error", tt.network, tt.laddr)
+			continue
+		}
+		var ae *net.AddrError
+		if !errors.As(err, &ae) || ae.Err != errLocalAddrFamily.Error() {
+			t.Errorf("%s from %v: got %v; want %v", tt.network, tt.laddr, err, errLocalAddrFamily)
+		}
+	}
+}
+
+func TestDialerLocalAddrInUse(t *testing.T) {
+	ln, err := newLocalListener("srt4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	laddr := &SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: pc.LocalAddr().(*net.UDPAddr).Port}
+
+	d := &Dialer{LocalAddr: laddr, Timeout: 5 * time.Second}
+	start := time.Now()
+	c, err := d.Dial("srt4", ln.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatalf("dial from %v, which is in use, succeeded", laddr)
+	}
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("got %v; want %v", err, syscall.EADDRINUSE)
+	}
+	var hte *HandshakeTimeoutError
+	if errors.As(err, &hte) {
+		t.Errorf("got handshake timeout %v; want bind error", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("dial took %v; want it to fail at bind", d)
+	}
+
+	// A dial canceled beforehand must not get as far as the bind.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if c, err := d.DialContext(ctx, "srt4", ln.Addr().String()); err == nil {
+		c.Close()
+		t.Fatal("canceled dial succeeded")
+	} else if errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("canceled dial got %v; want cancelation", err)
+	}
+}
+
+func TestDialerRequireLiteralIP(t *testing.T) {
+	origTestHookLookupIP := testHookLookupIP
+	defer func() { testHookLookupIP = origTestHookLookupIP }()
+	lookups := 0
+	testHookLookupIP = func(ctx context.Context, fn func(context.Context, string) ([]net.IPAddr, error), host string) ([]net.IPAddr, error) {
+		lookups++
+		return fn(ctx, host)
+	}
+
+	d := &Dialer{RequireLiteralIP: true}
+	for _, addr := range []string{"localhost:5000", "example.com:5000", ":5000"} {
+		c, err := d.Dial("srt", addr)
+		if err == nil {
+			c.Close()
+			t.Errorf("dial to %s succeeded", addr)
+			continue
+		}
+		if perr := parseDialError(err); perr != nil {
+			t.Error(perr)
+		}
+		if !strings.Contains(err.Error(), errNotLiteralIP.Error()) {
+			t.Errorf("dial to %s: got %v; want %v", addr, err, errNotLiteralIP)
+		}
+	}
+	if lookups != 0 {
+		t.Errorf("%d host name lookups; want none", lookups)
+	}
+
+	ln, err := newLocalListener("srt4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	c, err := d.Dial("srt4", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestDialerInterfaceName(t *testing.T) {
+	ln, err := newLocalListener("srt4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	d := &Dialer{InterfaceName: "gosrt-nonexistent"}
+	c, err := d.Dial("srt4", ln.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatal("dial through nonexistent interface succeeded")
+	}
+	if runtime.GOOS != "linux" {
+		if !errors.Is(err, errBindToDevice) {
+			t.Fatalf("got %v; want %v", err, errBindToDevice)
+		}
+		return
+	}
+	if errors.Is(err, syscall.EPERM) {
+		t.Skip("SO_BINDTODEVICE not permitted")
+	}
+	if !errors.Is(err, syscall.ENODEV) {
+		t.Fatalf("got %v; want %v", err, syscall.ENODEV)
+	}
+
+	ifi := loopbackInterface()
+	if ifi == nil {
+		t.Skip("no loopback interface")
+	}
+	d = &Dialer{InterfaceName: ifi.Name}
+	c, err = d.Dial("srt4", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestDialerReusePort(t *testing.T) {
+	ln, err := newLocalListener("srt4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	laddr := &SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: pc.LocalAddr().(*net.UDPAddr).Port}
+	pc.Close()
+
+	// The replies to a redial may reach the old socket until libsrt
+	// releases it, so each dial is retried, as the doc says to.
+	d := &Dialer{LocalAddr: laddr, ReusePort: true, Timeout: time.Second}
+	for i := 0; i < 3; i++ {
+		var c net.Conn
+		var err error
+		for try := 0; try < 5; try++ {
+			c, err = d.Dial("srt4", ln.Addr().String())
+			if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+				break
+			}
+		}
+		if runtime.GOOS != "linux" {
+			if !errors.Is(err, errReusePort) {
+				t.Fatalf("got %v; want %v", err, errReusePort)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("dial #%d from %v: %v", i, laddr, err)
+		}
+		if a := c.LocalAddr().(*SRTAddr); a.Port != laddr.Port {
+			t.Errorf("dial #%d from port %d; want %d", i, a.Port, laddr.Port)
+		}
+		// Dial again at once, while libsrt may still hold the port.
+		c.Close()
+	}
+}
+
+func TestSetMSS(t *testing.T) {
+	var lc ListenConfig
+	for _, mss := range []int{0, minMSS - 1, maxMSS + 1} {
+		if err := lc.SetMSS(mss); err == nil {
+			t.Errorf("SetMSS(%d) succeeded; want error", mss)
+		}
+	}
+	if err := lc.SetMSS(1360); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			accepted <- nil
+			return
+		}
+		accepted <- c
+	}()
+
+	c, err := Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := <-accepted
+	if sc == nil {
+		t.Fatal("accept failed")
+	}
+	defer sc.Close()
+	for _, c := range []net.Conn{c, sc} {
+		mss, err := c.(*SRTConn).MSS()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mss != 1360 {
+			t.Errorf("got MSS %d; want 1360", mss)
+		}
+	}
+}
+
+func TestDialerDialSRTAddr(t *testing.T) {
+	origTestHookLookupIP, origTestHookDialSRT := testHookLookupIP, testHookDialSRT
+	defer func() { testHookLookupIP, testHookDialSRT = origTestHookLookupIP, origTestHookDialSRT }()
+	lookups := 0
+	testHookLookupIP = func(ctx context.Context, fn func(context.Context, string) ([]net.IPAddr, error), host string) ([]net.IPAddr, error) {
+		lookups++
+		return fn(ctx, host)
+	}
+	var dialed *SRTAddr
+	var hasDeadline bool
+	testHookDialSRT = func(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+		dialed = raddr
+		_, hasDeadline = ctx.Deadline()
+		return newSRTConn(&netFD{net: network, raddr: raddr}), nil
+	}
+
+	raddr := &SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}
+	d := &Dialer{Timeout: time.Minute}
+	c, err := d.DialSRTAddr(context.Background(), "srt", raddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dialed != raddr {
+		t.Errorf("dialed %v; want %v", dialed, raddr)
+	}
+	if !hasDeadline {
+		t.Error("Timeout of the Dialer not applied")
+	}
+	if c.RemoteAddr() != raddr {
+		t.Errorf("got remote address %v; want %v", c.RemoteAddr(), raddr)
+	}
+	if lookups != 0 {
+		t.Errorf("%d host name lookups; want none", lookups)
+	}
+
+	for _, tt := range []struct {
+		network string
+		raddr   *SRTAddr
+		want    error
+	}{
+		{"udp", raddr, net.UnknownNetworkError("udp")},
+		{"srt", nil, errMissingAddress},
+	} {
+		_, err := d.DialSRTAddr(context.Background(), tt.network, tt.raddr)
+		if perr := parseDialError(err); perr != nil {
+			t.Error(perr)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want.Error()) {
+			t.Errorf("DialSRTAddr(%q, %v): got %v; want %v", tt.network, tt.raddr, err, tt.want)
+		}
+	}
+}
+
+// BenchmarkDialResolution compares dialing a host name, looked up on
+// every dial, with dialing the address it resolves to. The dial itself
+// is stubbed out, leaving the cost of the resolution.
+func BenchmarkDialResolution(b *testing.B) {
+	origTestHookDialSRT := testHookDialSRT
+	defer func() { testHookDialSRT = origTestHookDialSRT }()
+	testHookDialSRT = func(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+		return newSRTConn(&netFD{net: network, raddr: raddr}), nil
+	}
+
+	var d Dialer
+	b.Run("Resolve", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.DialContext(context.Background(), "srt4", "localhost:5000"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Resolved", func(b *testing.B) {
+		raddr, err := ResolveSRTAddr("srt4", "localhost:5000")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.DialSRTAddr(context.Background(), "srt4", raddr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A DropLimit bounds the packets the receiver of a connection may drop,
+// as counted by PktRcvDropTotal of SRTStats, at Packets per Window.
+// In live mode libsrt drops what it cannot deliver in time, as when
+// the receive buffer overflows because the reader falls behind, and
+// the data is silently lost. A recording ingest may rather have Read
+// fail, so that it reconnects, or at least knows. The zero DropLimit
+// sets no limit.
+type DropLimit struct {
+	Packets int64
+	Window  time.Duration
+}
+
+func (l DropLimit) validate() error {
+	if l == (DropLimit{}) {
+		return nil
+	}
+	if l.Packets < 0 || l.Window <= 0 {
+		return errors.New("invalid drop limit of " + strconv.FormatInt(l.Packets, 10) + " packets per " + l.Window.String())
+	}
+	return nil
+}
+
+// DropLimitError is the error of the reads of a connection whose
+// receiver dropped more packets than its DropLimit allows.
+type DropLimitError struct {
+	Dropped int64         // packets dropped over Window
+	Window  time.Duration // the time they were dropped in
+	Limit   DropLimit
+}
+
+func (e *DropLimitError) Error() string {
+	return "receiver dropped " + strconv.FormatInt(e.Dropped, 10) + " packets in " + e.Window.String() +
+		", over the limit of " + strconv.FormatInt(e.Limit.Packets, 10) + " per " + e.Limit.Window.String()
+}
+
+// Timeout reports whether the error is a timeout; it is not.
+func (e *DropLimitError) Timeout() bool { return false }
+
+// Temporary reports whether the error is temporary; it is not.
+func (e *DropLimitError) Temporary() bool { return false }
+
+// dropMonitor checks the drops of a connection against its DropLimit.
+// The count is taken once per window, on a read, so it costs a call to
+// libsrt per window rather than per read.
+type dropMonitor struct {
+	limit DropLimit
+
+	mu    sync.Mutex
+	start time.Time // of the current window
+	base  int64     // PktRcvDropTotal at start
+	err   error     // set once the limit is exceeded
+}
+
+func newDropMonitor(s int, limit DropLimit) *dropMonitor {
+	m := &dropMonitor{limit: limit, start: time.Now()}
+	if mon, err := srtapi.Bistats(s, false, true); err == nil {
+		m.base = int64(mon.PktRcvDropTotal)
+	}
+	return m
+}
+
+// check returns a *DropLimitError once the drops on s in a window went
+// over the limit, and every time after that.
+func (m *dropMonitor) check(s int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	now := time.Now()
+	elapsed := now.Sub(m.start)
+	if elapsed < m.limit.Window {
+		return nil
+	}
+	mon, err := srtapi.Bistats(s, false, true)
+	if err != nil {
+		// The read meets the error too.
+		return nil
+	}
+	total := int64(mon.PktRcvDropTotal)
+	dropped := total - m.base
+	// Reads may be further apart than the window; hold the drops of
+	// the time since the last count to the limit of as much time.
+	if float64(dropped) > float64(m.limit.Packets)*float64(elapsed)/float64(m.limit.Window) {
+		m.err = &DropLimitError{Dropped: dropped, Window: elapsed, Limit: m.limit}
+		return m.err
+	}
+	m.start, m.base = now, total
+	return nil
+}
+
+// SetRecvDropLimit sets the limit to the packets the receiver of the
+// connection may drop. Once they go over it, every read fails with a
+// *DropLimitError, wrapped in an *OpError; the connection is left
+// open, for the caller to close. Drops are counted from the call on,
+// and checked at most once per window, on a read: a reader that stops
+// reading only learns of them when it reads again. The zero DropLimit
+// removes the limit.
+func (c *SRTConn) SetRecvDropLimit(l DropLimit) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if err := l.validate(); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	c.fd.setDropLimit(l)
+	return nil
+}
+
+// recvDropLimitContextKey is the type of contextKeys used for the
+// RecvDropLimit of a ListenConfig.
+type recvDropLimitContextKey struct{}
+
+func recvDropLimitValue(ctx context.Context) DropLimit {
+	l, _ := ctx.Value(recvDropLimitContextKey{}).(DropLimit)
+	return l
+}
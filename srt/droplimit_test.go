@@ -0,0 +1,94 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDropLimitValidate(t *testing.T) {
+	for _, tt := range []struct {
+		limit DropLimit
+		ok    bool
+	}{
+		{DropLimit{}, true},
+		{DropLimit{Packets: 10, Window: time.Second}, true},
+		{DropLimit{Packets: 0, Window: time.Second}, true},
+		{DropLimit{Packets: 10}, false},
+		{DropLimit{Packets: -1, Window: time.Second}, false},
+	} {
+		if err := tt.limit.validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v: validate() = %v", tt.limit, err)
+		}
+	}
+
+	lc := ListenConfig{RecvDropLimit: DropLimit{Packets: 10}}
+	if ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0"); err == nil {
+		ln.Close()
+		t.Error("listen with an invalid RecvDropLimit succeeded")
+	}
+}
+
+func TestRecvDropLimit(t *testing.T) {
+	limit := DropLimit{Packets: 1, Window: 200 * time.Millisecond}
+	lc := ListenConfig{RecvDropLimit: limit}
+	lc.SetOption("latency", "20")
+	lc.SetOption("fc", "32")
+	lc.SetOption("rcvbuf", strconv.Itoa(32*(maxMSS-srtHeaderSize)))
+	ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	c, err := Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+
+	go func() {
+		b := make([]byte, 1316)
+		for {
+			if _, err := c.Write(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Fall behind, so that the receive buffer overflows.
+	time.Sleep(time.Second)
+	s.SetReadDeadline(time.Now().Add(10 * time.Second))
+	b := make([]byte, 1500)
+	for {
+		_, err = s.Read(b)
+		if err != nil {
+			break
+		}
+	}
+	var derr *DropLimitError
+	if !errors.As(err, &derr) {
+		t.Fatalf("got %v; want a *DropLimitError", err)
+	}
+	if derr.Dropped <= limit.Packets || derr.Limit != limit {
+		t.Errorf("got %+v; want more than %d drops over %+v", derr, limit.Packets, limit)
+	}
+	if _, err := s.Read(b); !errors.As(err, &derr) {
+		t.Errorf("Read after the limit: got %v; want a *DropLimitError", err)
+	}
+}
@@ -10,6 +10,8 @@
 package srt
 
 import (
+	"errors"
+	"net"
 	"os"
 
 	"github.com/openfresh/gosrt/srtapi"
@@ -23,3 +25,33 @@ func wrapSyscallError(name string, err error) error {
 	}
 	return err
 }
+
+// A connError is the error of a read or write on a broken or closed
+// connection. It reads and unwraps as err, and also matches class,
+// ErrConnectionBroken or ErrConnectionClosed.
+type connError struct {
+	class error
+	err   error
+}
+
+func (e *connError) Error() string        { return e.err.Error() }
+func (e *connError) Unwrap() error        { return e.err }
+func (e *connError) Is(target error) bool { return target == e.class }
+
+// classifyConnError returns err as a connError if it tells that the
+// connection is broken or closed, and as is otherwise.
+func classifyConnError(err error) error {
+	if errors.Is(err, net.ErrClosed) {
+		return &connError{class: ErrConnectionClosed, err: err}
+	}
+	var errno srtapi.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case srtapi.ECONNLOST, srtapi.ECONNFAIL, srtapi.ENOCONN:
+			return &connError{class: ErrConnectionBroken, err: err}
+		case srtapi.EINVSOCK:
+			return &connError{class: ErrConnectionClosed, err: err}
+		}
+	}
+	return err
+}
@@ -18,6 +18,9 @@ var (
 )
 
 func isPlatformError(err error) bool {
-	_, ok := err.(srtapi.Errno)
-	return ok
+	switch err.(type) {
+	case srtapi.Errno, *srtapi.SysError:
+		return true
+	}
+	return false
 }
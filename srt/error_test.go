@@ -10,17 +10,20 @@ package srt
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"runtime"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/openfresh/gosrt/internal/poll"
 	"github.com/openfresh/gosrt/internal/socktest"
+	"github.com/openfresh/gosrt/srtapi"
 )
 
 func (e *OpError) isValid() error {
@@ -70,7 +73,7 @@ second:
 		return nil
 	}
 	switch err := nestedErr.(type) {
-	case *net.AddrError, *net.DNSError, net.InvalidAddrError, *net.ParseError, *poll.TimeoutError, net.UnknownNetworkError:
+	case *net.AddrError, *net.DNSError, net.InvalidAddrError, *net.ParseError, *poll.TimeoutError, net.UnknownNetworkError, *RejectError, *HandshakeTimeoutError:
 		return nil
 	case *os.SyscallError:
 		nestedErr = err.Err
@@ -81,7 +84,7 @@ second:
 	}
 	switch nestedErr {
 	case errCanceled, poll.ErrNetClosing, errMissingAddress, errNoSuitableAddress,
-		context.DeadlineExceeded, context.Canceled:
+		errBindToDevice, context.DeadlineExceeded, context.Canceled:
 		return nil
 	}
 	return fmt.Errorf("unexpected type on 2nd nested level: %T", nestedErr)
@@ -343,12 +346,15 @@ second:
 		return nil
 	}
 	switch err := nestedErr.(type) {
+	case *connError:
+		nestedErr = err.err
+		goto second
 	case *os.SyscallError:
 		nestedErr = err.Err
 		goto third
 	}
 	switch nestedErr {
-	case poll.ErrNetClosing, poll.ErrTimeout:
+	case poll.ErrNetClosing, poll.ErrTimeout, ErrListenerClosed:
 		return nil
 	}
 	return fmt.Errorf("unexpected type on 2nd nested level: %T", nestedErr)
@@ -385,6 +391,9 @@ second:
 	switch err := nestedErr.(type) {
 	case *net.AddrError, *net.DNSError, net.InvalidAddrError, *net.ParseError, *poll.TimeoutError, net.UnknownNetworkError:
 		return nil
+	case *connError:
+		nestedErr = err.err
+		goto second
 	case *os.SyscallError:
 		nestedErr = err.Err
 		goto third
@@ -558,3 +567,88 @@ func TestAcceptError(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 	ls.teardown()
 }
+
+func TestSysError(t *testing.T) {
+	err := wrapSyscallError("bind", &srtapi.SysError{Errno: syscall.EADDRINUSE})
+	if !errors.Is(err, srtapi.ESOCKFAIL) {
+		t.Errorf("%v does not match ESOCKFAIL", err)
+	}
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("%v does not match EADDRINUSE", err)
+	}
+	if errors.Is(err, srtapi.ECONNSETUP) {
+		t.Errorf("%v matches ECONNSETUP", err)
+	}
+}
+
+func TestClassifyConnError(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want error // the class, or nil for none
+	}{
+		{wrapSyscallError("write", srtapi.ECONNLOST), ErrConnectionBroken},
+		{wrapSyscallError("read", srtapi.ECONNFAIL), ErrConnectionBroken},
+		{wrapSyscallError("write", srtapi.ENOCONN), ErrConnectionBroken},
+		{poll.ErrNetClosing, ErrConnectionClosed},
+		{wrapSyscallError("read", srtapi.EINVSOCK), ErrConnectionClosed},
+		{poll.ErrTimeout, nil},
+		{ErrWouldBlock, nil},
+		{wrapSyscallError("write", srtapi.ELARGEMSG), nil},
+	} {
+		err := classifyConnError(tt.err)
+		for _, class := range []error{ErrConnectionBroken, ErrConnectionClosed} {
+			if got := errors.Is(err, class); got != (class == tt.want) {
+				t.Errorf("errors.Is(%v, %v) = %v", tt.err, class, got)
+			}
+		}
+		if err.Error() != tt.err.Error() {
+			t.Errorf("got %q; want %q", err, tt.err)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%v does not wrap itself", tt.err)
+		}
+	}
+}
+
+func TestConnectionBrokenError(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+	c, err := Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The peer closes at once: the writes fail once the caller learns
+	// of it.
+	b := []byte("broken")
+	deadline := time.Now().Add(5 * time.Second)
+	for err == nil && time.Now().Before(deadline) {
+		_, err = c.Write(b)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if perr := parseWriteError(err); perr != nil {
+		t.Error(perr)
+	}
+	if !errors.Is(err, ErrConnectionBroken) || errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("write after the peer closed: got %v; want %v", err, ErrConnectionBroken)
+	}
+
+	c.Close()
+	_, err = c.Read(b)
+	if perr := parseReadError(err); perr != nil {
+		t.Error(perr)
+	}
+	if !errors.Is(err, ErrConnectionClosed) || !errors.Is(err, net.ErrClosed) {
+		t.Errorf("read after Close: got %v; want %v", err, ErrConnectionClosed)
+	}
+}
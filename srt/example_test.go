@@ -8,11 +8,15 @@
 package srt_test
 
 import (
+	"context"
 	"io"
 	"log"
 	"net"
+	"strings"
+	"syscall"
 
 	"github.com/openfresh/gosrt/srt"
+	"github.com/openfresh/gosrt/srtapi"
 )
 
 //lint:ignore U1000 Dummy interface ffor Testable Example
@@ -49,3 +53,42 @@ func ExampleListener() {
 		}(conn)
 	}
 }
+
+// libsrt checks the key material of a caller against the single
+// passphrase of the socket, after the listen callback has run, so a
+// listener cannot try several passphrases in turn. During a passphrase
+// rotation, callers can instead name the key they use in their stream
+// ID, and the listen callback set the passphrase of the new socket
+// accordingly. The stream ID of the accepted connection then tells
+// which key it uses. Callers that cannot be changed need a listener
+// per passphrase, on ports of their own.
+func ExampleWithListenCallback_passphraseRotation() {
+	passphrases := map[string]string{
+		"2020-06": "old passphrase of 10 characters or more",
+		"2020-07": "new passphrase of 10 characters or more",
+	}
+	ctx := srt.WithListenCallback(context.Background(), func(ns, hsversion int, peeraddr syscall.Sockaddr, streamid string) int {
+		// The stream ID is of the form "#!::k=2020-07,r=live".
+		for _, kv := range strings.Split(strings.TrimPrefix(streamid, "#!::"), ",") {
+			if strings.HasPrefix(kv, "k=") {
+				if p, ok := passphrases[kv[2:]]; ok && srtapi.SetsockoptString(ns, 0, srtapi.OptionPassphrase, p) == nil {
+					return 0
+				}
+			}
+		}
+		return -1 // unknown key: reject
+	})
+	l, err := srt.ListenContext(ctx, "srt", ":2000")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Print("key ", conn.(*srt.SRTConn).StreamIDParsed()["k"])
+		conn.Close()
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A FanoutPolicy says what an SRTFanout does with a message for a
+// destination whose queue is full.
+type FanoutPolicy int
+
+const (
+	// FanoutDropOldest drops the oldest message of the queue to make
+	// room for the new one.
+	FanoutDropOldest FanoutPolicy = iota
+
+	// FanoutBlock makes Write wait for room in the queue. No message
+	// is lost, but a slow destination then holds back all the
+	// others.
+	FanoutBlock
+
+	// FanoutDisconnect removes the destination and closes its
+	// connection.
+	FanoutDisconnect
+)
+
+// errFanoutOverflow is the error of a destination disconnected by
+// FanoutDisconnect.
+var errFanoutOverflow = errors.New("fanout queue overflow")
+
+// An SRTFanout writes every message to several SRT connections. Each
+// destination has a bounded queue drained by a goroutine of its own,
+// so a slow or dead destination does not stall the others, unless the
+// policy is FanoutBlock.
+//
+// A destination whose write fails is removed, and the error reported
+// by the next call to Write. The connections are not closed, except
+// by FanoutDisconnect.
+type SRTFanout struct {
+	queueLen int
+	policy   FanoutPolicy
+
+	mu    sync.Mutex
+	dests map[*SRTConn]*fanoutDest
+	errs  map[*SRTConn]error // failures not reported yet
+}
+
+type fanoutDest struct {
+	c       *SRTConn
+	queue   chan []byte
+	done    chan struct{} // closed on removal
+	dropped uint64        // atomic
+}
+
+// NewSRTFanout returns an SRTFanout queueing up to queueLen messages
+// per destination, with policy applying to full queues.
+func NewSRTFanout(queueLen int, policy FanoutPolicy) *SRTFanout {
+	if queueLen < 1 {
+		queueLen = 1
+	}
+	return &SRTFanout{
+		queueLen: queueLen,
+		policy:   policy,
+		dests:    make(map[*SRTConn]*fanoutDest),
+		errs:     make(map[*SRTConn]error),
+	}
+}
+
+// Add adds c as a destination. Only the messages written afterwards
+// are sent to it.
+func (f *SRTFanout) Add(c *SRTConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.dests[c]; ok {
+		return
+	}
+	d := &fanoutDest{
+		c:     c,
+		queue: make(chan []byte, f.queueLen),
+		done:  make(chan struct{}),
+	}
+	f.dests[c] = d
+	go f.send(d)
+}
+
+// Remove removes the destination c. Messages still queued for it are
+// discarded, and a write in progress completes. It is safe to call
+// at any time, including while Write runs.
+func (f *SRTFanout) Remove(c *SRTConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remove(c)
+}
+
+// remove removes c from the destinations. f.mu must be held.
+func (f *SRTFanout) remove(c *SRTConn) *fanoutDest {
+	d := f.dests[c]
+	if d != nil {
+		delete(f.dests, c)
+		close(d.done)
+	}
+	return d
+}
+
+func (f *SRTFanout) send(d *fanoutDest) {
+	for {
+		select {
+		case b := <-d.queue:
+			if _, err := d.c.Write(b); err != nil {
+				f.fail(d, err)
+				return
+			}
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// fail removes d after err, unless it is already gone.
+func (f *SRTFanout) fail(d *fanoutDest, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dests[d.c] == d {
+		f.remove(d.c)
+		f.errs[d.c] = err
+	}
+}
+
+// Write queues b for every destination and returns len(b). b is
+// copied, so the caller may reuse it right away.
+//
+// The error, if not nil, is a *FanoutError holding the destinations
+// that failed since the previous call, which have been removed.
+func (f *SRTFanout) Write(b []byte) (int, error) {
+	msg := append([]byte(nil), b...)
+	f.mu.Lock()
+	dests := make([]*fanoutDest, 0, len(f.dests))
+	for _, d := range f.dests {
+		dests = append(dests, d)
+	}
+	f.mu.Unlock()
+
+	for _, d := range dests {
+		f.enqueue(d, msg)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.errs) == 0 {
+		return len(b), nil
+	}
+	err := &FanoutError{Errs: f.errs}
+	f.errs = make(map[*SRTConn]error)
+	return len(b), err
+}
+
+func (f *SRTFanout) enqueue(d *fanoutDest, msg []byte) {
+	switch f.policy {
+	case FanoutBlock:
+		select {
+		case d.queue <- msg:
+		case <-d.done:
+		}
+	case FanoutDisconnect:
+		select {
+		case d.queue <- msg:
+		default:
+			f.fail(d, errFanoutOverflow)
+			d.c.Close()
+		}
+	default:
+		for {
+			select {
+			case d.queue <- msg:
+				return
+			default:
+			}
+			select {
+			case <-d.queue:
+				atomic.AddUint64(&d.dropped, 1)
+			default:
+			}
+		}
+	}
+}
+
+// FanoutBacklog describes the queue of a destination of an
+// SRTFanout.
+type FanoutBacklog struct {
+	Conn *SRTConn
+
+	// Queued is the number of messages waiting to be written.
+	Queued int
+
+	// Dropped counts the messages dropped by FanoutDropOldest.
+	Dropped uint64
+}
+
+// Backlog returns the state of the queue of every destination.
+func (f *SRTFanout) Backlog() []FanoutBacklog {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	backlog := make([]FanoutBacklog, 0, len(f.dests))
+	for c, d := range f.dests {
+		backlog = append(backlog, FanoutBacklog{
+			Conn:    c,
+			Queued:  len(d.queue),
+			Dropped: atomic.LoadUint64(&d.dropped),
+		})
+	}
+	return backlog
+}
+
+// Close removes all the destinations.
+func (f *SRTFanout) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for c := range f.dests {
+		f.remove(c)
+	}
+	return nil
+}
+
+// A FanoutError holds the errors of the destinations of an SRTFanout
+// that failed.
+type FanoutError struct {
+	Errs map[*SRTConn]error
+}
+
+func (e *FanoutError) Error() string {
+	s := make([]string, 0, len(e.Errs))
+	for c, err := range e.Errs {
+		s = append(s, fmt.Sprintf("%v: %v", c.RemoteAddr(), err))
+	}
+	sort.Strings(s)
+	return "fanout: " + strings.Join(s, "; ")
+}
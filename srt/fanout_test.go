@@ -0,0 +1,113 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSRTFanout(t *testing.T) {
+	f := NewSRTFanout(16, FanoutDropOldest)
+	defer f.Close()
+	var clients []*SRTConn
+	for i := 0; i < 2; i++ {
+		c, s := newSRTPair(t)
+		defer c.Close()
+		defer s.Close()
+		f.Add(s)
+		clients = append(clients, c)
+	}
+
+	read := func(c *SRTConn, want []byte) {
+		t.Helper()
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 1500)
+		n, err := c.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[:n], want) {
+			t.Fatalf("got %q; want %q", buf[:n], want)
+		}
+	}
+
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range clients {
+		read(c, []byte("hello"))
+	}
+	if n := len(f.Backlog()); n != 2 {
+		t.Fatalf("got %d destinations; want 2", n)
+	}
+
+	f.Remove(f.Backlog()[0].Conn)
+	if n := len(f.Backlog()); n != 1 {
+		t.Fatalf("got %d destinations; want 1", n)
+	}
+	if _, err := f.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	// One of the clients gets the message; find which by the
+	// remaining destination.
+	remaining := f.Backlog()[0].Conn.RemoteAddr().String()
+	for _, c := range clients {
+		if c.LocalAddr().String() == remaining {
+			read(c, []byte("world"))
+		}
+	}
+}
+
+func TestSRTFanoutPolicy(t *testing.T) {
+	newDest := func() *fanoutDest {
+		return &fanoutDest{c: &SRTConn{}, queue: make(chan []byte, 2), done: make(chan struct{})}
+	}
+
+	f := NewSRTFanout(2, FanoutDropOldest)
+	d := newDest()
+	for _, s := range []string{"a", "b", "c", "d"} {
+		f.enqueue(d, []byte(s))
+	}
+	if d.dropped != 2 {
+		t.Errorf("got %d dropped; want 2", d.dropped)
+	}
+	if got := string(<-d.queue) + string(<-d.queue); got != "cd" {
+		t.Errorf("got %q queued; want %q", got, "cd")
+	}
+
+	f = NewSRTFanout(2, FanoutDisconnect)
+	d = newDest()
+	f.dests[d.c] = d
+	for _, s := range []string{"a", "b", "c"} {
+		f.enqueue(d, []byte(s))
+	}
+	if _, ok := f.dests[d.c]; ok {
+		t.Error("destination not removed on overflow")
+	}
+	if _, err := f.Write(nil); err == nil {
+		t.Error("got no error after overflow")
+	} else if err := err.(*FanoutError).Errs[d.c]; err != errFanoutOverflow {
+		t.Errorf("got %v; want %v", err, errFanoutOverflow)
+	}
+
+	f = NewSRTFanout(2, FanoutBlock)
+	d = newDest()
+	f.dests[d.c] = d
+	f.enqueue(d, []byte("a"))
+	f.enqueue(d, []byte("b"))
+	blocked := make(chan struct{})
+	go func() {
+		f.enqueue(d, []byte("c"))
+		close(blocked)
+	}()
+	select {
+	case <-blocked:
+		t.Fatal("enqueue did not block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	f.Remove(d.c)
+	<-blocked
+}
@@ -11,10 +11,14 @@ package srt
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/openfresh/gosrt/internal/poll"
 	"github.com/openfresh/gosrt/srtapi"
@@ -25,12 +29,37 @@ type netFD struct {
 	pfd poll.FD
 
 	// immutable until Close
+	socketID    uint32 // the SRTSOCKET, kept past Close
 	family      int
 	sotype      int
 	isConnected bool
 	net         string
 	laddr       net.Addr
 	raddr       net.Addr
+	streamID    string
+	routeLocal  bool // laddr is a wildcard, resolved by localAddr
+	streamAPI   bool // connected with the message API off
+
+	lastRead int64 // unix nanoseconds of the last successful read; atomic
+	recvIdle recvIdle
+
+	handshakePending int32 // set until Handshake connects a deferred caller; atomic
+
+	drops atomic.Value // *dropMonitor of the DropLimit set, or nil
+
+	sendClosed int32 // set by CloseSend; atomic
+	eofRead    int32 // set once the marker of CloseSend is read; atomic
+	halfClose  int32 // set by SetHalfClose; atomic
+
+	writeDeadline int64 // unix nanoseconds, or 0 for none; atomic
+
+	udp *os.File // a duplicate of the UDP socket handed to libsrt, for File, or nil
+
+	routeOnce  sync.Once
+	routedAddr net.Addr // laddr resolved by localAddr
+
+	monMu sync.Mutex
+	mons  [numMonitors]*statsMonitor // of StartMonitor, OnKeyStateChange and SetIdleTimeout, or nil
 }
 
 func newFD(sysfd, family, sotype int, net string) (*netFD, error) {
@@ -38,9 +67,10 @@ func newFD(sysfd, family, sotype int, net string) (*netFD, error) {
 		pfd: poll.FD{
 			Sysfd: sysfd,
 		},
-		family: family,
-		sotype: sotype,
-		net:    net,
+		socketID: uint32(sysfd),
+		family:   family,
+		sotype:   sotype,
+		net:      net,
 	}
 	return ret, nil
 }
@@ -52,6 +82,15 @@ func (fd *netFD) init() error {
 func (fd *netFD) setAddr(laddr, raddr net.Addr) {
 	fd.laddr = laddr
 	fd.raddr = raddr
+	if raddr != nil {
+		// The stream ID and API are fixed by the handshake; keep them
+		// at hand for routing accepted connections and checking reads.
+		fd.streamID, _ = srtapi.GetsockflagString(fd.pfd.Sysfd, srtapi.OptionStreamid)
+		if on, err := srtapi.GetsockoptInt(fd.pfd.Sysfd, 0, srtapi.OptionMessageapi); err == nil {
+			fd.streamAPI = on == 0
+		}
+	}
+	atomic.StoreInt64(&fd.lastRead, time.Now().UnixNano())
 	runtime.SetFinalizer(fd, (*netFD).Close)
 }
 
@@ -68,7 +107,7 @@ func (fd *netFD) connect(ctx context.Context, la, ra syscall.Sockaddr) (rsa sysc
 	case srtapi.StatusConnected:
 		return nil, nil
 	default:
-		return nil, fmt.Errorf("unexpected socket state %d", state)
+		return nil, fd.connectError(state)
 	}
 	if err := fd.pfd.Init(fd.net, true); err != nil {
 		return nil, err
@@ -135,44 +174,228 @@ func (fd *netFD) connect(ctx context.Context, la, ra syscall.Sockaddr) (rsa sysc
 		case srtapi.StatusConnected:
 			return nil, nil
 		default:
-			return nil, fmt.Errorf("unexpected socket state %d", state)
+			return nil, fd.connectError(state)
 		}
 	}
 }
 
+// connectError returns the error of a connect that left the socket
+// in state: a RejectError if libsrt knows why it was rejected, or a
+// HandshakeTimeoutError if it gave up waiting for an answer.
+func (fd *netFD) connectError(state int) error {
+	if reason := srtapi.GetRejectReason(fd.pfd.Sysfd); reason != srtapi.RejectUnknown {
+		return &RejectError{Reason: RejectReason(reason)}
+	}
+	switch state {
+	case srtapi.StatusBroken, srtapi.StatusClosed, srtapi.StatusNonexist:
+		mss, _ := getsockoptIntFunc(fd.pfd.Sysfd, 0, srtapi.OptionMss)
+		return &HandshakeTimeoutError{MSS: mss}
+	}
+	return fmt.Errorf("unexpected socket state %d", state)
+}
+
 func (fd *netFD) Close() error {
 	runtime.SetFinalizer(fd, nil)
+	fd.stopMonitors()
+	if fd.udp != nil {
+		fd.udp.Close()
+	}
 	return fd.pfd.Close()
 }
 
+// notConnected returns ErrNotConnected while the handshake of a caller
+// made by DialSRTDeferred is pending.
+func (fd *netFD) notConnected() error {
+	if atomic.LoadInt32(&fd.handshakePending) != 0 {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+// notReadable returns the error of notConnected, io.EOF once the peer
+// called CloseSend, or the *DropLimitError of a connection whose
+// receiver dropped more than its DropLimit allows.
+func (fd *netFD) notReadable() error {
+	if err := fd.notConnected(); err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&fd.eofRead) != 0 {
+		return io.EOF
+	}
+	if m, _ := fd.drops.Load().(*dropMonitor); m != nil {
+		return m.check(fd.pfd.Sysfd)
+	}
+	return nil
+}
+
+// setDropLimit starts checking the drops of fd against l, or stops if
+// l is the zero DropLimit.
+func (fd *netFD) setDropLimit(l DropLimit) {
+	var m *dropMonitor
+	if l != (DropLimit{}) {
+		m = newDropMonitor(fd.pfd.Sysfd, l)
+	}
+	fd.drops.Store(m)
+}
+
 func (fd *netFD) Read(p []byte) (n int, err error) {
+	if err := fd.notReadable(); err != nil {
+		return 0, err
+	}
 	n, err = fd.pfd.Read(p)
-	return n, wrapSyscallError("read", err)
+	return fd.readEOF(p, n, fd.readErr(err))
+}
+
+func (fd *netFD) readContext(ctx context.Context, p []byte) (n int, err error) {
+	if err := fd.notReadable(); err != nil {
+		return 0, err
+	}
+	n, err = fd.pfd.ReadContext(ctx, p)
+	return fd.readEOF(p, n, fd.readErr(err))
+}
+
+func (fd *netFD) readNoWait(p []byte) (n int, err error) {
+	if err := fd.notReadable(); err != nil {
+		return 0, err
+	}
+	n, err = fd.pfd.ReadNoWait(p)
+	if n == 0 && err == nil {
+		return 0, nil
+	}
+	return fd.readEOF(p, n, fd.readErr(err))
+}
+
+func (fd *netFD) readMsg(p []byte, mc *srtapi.MsgCtrl) (n int, err error) {
+	if err := fd.notReadable(); err != nil {
+		return 0, err
+	}
+	n, err = fd.pfd.ReadMsg(p, mc)
+	return fd.readEOF(p, n, fd.readErr(err))
+}
+
+func (fd *netFD) readBuffers(bufs [][]byte) (n int, err error) {
+	if err := fd.notReadable(); err != nil {
+		return 0, err
+	}
+	n, err = fd.pfd.ReadBuffers(bufs)
+	return fd.readBuffersEOF(bufs, n, fd.readErr(err))
+}
+
+// readErr records a successful read when err is nil, or else maps the
+// error of a failed one.
+func (fd *netFD) readErr(err error) error {
+	if err == nil {
+		atomic.StoreInt64(&fd.lastRead, time.Now().UnixNano())
+		return nil
+	}
+	if err == srtapi.EASYNCRCV {
+		return ErrWouldBlock
+	}
+	return classifyConnError(wrapSyscallError("read", err))
 }
 
 func (fd *netFD) Write(p []byte) (nn int, err error) {
+	if err := fd.notWritable(); err != nil {
+		return 0, err
+	}
 	nn, err = fd.pfd.Write(p)
-	return nn, wrapSyscallError("write", err)
+	if err == srtapi.ELARGEMSG {
+		if max, merr := maxMessageSize(fd); merr == nil {
+			return nn, &MessageSizeError{Size: len(p), Max: max}
+		}
+	}
+	return nn, writeErr(err)
 }
 
-func (fd *netFD) accept() (netfd *netFD, err error) {
-	d, rsa, errcall, err := fd.pfd.Accept()
+func (fd *netFD) writeBuffers(bufs *net.Buffers) (n int64, err error) {
+	if err := fd.notWritable(); err != nil {
+		return 0, err
+	}
+	n, err = fd.pfd.WriteBuffers((*[][]byte)(bufs), writeBufSize(fd))
+	return n, writeErr(err)
+}
+
+// writeErr maps the error of a failed write.
+func writeErr(err error) error {
+	if err == srtapi.EASYNCSND {
+		return ErrWouldBlock
+	}
+	return classifyConnError(wrapSyscallError("write", err))
+}
+
+// writeBufSize returns the size of the chunks WriteBuffers coalesces
+// buffers into: in live mode a message is one packet of at most the
+// payload size, in file mode as large as the relay buffer.
+func writeBufSize(fd *netFD) int {
+	if size, err := srtapi.GetsockoptInt(fd.pfd.Sysfd, 0, srtapi.OptionPayloadsize); err == nil && size > 0 {
+		return size
+	}
+	return relayBufferSize
+}
+
+func (fd *netFD) accept(ctx context.Context) (netfd *netFD, err error) {
+	d, rsa, errcall, err := fd.pfd.AcceptContext(ctx)
+	if err != nil {
+		if errcall != "" {
+			err = wrapSyscallError(errcall, err)
+		}
+		return nil, err
+	}
+	return fd.newAccepted(d, rsa)
+}
+
+// acceptNoWait accepts a connection if one is ready, and returns nil
+// and a nil error otherwise.
+func (fd *netFD) acceptNoWait() (*netFD, error) {
+	d, rsa, errcall, err := fd.pfd.AcceptNoWait()
 	if err != nil {
 		if errcall != "" {
 			err = wrapSyscallError(errcall, err)
 		}
 		return nil, err
 	}
+	if d < 0 {
+		return nil, nil
+	}
+	return fd.newAccepted(d, rsa)
+}
 
+// newAccepted returns the netFD of d, a socket accepted on fd from rsa.
+func (fd *netFD) newAccepted(d int, rsa syscall.Sockaddr) (netfd *netFD, err error) {
 	if netfd, err = newFD(d, fd.family, fd.sotype, fd.net); err != nil {
 		poll.CloseFunc(d)
 		return nil, err
 	}
 	if err = netfd.init(); err != nil {
-		fd.Close()
+		netfd.Close()
 		return nil, err
 	}
+	if fd.udp != nil {
+		// Accepted sockets share the UDP socket of the listener.
+		netfd.udp = dupUDP(fd.udp)
+	}
+	if rsa == nil {
+		rsa, _ = srtapi.Getpeername(netfd.pfd.Sysfd)
+	}
 	lsa, _ := srtapi.Getsockname(netfd.pfd.Sysfd)
-	netfd.setAddr(netfd.addrFunc()(lsa), netfd.addrFunc()(rsa))
+	laddr, raddr := netfd.addrFunc()(lsa), netfd.addrFunc()(rsa)
+	if la, ok := laddr.(*SRTAddr); ok && la.isWildcard() {
+		netfd.routeLocal = true
+	}
+	netfd.setAddr(laddr, raddr)
 	return netfd, nil
 }
+
+// localAddr returns the local address of the connection. That of a
+// connection accepted on a wildcard address is looked up on the first
+// call, with routeLocalAddr, rather than on every Accept.
+func (fd *netFD) localAddr() net.Addr {
+	if !fd.routeLocal {
+		return fd.laddr
+	}
+	fd.routeOnce.Do(func() {
+		ra, _ := fd.raddr.(*SRTAddr)
+		fd.routedAddr = routeLocalAddr(fd.laddr.(*SRTAddr), ra)
+	})
+	return fd.routedAddr
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build nacl plan9 windows
+
+package srt
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+func keepUDP(s int) *os.File { return nil }
+
+func dupUDP(f *os.File) *os.File { return nil }
+
+func (fd *netFD) dup() (*os.File, error) {
+	return nil, syscall.ENOPROTOOPT
+}
+
+func (u *sharedUDP) bind(s int) error {
+	return syscall.ENOPROTOOPT
+}
+
+func (u *sharedUDP) release() {}
+
+func shareUDPConn(c *net.UDPConn) (*sharedUDP, string, *SRTAddr, error) {
+	return nil, "", nil, syscall.ENOPROTOOPT
+}
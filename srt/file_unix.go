@@ -0,0 +1,135 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package srt
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// errNoUDPSocket is returned by File for a connection whose UDP socket
+// libsrt made itself: libsrt does not tell which one it is.
+var errNoUDPSocket = errors.New("UDP socket of the connection unknown: libsrt does not expose its own")
+
+// keepUDP returns a duplicate of s, the UDP socket handed to libsrt for
+// a connection, for File to copy later, or nil if s cannot be
+// duplicated. The duplicate keeps the socket open past libsrt closing
+// its own, so File never copies a descriptor number reused since.
+func keepUDP(s int) *os.File {
+	ns, err := dupCloseOnExec(s)
+	if err != nil {
+		return nil
+	}
+	return os.NewFile(uintptr(ns), "udp")
+}
+
+// dupUDP returns another duplicate of f, a socket kept by keepUDP, or
+// nil.
+func dupUDP(f *os.File) *os.File {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	var dup *os.File
+	rc.Control(func(s uintptr) { dup = keepUDP(int(s)) })
+	return dup
+}
+
+func dupCloseOnExec(s int) (int, error) {
+	syscall.ForkLock.RLock()
+	ns, err := syscall.Dup(s)
+	if err == nil {
+		syscall.CloseOnExec(ns)
+	}
+	syscall.ForkLock.RUnlock()
+	return ns, err
+}
+
+func (fd *netFD) dup() (*os.File, error) {
+	if fd.udp == nil {
+		return nil, errNoUDPSocket
+	}
+	rc, err := fd.udp.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	ns := -1
+	var dupErr error
+	if err := rc.Control(func(s uintptr) { ns, dupErr = dupCloseOnExec(int(s)) }); err != nil {
+		return nil, err
+	}
+	if dupErr != nil {
+		return nil, os.NewSyscallError("dup", dupErr)
+	}
+	return os.NewFile(uintptr(ns), fd.name()), nil
+}
+
+func (fd *netFD) name() string {
+	var ls, rs string
+	if fd.laddr != nil {
+		ls = fd.laddr.String()
+	}
+	if fd.raddr != nil {
+		rs = fd.raddr.String()
+	}
+	return fd.net + ":" + ls + "->" + rs
+}
+
+// bind binds the SRT socket s to the UDP socket.
+func (u *sharedUDP) bind(s int) error {
+	if err := srtapi.BindPeerOf(s, u.fd); err != nil {
+		return os.NewSyscallError("bind", err)
+	}
+	u.taken = true
+	return nil
+}
+
+// release closes the duplicate unless libsrt took it over.
+func (u *sharedUDP) release() {
+	if !u.taken {
+		syscall.Close(u.fd)
+	}
+}
+
+// shareUDPConn duplicates the descriptor of c, and returns it along
+// with the network and local address that match its family.
+func shareUDPConn(c *net.UDPConn) (u *sharedUDP, network string, laddr *SRTAddr, err error) {
+	if c == nil {
+		return nil, "", nil, syscall.EINVAL
+	}
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	s := -1
+	var dupErr error
+	err = rc.Control(func(fd uintptr) { s, dupErr = dupCloseOnExec(int(fd)) })
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if dupErr != nil {
+		return nil, "", nil, os.NewSyscallError("dup", dupErr)
+	}
+	sa, err := syscall.Getsockname(s)
+	if err != nil {
+		syscall.Close(s)
+		return nil, "", nil, os.NewSyscallError("getsockname", err)
+	}
+	switch sa.(type) {
+	case *syscall.SockaddrInet4:
+		network = "srt4"
+	case *syscall.SockaddrInet6:
+		network = "srt6"
+	default:
+		syscall.Close(s)
+		return nil, "", nil, syscall.EAFNOSUPPORT
+	}
+	return &sharedUDP{fd: s}, network, sockaddrToSRT(sa).(*SRTAddr), nil
+}
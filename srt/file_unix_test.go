@@ -0,0 +1,198 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// checkFile checks that f is a copy of a UDP socket bound to the port
+// of c.
+func checkFile(t *testing.T, f *os.File, c *SRTConn) {
+	t.Helper()
+	rc, err := f.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Control(func(fd uintptr) {
+		s := int(fd)
+		if typ, err := syscall.GetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_TYPE); err != nil || typ != syscall.SOCK_DGRAM {
+			t.Errorf("got socket type %v, %v; want SOCK_DGRAM", typ, err)
+		}
+		sa, err := syscall.Getsockname(s)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if a := sockaddrToSRT(sa).(*SRTAddr); a.Port != c.LocalAddr().(*SRTAddr).Port {
+			t.Errorf("got %v; want bound to the port of %v", a, c.LocalAddr())
+		}
+	})
+}
+
+func TestSRTConnFile(t *testing.T) {
+	lc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lc.Close()
+	ln, err := ListenSRTOnConn(lc, ListenConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	accepted := make(chan *SRTConn, 1)
+	go func() {
+		c, err := ln.AcceptSRT()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	c, err := DialSRTOnConn(context.Background(), dc, ln.Addr().(*SRTAddr), Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+
+	for _, c := range []*SRTConn{c, s} {
+		f, err := c.File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkFile(t, f, c)
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The connection must survive closing its copy.
+	if _, err := c.Write([]byte("FILE TEST")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 16)
+	if _, err := s.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// libsrt does not tell which UDP socket it made for a connection.
+	c2, err := Dial("srt4", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if _, err := c2.(*SRTConn).File(); !errors.Is(err, errNoUDPSocket) {
+		t.Errorf("File of a connection on a UDP socket of libsrt = %v; want %v", err, errNoUDPSocket)
+	}
+}
+
+func TestKeepUDP(t *testing.T) {
+	pc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := pc.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := &netFD{net: "srt", laddr: &SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: pc.LocalAddr().(*net.UDPAddr).Port}}
+	rc.Control(func(s uintptr) { fd.udp = keepUDP(int(s)) })
+	if fd.udp == nil {
+		t.Fatal("keepUDP failed")
+	}
+	// The kept copy outlives the socket it was made of, as when
+	// libsrt closes its own.
+	pc.Close()
+
+	// An accepted connection keeps its own copy, past the listener.
+	accepted := &netFD{net: "srt", laddr: fd.laddr, udp: dupUDP(fd.udp)}
+	fd.udp.Close()
+	defer accepted.udp.Close()
+	f, err := accepted.dup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, f, newSRTConn(accepted))
+	f.Close()
+	if _, err := (&netFD{net: "srt"}).dup(); !errors.Is(err, errNoUDPSocket) {
+		t.Errorf("dup without a kept socket = %v; want %v", err, errNoUDPSocket)
+	}
+}
+
+func TestListenSRTOnConn(t *testing.T) {
+	lc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := ListenSRTOnConn(lc, ListenConfig{})
+	if err != nil {
+		lc.Close()
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if got, want := ln.Addr().(*SRTAddr).Port, lc.LocalAddr().(*net.UDPAddr).Port; got != want {
+		t.Errorf("got listener port %d; want %d", got, want)
+	}
+	// The listener owns a copy of the socket, so closing the
+	// original must not affect it.
+	lc.Close()
+
+	dc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.Close()
+
+	accepted := make(chan *SRTConn, 1)
+	go func() {
+		c, err := ln.AcceptSRT()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	c, err := DialSRTOnConn(context.Background(), dc, ln.Addr().(*SRTAddr), Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+	if got, want := c.LocalAddr().(*SRTAddr).Port, dc.LocalAddr().(*net.UDPAddr).Port; got != want {
+		t.Errorf("got caller port %d; want %d", got, want)
+	}
+
+	if _, err := c.Write([]byte("UDPCONN TEST")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 16)
+	n, err := s.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != "UDPCONN TEST" {
+		t.Errorf("got %q; want %q", b[:n], "UDPCONN TEST")
+	}
+}
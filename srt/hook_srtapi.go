@@ -21,4 +21,5 @@ var (
 	connectFunc       = srtapi.Connect
 	listenFunc        = srtapi.Listen
 	getsockoptIntFunc = srtapi.GetsockoptInt
+	getVersionFunc    = srtapi.GetVersion
 )
@@ -0,0 +1,122 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// recvIdle follows the bytes a connection received, as libsrt counts
+// them, to tell when it last received any whether they were read or
+// not.
+type recvIdle struct {
+	mu    sync.Mutex
+	bytes uint64    // ByteRecvTotal at the last check
+	at    time.Time // of the last check that saw it move, or zero
+}
+
+// observe records bytes, the ByteRecvTotal of the connection at now,
+// and returns when it was last seen to move.
+func (r *recvIdle) observe(bytes uint64, now time.Time) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if bytes != r.bytes {
+		r.bytes, r.at = bytes, now
+	}
+	return r.at
+}
+
+// idleDuration returns the time since fd last received data: since
+// the last read, or the last check that saw data arrive unread,
+// whichever is later.
+func (fd *netFD) idleDuration(now time.Time) (time.Duration, error) {
+	mon, err := srtapi.Bistats(fd.pfd.Sysfd, false, true)
+	if err != nil {
+		return 0, err
+	}
+	since := time.Unix(0, atomic.LoadInt64(&fd.lastRead))
+	if at := fd.recvIdle.observe(mon.ByteRecvTotal, now); at.After(since) {
+		since = at
+	}
+	return now.Sub(since), nil
+}
+
+// IdleDuration returns the time since the connection last received
+// data, or since it was established if it received none. Data that
+// arrives is seen when it is read, or else on the next call to
+// IdleDuration or check of the idle timeout, so the result is only as
+// fine as these are frequent. Control packets, such as keepalives, do
+// not count.
+func (c *SRTConn) IdleDuration() (time.Duration, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	d, err := c.fd.idleDuration(time.Now())
+	if err != nil {
+		return 0, &OpError{Op: "stats", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return d, nil
+}
+
+// SetIdleTimeout makes the connection close itself once it has
+// received no data for d, as IdleDuration tells, checked four times
+// per d on the goroutine that samples the statistics of all
+// connections. Reads and writes then fail as after Close. It bounds
+// the resources held by peers that stay connected, keeping libsrt
+// happy with keepalives, but send nothing. Zero removes the timeout.
+//
+// Connections accepted by a listener with an IdleTimeout have it set
+// at accept; an application that takes one over for its own handling,
+// such as a long-lived but quiet control channel, calls
+// SetIdleTimeout(0) to keep the listener from closing it.
+func (c *SRTConn) SetIdleTimeout(d time.Duration) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if d < 0 {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: negativeIdleTimeout(d)}
+	}
+	c.fd.setIdleTimeout(d)
+	return nil
+}
+
+func negativeIdleTimeout(d time.Duration) error {
+	return errors.New("negative idle timeout " + d.String())
+}
+
+func (fd *netFD) setIdleTimeout(d time.Duration) {
+	if d == 0 {
+		fd.setMonitor(monitorIdle, nil)
+		return
+	}
+	interval := d / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	sample := func(s int) (interface{}, error) {
+		idle, err := fd.idleDuration(time.Now())
+		if err != nil || idle < d {
+			return nil, err
+		}
+		return idle, nil
+	}
+	fd.setMonitor(monitorIdle, newStatsMonitor(fd, interval, sample, func(interface{}) {
+		fd.Close()
+	}))
+}
+
+// idleTimeoutContextKey is the type of contextKeys used for the
+// IdleTimeout of a ListenConfig.
+type idleTimeoutContextKey struct{}
+
+func idleTimeoutValue(ctx context.Context) time.Duration {
+	d, _ := ctx.Value(idleTimeoutContextKey{}).(time.Duration)
+	return d
+}
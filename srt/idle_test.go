@@ -0,0 +1,111 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRecvIdleObserve(t *testing.T) {
+	var r recvIdle
+	t0 := time.Unix(1000, 0)
+	if at := r.observe(0, t0); !at.IsZero() {
+		t.Errorf("nothing received: got %v; want zero", at)
+	}
+	if at := r.observe(1316, t0.Add(time.Second)); !at.Equal(t0.Add(time.Second)) {
+		t.Errorf("data received: got %v; want %v", at, t0.Add(time.Second))
+	}
+	if at := r.observe(1316, t0.Add(5*time.Second)); !at.Equal(t0.Add(time.Second)) {
+		t.Errorf("no more data: got %v; want %v", at, t0.Add(time.Second))
+	}
+	if at := r.observe(2632, t0.Add(6*time.Second)); !at.Equal(t0.Add(6 * time.Second)) {
+		t.Errorf("more data: got %v; want %v", at, t0.Add(6*time.Second))
+	}
+}
+
+func TestListenConfigIdleTimeout(t *testing.T) {
+	lc := ListenConfig{IdleTimeout: -time.Second}
+	if ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0"); err == nil {
+		ln.Close()
+		t.Fatal("listen with a negative idle timeout succeeded")
+	}
+
+	const timeout = 300 * time.Millisecond
+	lc = ListenConfig{IdleTimeout: timeout}
+	ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			c, err := ln.Accept()
+			if err != nil {
+				t.Error(err)
+				accepted <- nil
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	// The first caller sends once, then goes silent; the second is
+	// taken over by the application, which opts out of the timeout.
+	var peers []net.Conn
+	var conns []*SRTConn
+	for i := 0; i < 2; i++ {
+		c, err := Dial("srt", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		peers = append(peers, c)
+		s := <-accepted
+		if s == nil {
+			t.FailNow()
+		}
+		defer s.Close()
+		conns = append(conns, s.(*SRTConn))
+	}
+	if err := conns[1].SetIdleTimeout(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := peers[0].Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1500)
+	conns[0].SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conns[0].Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := conns[0].IdleDuration(); err != nil || d > timeout {
+		t.Errorf("IdleDuration right after a read = %v, %v; want less than %v", d, err, timeout)
+	}
+
+	// The silent connection is closed once idle for the timeout.
+	start := time.Now()
+	_, err = conns[0].Read(b)
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Read on the idle connection = %v; want %v", err, net.ErrClosed)
+	}
+	if d := time.Since(start); d < timeout/2 || d > 2*time.Second {
+		t.Errorf("idle connection closed after %v; want about %v", d, timeout)
+	}
+
+	// The one taken over is left alone.
+	if d, err := conns[1].IdleDuration(); err != nil || d < timeout {
+		t.Errorf("IdleDuration of the quiet connection = %v, %v; want at least %v", d, err, timeout)
+	}
+	conns[1].SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := conns[1].Read(b); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read on the connection taken over = %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+}
@@ -26,6 +26,31 @@ func loopbackInterface() *net.Interface {
 	return nil
 }
 
+// nonLoopbackIPv4Addr returns an IPv4 address of an available
+// non-loopback network interface for tests. It returns nil if no
+// suitable address is found.
+func nonLoopbackIPv4Addr() net.IP {
+	ift, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, ifi := range ift {
+		if ifi.Flags&net.FlagLoopback != 0 || ifi.Flags&net.FlagUp == 0 {
+			continue
+		}
+		ifat, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, ifa := range ifat {
+			if ifa, ok := ifa.(*net.IPNet); ok && ifa.IP.To4() != nil && ifa.IP.IsGlobalUnicast() {
+				return ifa.IP.To4()
+			}
+		}
+	}
+	return nil
+}
+
 // ipv6LinkLocalUnicastAddr returns an IPv6 link-local unicast address
 // on the given network interface for tests. It returns "" if no
 // suitable address is found.
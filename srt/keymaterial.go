@@ -0,0 +1,265 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A KMState is the state of the key material of one direction of an
+// encrypted connection.
+type KMState int
+
+// Key material states, as in SRT_KM_STATE.
+const (
+	KMUnsecured KMState = srtapi.KMStateUnsecured // no encryption
+	KMSecuring  KMState = srtapi.KMStateSecuring  // key exchange in progress
+	KMSecured   KMState = srtapi.KMStateSecured   // encrypted with a valid key
+	KMNoSecret  KMState = srtapi.KMStateNosecret  // the peer encrypts, but no passphrase is set
+	KMBadSecret KMState = srtapi.KMStateBadsecret // the passphrases differ
+)
+
+var kmStateNames = []string{
+	KMUnsecured: "unsecured",
+	KMSecuring:  "securing",
+	KMSecured:   "secured",
+	KMNoSecret:  "no secret",
+	KMBadSecret: "bad secret",
+}
+
+func (s KMState) String() string {
+	if s >= 0 && int(s) < len(kmStateNames) {
+		return kmStateNames[s]
+	}
+	return "KMState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// KMState returns the state of the key material used for sending and
+// for receiving.
+//
+// libsrt does not report which of the even and odd keys is active.
+// Key rotation keeps the state at KMSecured throughout, and shows in
+// the statistics instead: a receiver missing a new key counts
+// undecryptable packets in PktRcvUndecrypt.
+func (c *conn) KMState() (snd, rcv KMState, err error) {
+	if snd, err = c.SndKeyState(); err != nil {
+		return 0, 0, err
+	}
+	if rcv, err = c.RcvKeyState(); err != nil {
+		return 0, 0, err
+	}
+	return snd, rcv, nil
+}
+
+// Encrypted reports whether the data is encrypted with matching keys
+// in both directions. It is false for connections that went through
+// with mismatching encryption settings while encryption was not
+// enforced.
+func (c *conn) Encrypted() (bool, error) {
+	snd, rcv, err := c.KMState()
+	if err != nil {
+		return false, err
+	}
+	return snd == KMSecured && rcv == KMSecured, nil
+}
+
+// SndKeyState returns the state of the key material the connection
+// encrypts the data it sends with. It is KMBadSecret when the peer
+// reported that it could not decrypt it with its passphrase.
+func (c *conn) SndKeyState() (KMState, error) {
+	return c.kmState(srtapi.OptionSndkmstate)
+}
+
+// RcvKeyState returns the state of the key material the connection
+// decrypts the data it receives with. It is KMBadSecret when the
+// passphrase of the connection does not match that of the peer, and
+// KMNoSecret when the peer encrypts but no passphrase is set.
+//
+// With enforced encryption, the libsrt default, such connections are
+// rejected during the handshake instead, with a RejectError of reason
+// RejectBadSecret or RejectUnsecure; the key states only tell the two
+// directions apart once encryption is not enforced.
+func (c *conn) RcvKeyState() (KMState, error) {
+	return c.kmState(srtapi.OptionRcvkmstate)
+}
+
+func (c *conn) kmState(opt int) (KMState, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	s, err := getsockoptIntFunc(c.fd.pfd.Sysfd, 0, opt)
+	if err != nil {
+		return 0, err
+	}
+	return KMState(s), nil
+}
+
+// A CryptoMode is the cipher mode of the encryption of a connection, as
+// in SRTO_CRYPTOMODE.
+type CryptoMode int
+
+const (
+	// CryptoAuto lets the handshake settle the mode: a caller
+	// proposes AES-CTR, and a listener takes the mode the caller
+	// proposes. It is the default.
+	CryptoAuto CryptoMode = 0
+
+	// CryptoAESCTR encrypts with AES in counter mode, the only mode
+	// before libsrt 1.5.2. It does not authenticate the data.
+	CryptoAESCTR CryptoMode = 1
+
+	// CryptoAESGCM encrypts with AES-GCM, which also authenticates
+	// the data, so that packets altered on the way are dropped
+	// rather than delivered. It needs libsrt 1.5.2 or later on both
+	// peers.
+	CryptoAESGCM CryptoMode = 2
+)
+
+var cryptoModeNames = []string{
+	CryptoAuto:   "auto",
+	CryptoAESCTR: "AES-CTR",
+	CryptoAESGCM: "AES-GCM",
+}
+
+func (m CryptoMode) String() string {
+	if m >= 0 && int(m) < len(cryptoModeNames) {
+		return cryptoModeNames[m]
+	}
+	return "CryptoMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// CryptoMode returns the cipher mode the connection encrypts with, as
+// the handshake settled it, or CryptoAuto if the connection is not
+// encrypted, as Encrypted reports. With libsrt older than 1.5.2, an
+// encrypted connection always uses CryptoAESCTR.
+func (c *SRTConn) CryptoMode() (CryptoMode, error) {
+	encrypted, err := c.Encrypted()
+	if err != nil || !encrypted {
+		return CryptoAuto, err
+	}
+	if !VersionAtLeast(1, 5, 2) {
+		return CryptoAESCTR, nil
+	}
+	m, err := getsockoptIntFunc(c.fd.pfd.Sysfd, 0, srtapi.OptionCryptomode)
+	if err != nil {
+		return CryptoAuto, c.infoError(err)
+	}
+	if CryptoMode(m) == CryptoAuto {
+		// Nothing was asked for, so the caller proposed AES-CTR.
+		return CryptoAESCTR, nil
+	}
+	return CryptoMode(m), nil
+}
+
+// A KeyDirection is the direction of the data a key material state
+// applies to.
+type KeyDirection int
+
+const (
+	KeySend KeyDirection = iota // SndKeyState
+	KeyRecv                     // RcvKeyState
+)
+
+func (d KeyDirection) String() string {
+	switch d {
+	case KeySend:
+		return "send"
+	case KeyRecv:
+		return "recv"
+	}
+	return "KeyDirection(" + strconv.Itoa(int(d)) + ")"
+}
+
+// keyStateInterval is how often OnKeyStateChange samples the states.
+const keyStateInterval = 250 * time.Millisecond
+
+// keyStateChange is a change of the key state of a direction, as
+// handed to the callback of OnKeyStateChange.
+type keyStateChange struct {
+	dir      KeyDirection
+	old, new KMState
+}
+
+// keyStateWatch follows the key states of a connection for
+// OnKeyStateChange. It runs on the sampler goroutine alone.
+type keyStateWatch struct {
+	reported [2]KMState // last reported, or found at the start
+	pending  [2]KMState // seen once, to be reported if seen again
+	seen     [2]bool    // pending is set
+}
+
+// sample returns the changes of the states of s, those seen on two
+// samples in a row. The end of a key exchange in progress at the
+// start is not a change.
+func (w *keyStateWatch) sample(s int) (interface{}, error) {
+	var changes []keyStateChange
+	for dir, opt := range []int{srtapi.OptionSndkmstate, srtapi.OptionRcvkmstate} {
+		v, err := getsockoptIntFunc(s, 0, opt)
+		if err != nil {
+			return nil, err
+		}
+		st := KMState(v)
+		switch {
+		case st == w.reported[dir]:
+			w.seen[dir] = false
+		case w.reported[dir] == KMSecuring && st == KMSecured:
+			w.reported[dir], w.seen[dir] = st, false
+		case w.seen[dir] && w.pending[dir] == st:
+			changes = append(changes, keyStateChange{KeyDirection(dir), w.reported[dir], st})
+			w.reported[dir], w.seen[dir] = st, false
+		default:
+			w.pending[dir], w.seen[dir] = st, true
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, nil
+}
+
+// OnKeyStateChange sets fn to be called when the key material state of
+// a direction changes, as SndKeyState and RcvKeyState report it: most
+// usefully, when it becomes KMBadSecret mid-stream, which otherwise
+// only shows as PktRcvUndecrypt growing. A nil fn removes the
+// callback.
+//
+// The states are sampled four times a second by the goroutine that
+// samples the statistics for StartMonitor, and fn runs on a goroutine
+// of the connection's own, as the callback of StartMonitor does;
+// changes that come while it runs are kept for it, in order. A state
+// is only reported once seen on two samples in a row, so that a
+// passing one is not, and the end of a key exchange in progress when
+// fn is set, as on the handshake, is not reported either. libsrt
+// keeps the state at KMSecured through key rotation, so rekeying is
+// not a change.
+func (c *SRTConn) OnKeyStateChange(fn func(dir KeyDirection, old, new KMState)) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if fn == nil {
+		c.fd.setMonitor(monitorKeyState, nil)
+		return nil
+	}
+	var w keyStateWatch
+	var err error
+	if w.reported[KeySend], err = c.SndKeyState(); err != nil {
+		return &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockopt", err)}
+	}
+	if w.reported[KeyRecv], err = c.RcvKeyState(); err != nil {
+		return &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockopt", err)}
+	}
+	m := newStatsMonitor(c.fd, keyStateInterval, w.sample, func(v interface{}) {
+		for _, ch := range v.([]keyStateChange) {
+			fn(ch.dir, ch.old, ch.new)
+		}
+	})
+	m.merge = func(waiting, latest interface{}) interface{} {
+		return append(waiting.([]keyStateChange), latest.([]keyStateChange)...)
+	}
+	c.fd.setMonitor(monitorKeyState, m)
+	return nil
+}
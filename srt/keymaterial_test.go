@@ -0,0 +1,55 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+func TestKeyStateWatch(t *testing.T) {
+	var snd, rcv KMState
+	old := getsockoptIntFunc
+	defer func() { getsockoptIntFunc = old }()
+	getsockoptIntFunc = func(fd, level, opt int) (int, error) {
+		switch opt {
+		case srtapi.OptionSndkmstate:
+			return int(snd), nil
+		case srtapi.OptionRcvkmstate:
+			return int(rcv), nil
+		}
+		return old(fd, level, opt)
+	}
+
+	w := keyStateWatch{reported: [2]KMState{KMSecuring, KMSecuring}}
+	for i, tt := range []struct {
+		snd, rcv KMState
+		want     []keyStateChange
+	}{
+		// The key exchange of the handshake ends.
+		{KMSecured, KMSecured, nil},
+		{KMSecured, KMSecured, nil},
+		// A state seen once is not reported.
+		{KMSecured, KMBadSecret, nil},
+		{KMSecured, KMSecured, nil},
+		// One seen twice is.
+		{KMSecured, KMBadSecret, nil},
+		{KMSecured, KMBadSecret, []keyStateChange{{KeyRecv, KMSecured, KMBadSecret}}},
+		{KMSecured, KMBadSecret, nil},
+		{KMBadSecret, KMSecured, nil},
+		{KMBadSecret, KMSecured, []keyStateChange{{KeySend, KMSecured, KMBadSecret}, {KeyRecv, KMBadSecret, KMSecured}}},
+	} {
+		snd, rcv = tt.snd, tt.rcv
+		v, err := w.sample(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := v.([]keyStateChange)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("#%d: got %v; want %v", i, got, tt.want)
+		}
+	}
+}
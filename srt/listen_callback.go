@@ -17,6 +17,15 @@ func WithListenCallback(ctx context.Context, callback srtapi.SrtListenCallbackFu
 	return context.WithValue(ctx, listenCallbackContextKey{}, callback)
 }
 
+// onAcceptContextKey is the type of contextKeys used for the OnAccept
+// function of a ListenConfig.
+type onAcceptContextKey struct{}
+
+func onAcceptValue(ctx context.Context) func(*SRTAddr, string) string {
+	fn, _ := ctx.Value(onAcceptContextKey{}).(func(*SRTAddr, string) string)
+	return fn
+}
+
 func listenCallbackValue(ctx context.Context) srtapi.SrtListenCallbackFunc {
 	callback, _ := ctx.Value(listenCallbackContextKey{}).(srtapi.SrtListenCallbackFunc)
 	return callback
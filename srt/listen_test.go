@@ -10,6 +10,8 @@
 package srt
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
@@ -18,6 +20,7 @@ import (
 	"time"
 
 	"github.com/openfresh/gosrt/internal/testenv"
+	"github.com/openfresh/gosrt/srtapi"
 )
 
 func (ln *SRTListener) port() string {
@@ -345,3 +348,271 @@ func TestClosingListener(t *testing.T) {
 	}
 	ln2.Close()
 }
+
+func TestListenConfigBacklog(t *testing.T) {
+	const backlog = 2
+	lc := ListenConfig{Backlog: backlog}
+	ln, err := lc.Listen(context.Background(), "srt4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Nobody accepts, so the connections pile up in the backlog.
+	d := Dialer{Timeout: 5 * time.Second}
+	for i := 0; i < backlog; i++ {
+		c, err := d.Dial("srt4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		defer c.Close()
+	}
+	c, err := d.Dial("srt4", ln.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatalf("#%d: dial beyond the backlog succeeded", backlog)
+	}
+	var rerr *RejectError
+	if !errors.As(err, &rerr) || rerr.Reason != RejectBacklog {
+		t.Fatalf("#%d: got %v; want reject for %v", backlog, err, RejectBacklog)
+	}
+	if !errors.Is(err, srtapi.ECONNREJ) {
+		t.Errorf("#%d: %v is not ECONNREJ", backlog, err)
+	}
+}
+
+func TestListenConfigIPv6Only(t *testing.T) {
+	if !supportsIPv4() || !supportsIPv6() || !supportsIPv4map() {
+		t.Skip("dual stack is required")
+	}
+	for _, ipv6Only := range []bool{true, false} {
+		lc := ListenConfig{IPv6Only: &ipv6Only}
+		ln, err := lc.Listen(context.Background(), "srt", "[::]:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			if c, err := ln.Accept(); err == nil {
+				c.Close()
+			}
+		}()
+		_, port, _ := net.SplitHostPort(ln.Addr().String())
+		d := Dialer{Timeout: time.Second}
+		c, err := d.Dial("srt4", net.JoinHostPort("127.0.0.1", port))
+		if err == nil {
+			c.Close()
+		}
+		if ipv6Only && err == nil {
+			t.Error("IPv6Only true: IPv4 caller connected")
+		}
+		if !ipv6Only && err != nil {
+			t.Errorf("IPv6Only false: IPv4 caller failed: %v", err)
+		}
+		ln.Close()
+	}
+}
+
+var listenAddrFamilyTests = []struct {
+	network string
+	ip      net.IP
+	family  int // 0 for a family mismatch
+}{
+	{"srt4", net.IPv4zero, syscall.AF_INET},
+	{"srt4", net.IPv4(127, 0, 0, 1), syscall.AF_INET},
+	{"srt4", net.IPv6loopback, 0},
+
+	{"srt6", net.IPv6unspecified, syscall.AF_INET6},
+	{"srt6", net.IPv6loopback, syscall.AF_INET6},
+	{"srt6", net.IPv4(127, 0, 0, 1), 0},
+
+	{"srt", net.IPv4(127, 0, 0, 1), syscall.AF_INET},
+	{"srt", net.IPv6loopback, syscall.AF_INET6},
+}
+
+func TestListenAddrFamily(t *testing.T) {
+	for _, tt := range listenAddrFamilyTests {
+		if tt.ip.To4() == nil && !supportsIPv6() || tt.ip.To4() != nil && !supportsIPv4() {
+			continue
+		}
+		laddr := &SRTAddr{IP: tt.ip}
+		for i, listen := range []func() (*SRTListener, error){
+			func() (*SRTListener, error) { return ListenSRT(tt.network, laddr) },
+			func() (*SRTListener, error) {
+				ln, err := Listen(tt.network, laddr.String())
+				if err != nil {
+					return nil, err
+				}
+				return ln.(*SRTListener), nil
+			},
+		} {
+			ln, err := listen()
+			if tt.family == 0 {
+				if err == nil {
+					ln.Close()
+					t.Errorf("#%d: %s on %v succeeded; want error", i, tt.network, laddr)
+					continue
+				}
+				if perr := parseDialError(err); perr != nil {
+					t.Error(perr)
+				}
+				var ae *net.AddrError
+				if !errors.As(err, &ae) {
+					t.Errorf("#%d: %s on %v: got %v; want AddrError", i, tt.network, laddr, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("#%d: %s on %v: %v", i, tt.network, laddr, err)
+				continue
+			}
+			if ln.fd.family != tt.family {
+				t.Errorf("#%d: %s on %v got %v; want %v", i, tt.network, laddr, ln.fd.family, tt.family)
+			}
+			if a := ln.Addr().(*SRTAddr); a.IP.To4() == nil != (tt.family == syscall.AF_INET6) {
+				t.Errorf("#%d: %s on %v listens on %v", i, tt.network, laddr, a)
+			}
+			ln.Close()
+		}
+	}
+
+	// ListenSRT reports the mismatch itself, rather than leaving it
+	// to the resolver.
+	_, err := ListenSRT("srt4", &SRTAddr{IP: net.IPv6loopback})
+	var ae *net.AddrError
+	if !errors.As(err, &ae) || ae.Err != errLocalAddrFamily.Error() {
+		t.Errorf("got %v; want %v", err, errLocalAddrFamily)
+	}
+}
+
+func TestListenConfigInterfaceName(t *testing.T) {
+	lc := ListenConfig{InterfaceName: "gosrt-nonexistent"}
+	ln, err := lc.Listen(context.Background(), "srt4", "0.0.0.0:0")
+	if err == nil {
+		ln.Close()
+		t.Fatal("listen on nonexistent interface succeeded")
+	}
+	if runtime.GOOS != "linux" {
+		if !errors.Is(err, errBindToDevice) {
+			t.Fatalf("got %v; want %v", err, errBindToDevice)
+		}
+		return
+	}
+	if errors.Is(err, syscall.EPERM) {
+		t.Skip("SO_BINDTODEVICE not permitted")
+	}
+	if !errors.Is(err, syscall.ENODEV) {
+		t.Fatalf("got %v; want %v", err, syscall.ENODEV)
+	}
+
+	lo := loopbackInterface()
+	if lo == nil {
+		t.Skip("no loopback interface")
+	}
+	var other string
+	if ift, err := net.Interfaces(); err == nil {
+		for _, ifi := range ift {
+			if ifi.Flags&net.FlagLoopback == 0 && ifi.Flags&net.FlagUp != 0 {
+				other = ifi.Name
+				break
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		ifname string
+		accept bool
+	}{
+		{lo.Name, true},
+		{other, false},
+	} {
+		if tt.ifname == "" {
+			continue
+		}
+		lc := ListenConfig{InterfaceName: tt.ifname}
+		ln, err := lc.Listen(context.Background(), "srt4", "0.0.0.0:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			c, err := ln.Accept()
+			if err == nil {
+				c.Close()
+			}
+		}()
+		// Packets to 127.0.0.1 arrive through the loopback
+		// interface only.
+		port := ln.Addr().(*SRTAddr).Port
+		ctx := WithOptions(context.Background(), Options("conntimeo", "1000"))
+		var d Dialer
+		c, err := d.DialContext(ctx, "srt4", net.JoinHostPort("127.0.0.1", fmt.Sprint(port)))
+		if tt.accept && err != nil {
+			t.Errorf("listener on %s: %v", tt.ifname, err)
+		}
+		if !tt.accept && err == nil {
+			t.Errorf("listener on %s accepted a connection through %s", tt.ifname, lo.Name)
+		}
+		if err == nil {
+			c.Close()
+		}
+		ln.Close()
+	}
+}
+
+func TestListenConfigOnAccept(t *testing.T) {
+	type call struct {
+		raddr    *SRTAddr
+		streamID string
+	}
+	calls := make(chan call, 2)
+	lc := ListenConfig{OnAccept: func(raddr *SRTAddr, streamID string) string {
+		calls <- call{raddr, streamID}
+		return "encoder@" + raddr.IP.String()
+	}}
+	ln, err := lc.Listen(context.Background(), "srt4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	for _, streamID := range []string{"", "cam1"} {
+		accepted := make(chan *SRTConn, 1)
+		go func() {
+			c, err := ln.(*SRTListener).AcceptSRT()
+			if err != nil {
+				t.Error(err)
+			}
+			accepted <- c
+		}()
+		ctx := context.Background()
+		if streamID != "" {
+			ctx = WithOptions(ctx, Options("streamid", streamID))
+		}
+		var d Dialer
+		c, err := d.DialContext(ctx, "srt4", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := <-accepted
+		if s == nil {
+			c.Close()
+			t.FailNow()
+		}
+
+		got := <-calls
+		la := c.LocalAddr().(*SRTAddr)
+		if got.raddr == nil || !got.raddr.IP.Equal(la.IP) || got.raddr.Port != la.Port {
+			t.Errorf("OnAccept got address %v; want %v", got.raddr, la)
+		}
+		if got.streamID != streamID {
+			t.Errorf("OnAccept got stream ID %q; want %q", got.streamID, streamID)
+		}
+		if ra := s.RemoteAddr().(*SRTAddr); !ra.IP.Equal(la.IP) || ra.Port != la.Port {
+			t.Errorf("RemoteAddr() = %v; want %v", ra, la)
+		}
+		if key := s.RouteKey(); key != "encoder@127.0.0.1" {
+			t.Errorf("RouteKey() = %q; want %q", key, "encoder@127.0.0.1")
+		}
+		s.Close()
+		c.Close()
+	}
+}
@@ -19,15 +19,39 @@ var DefaultResolver = &Resolver{nr: net.DefaultResolver}
 
 // A Resolver looks up names and numbers.
 //
-// A nil *Resolver is equivalent to a zero Resolver.
+// A nil *Resolver is equivalent to a zero Resolver, which uses
+// net.DefaultResolver.
 type Resolver struct {
 	nr *net.Resolver
 }
 
+// NewResolver returns a Resolver that looks names up with r, such as
+// a resolver pinned to a DNS server through its Dial field. A nil r
+// stands for net.DefaultResolver.
+func NewResolver(r *net.Resolver) *Resolver {
+	return &Resolver{nr: r}
+}
+
+func (r *Resolver) netResolver() *net.Resolver {
+	if r == nil || r.nr == nil {
+		return net.DefaultResolver
+	}
+	return r.nr
+}
+
 // LookupIPAddr looks up host using the local resolver.
 // It returns a slice of that host's IPv4 and IPv6 addresses.
+// The lookup is canceled when ctx is done.
 func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
-	return r.nr.LookupIPAddr(ctx, host)
+	return testHookLookupIP(ctx, r.netResolver().LookupIPAddr, host)
+}
+
+// LookupSRV looks up the SRV records of the given service, protocol
+// and domain name, as net.Resolver.LookupSRV does. SRT endpoints
+// announced by SRV records are dialed at net.JoinHostPort(srv.Target,
+// strconv.Itoa(int(srv.Port))).
+func (r *Resolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return r.netResolver().LookupSRV(ctx, service, proto, name)
 }
 
 // LookupPort looks up the port for the given network and service.
@@ -10,7 +10,10 @@ package srt
 
 import (
 	"context"
+	"errors"
 	"net"
+	"sync/atomic"
+	"testing"
 )
 
 func lookupLocalhost(ctx context.Context, fn func(context.Context, string) ([]net.IPAddr, error), host string) ([]net.IPAddr, error) {
@@ -24,3 +27,64 @@ func lookupLocalhost(ctx context.Context, fn func(context.Context, string) ([]ne
 		return fn(ctx, host)
 	}
 }
+
+func TestDialerResolver(t *testing.T) {
+	var dials int32
+	errDNS := errors.New("custom resolver reached")
+	d := Dialer{Resolver: NewResolver(&net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return nil, errDNS
+		},
+	})}
+	c, err := d.Dial("srt", "srt.example.com:9000")
+	if err == nil {
+		c.Close()
+		t.Fatal("dial through a failing resolver succeeded")
+	}
+	if atomic.LoadInt32(&dials) == 0 {
+		t.Errorf("custom resolver not used; got %v", err)
+	}
+
+	// Literal addresses need no lookup.
+	atomic.StoreInt32(&dials, 0)
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+	if c, err = d.Dial("srt", ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := atomic.LoadInt32(&dials); n != 0 {
+		t.Errorf("custom resolver used %d times for a literal address", n)
+	}
+
+	// A canceled context cancels the lookup.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d = Dialer{Resolver: NewResolver(&net.Resolver{PreferGo: true})}
+	if c, err := d.DialContext(ctx, "srt", "srt.example.com:9000"); err == nil {
+		c.Close()
+		t.Fatal("dial with a canceled context succeeded")
+	}
+}
+
+func TestNilResolver(t *testing.T) {
+	var r *Resolver
+	addrs, err := r.LookupIPAddr(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("got %v; want [127.0.0.1]", addrs)
+	}
+}
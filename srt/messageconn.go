@@ -0,0 +1,148 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A MessageSizeError is returned by writes in message mode, and by
+// the WriteTo method of an SRTMessageConn, when a message exceeds the
+// maximum message size.
+type MessageSizeError struct {
+	Size int // size of the message
+	Max  int // maximum message size of the connection
+}
+
+func (e *MessageSizeError) Error() string {
+	return "message of " + strconv.Itoa(e.Size) + " bytes exceeds the maximum message size of " + strconv.Itoa(e.Max) + " bytes"
+}
+
+// Is reports whether target is srtapi.ELARGEMSG, the error libsrt
+// reports oversized messages with.
+func (e *MessageSizeError) Is(target error) bool {
+	return target == srtapi.ELARGEMSG
+}
+
+// errPeerAddr is returned by WriteTo for an address other than that
+// of the peer.
+var errPeerAddr = errors.New("address is not the peer of the connection")
+
+// errStreamAPI is returned by the operations on messages of a
+// connection that uses the buffer API, which keeps no message
+// boundaries.
+var errStreamAPI = errors.New("connection uses the buffer API, not the message API")
+
+// SRTMessageConn adapts a connection in message mode to the
+// net.PacketConn interface: ReadFrom reads one message and WriteTo
+// sends one. A connected SRT socket has a single peer, so the
+// address ReadFrom returns is always the remote address of the
+// connection, and WriteTo only takes that address.
+//
+// The maximum message size is the payload size in live mode, 1316
+// bytes by default, and the size of the send buffer in file mode
+// with the message API on. WriteTo fails with a MessageSizeError
+// rather than truncate a larger message.
+type SRTMessageConn struct {
+	c   *SRTConn
+	max int
+}
+
+var _ net.PacketConn = &SRTMessageConn{}
+
+// NewSRTMessageConn returns a PacketConn adapter for c, which must be
+// in message mode. It fails for a connection that uses the buffer API.
+func NewSRTMessageConn(c *SRTConn) (*SRTMessageConn, error) {
+	if !c.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	if c.fd.streamAPI {
+		return nil, errStreamAPI
+	}
+	max, err := maxMessageSize(c.fd)
+	if err != nil {
+		return nil, &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return &SRTMessageConn{c: c, max: max}, nil
+}
+
+func maxMessageSize(fd *netFD) (int, error) {
+	size, err := srtapi.GetsockoptInt(fd.pfd.Sysfd, 0, srtapi.OptionPayloadsize)
+	if err != nil {
+		return 0, wrapSyscallError("getsockopt", err)
+	}
+	if size > 0 {
+		return size, nil
+	}
+	size, err = srtapi.GetsockoptInt(fd.pfd.Sysfd, 0, srtapi.OptionSndbuf)
+	return size, wrapSyscallError("getsockopt", err)
+}
+
+// Conn returns the underlying connection.
+func (c *SRTMessageConn) Conn() *SRTConn { return c.c }
+
+// MaxMessageSize returns the size of the largest message WriteTo
+// sends.
+func (c *SRTMessageConn) MaxMessageSize() int { return c.max }
+
+// ReadFrom implements the PacketConn ReadFrom method. It reads one
+// message into p; a message larger than p makes the read fail, so p
+// should be MaxMessageSize bytes long.
+func (c *SRTMessageConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, err := c.c.Read(p)
+	if err == io.EOF {
+		return n, nil, err
+	}
+	return n, c.c.RemoteAddr(), err
+}
+
+// WriteTo implements the PacketConn WriteTo method. It sends p as one
+// message to addr, which must be the remote address of the
+// connection.
+func (c *SRTMessageConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	fd := c.c.fd
+	if addr == nil {
+		return 0, &OpError{Op: "write", Net: fd.net, Source: fd.laddr, Err: errMissingAddress}
+	}
+	if !samePeer(addr, fd.raddr) {
+		return 0, &OpError{Op: "write", Net: fd.net, Source: fd.laddr, Addr: addr, Err: errPeerAddr}
+	}
+	if len(p) > c.max {
+		return 0, &OpError{Op: "write", Net: fd.net, Source: fd.laddr, Addr: fd.raddr, Err: &MessageSizeError{Size: len(p), Max: c.max}}
+	}
+	return c.c.Write(p)
+}
+
+func samePeer(addr, raddr net.Addr) bool {
+	if raddr == nil {
+		return false
+	}
+	a, ok := addr.(*SRTAddr)
+	if !ok {
+		return addr.String() == raddr.String()
+	}
+	r, ok := raddr.(*SRTAddr)
+	return ok && a.Port == r.Port && a.IP.Equal(r.IP) && a.Zone == r.Zone
+}
+
+// Close closes the underlying connection.
+func (c *SRTMessageConn) Close() error { return c.c.Close() }
+
+// LocalAddr returns the local network address.
+func (c *SRTMessageConn) LocalAddr() net.Addr { return c.c.LocalAddr() }
+
+// SetDeadline implements the PacketConn SetDeadline method.
+func (c *SRTMessageConn) SetDeadline(t time.Time) error { return c.c.SetDeadline(t) }
+
+// SetReadDeadline implements the PacketConn SetReadDeadline method.
+func (c *SRTMessageConn) SetReadDeadline(t time.Time) error { return c.c.SetReadDeadline(t) }
+
+// SetWriteDeadline implements the PacketConn SetWriteDeadline method.
+func (c *SRTMessageConn) SetWriteDeadline(t time.Time) error { return c.c.SetWriteDeadline(t) }
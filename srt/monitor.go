@@ -0,0 +1,283 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A statsMonitor samples a connection every interval, for StartMonitor,
+// OnKeyStateChange and SetIdleTimeout. The shared sampler takes the samples, and
+// hands them to a goroutine of the monitor's own that runs the
+// callback, so that a slow callback only delays its own connection. A
+// sample the callback is not ready for is merged with the one waiting,
+// if any: by default the latest replaces it.
+type statsMonitor struct {
+	fd       *netFD
+	interval time.Duration
+	sample   func(s int) (interface{}, error) // nil, nil for nothing to deliver
+	merge    func(waiting, latest interface{}) interface{}
+	fn       func(interface{})
+
+	// guarded by the sampler
+	next    time.Time // of the next sample
+	index   int       // in the sampler heap, or -1
+	stopped bool
+
+	samples  chan interface{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Kinds of the monitors of a connection.
+const (
+	monitorStats = iota
+	monitorKeyState
+	monitorIdle
+	numMonitors
+)
+
+func newStatsMonitor(fd *netFD, interval time.Duration, sample func(s int) (interface{}, error), fn func(interface{})) *statsMonitor {
+	m := &statsMonitor{
+		fd:       fd,
+		interval: interval,
+		sample:   sample,
+		fn:       fn,
+		next:     time.Now().Add(interval),
+		index:    -1,
+		samples:  make(chan interface{}, 1),
+		done:     make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+func sampleStats(s int) (interface{}, error) {
+	mon, err := srtapi.Bistats(s, false, true)
+	if err != nil {
+		return nil, err
+	}
+	return newSRTStats(mon), nil
+}
+
+func (m *statsMonitor) run() {
+	for {
+		select {
+		case s := <-m.samples:
+			select {
+			case <-m.done:
+				return
+			default:
+			}
+			m.fn(s)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// deliver hands s to the callback, merged with the sample waiting if
+// any.
+func (m *statsMonitor) deliver(s interface{}) {
+	for {
+		select {
+		case m.samples <- s:
+			return
+		default:
+		}
+		select {
+		case waiting := <-m.samples:
+			if m.merge != nil {
+				s = m.merge(waiting, s)
+			}
+		default:
+		}
+	}
+}
+
+// stop takes m off the sampler and ends its goroutine once the
+// callback in progress, if any, returns.
+func (m *statsMonitor) stop() {
+	m.stopOnce.Do(func() {
+		defaultSampler.remove(m)
+		close(m.done)
+	})
+}
+
+// A statsSampler takes the samples of all the monitors from a single
+// goroutine, which runs while there is a monitor to sample.
+type statsSampler struct {
+	mu      sync.Mutex
+	queue   monitorQueue
+	running bool
+	wake    chan struct{}
+}
+
+var defaultSampler = &statsSampler{wake: make(chan struct{}, 1)}
+
+func (s *statsSampler) add(m *statsMonitor) {
+	s.mu.Lock()
+	if m.stopped {
+		// The connection was closed as the monitor started.
+		s.mu.Unlock()
+		return
+	}
+	heap.Push(&s.queue, m)
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *statsSampler) remove(m *statsMonitor) {
+	s.mu.Lock()
+	m.stopped = true
+	if m.index >= 0 {
+		heap.Remove(&s.queue, m.index)
+	}
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *statsSampler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *statsSampler) run() {
+	t := time.NewTimer(time.Hour)
+	defer t.Stop()
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		m := s.queue[0]
+		now := time.Now()
+		if wait := m.next.Sub(now); wait > 0 {
+			s.mu.Unlock()
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+			t.Reset(wait)
+			select {
+			case <-t.C:
+			case <-s.wake:
+			}
+			continue
+		}
+		// Keep to the cadence, but do not make up for samples missed.
+		m.next = m.next.Add(m.interval)
+		if m.next.Before(now) {
+			m.next = now.Add(m.interval)
+		}
+		heap.Fix(&s.queue, 0)
+		s.mu.Unlock()
+
+		v, err := m.sample(m.fd.pfd.Sysfd)
+		if err != nil {
+			// The connection is gone.
+			m.stop()
+			continue
+		}
+		if v != nil {
+			m.deliver(v)
+		}
+	}
+}
+
+// monitorQueue is a heap of monitors by the time of their next sample.
+type monitorQueue []*statsMonitor
+
+func (q monitorQueue) Len() int           { return len(q) }
+func (q monitorQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+
+func (q monitorQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *monitorQueue) Push(x interface{}) {
+	m := x.(*statsMonitor)
+	m.index = len(*q)
+	*q = append(*q, m)
+}
+
+func (q *monitorQueue) Pop() interface{} {
+	old := *q
+	m := old[len(old)-1]
+	old[len(old)-1] = nil
+	m.index = -1
+	*q = old[:len(old)-1]
+	return m
+}
+
+// setMonitor replaces the monitor of the kind of fd with m, which may
+// be nil, and starts m.
+func (fd *netFD) setMonitor(kind int, m *statsMonitor) {
+	fd.monMu.Lock()
+	old := fd.mons[kind]
+	fd.mons[kind] = m
+	fd.monMu.Unlock()
+	if old != nil {
+		old.stop()
+	}
+	if m != nil {
+		defaultSampler.add(m)
+	}
+}
+
+// stopMonitors stops all the monitors of fd.
+func (fd *netFD) stopMonitors() {
+	for kind := 0; kind < numMonitors; kind++ {
+		fd.setMonitor(kind, nil)
+	}
+}
+
+// StartMonitor calls fn with the statistics of the connection every
+// interval, as SRTStats(false) returns them, until StopMonitor is
+// called or the connection is closed. It replaces the monitor started
+// before, if any.
+//
+// The samples of all connections are taken by a single goroutine,
+// while fn runs on a goroutine of the connection's own, one call at a
+// time. A callback slower than the interval delays no other
+// connection; it misses samples instead, getting the latest one when
+// it returns. Sampling stops on its own once the statistics cannot be
+// had, as when the socket is gone.
+func (c *SRTConn) StartMonitor(interval time.Duration, fn func(*SRTStats)) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if interval <= 0 || fn == nil {
+		return &OpError{Op: "monitor", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: errors.New("invalid monitor interval " + interval.String() + " or nil callback")}
+	}
+	m := newStatsMonitor(c.fd, interval, sampleStats, func(v interface{}) { fn(v.(*SRTStats)) })
+	c.fd.setMonitor(monitorStats, m)
+	return nil
+}
+
+// StopMonitor stops the monitor of StartMonitor, if any. It does not
+// wait for a callback in progress, which runs to its end.
+func (c *SRTConn) StopMonitor() {
+	if !c.ok() {
+		return
+	}
+	c.fd.setMonitor(monitorStats, nil)
+}
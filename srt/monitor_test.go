@@ -0,0 +1,102 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestStatsMonitorDeliver(t *testing.T) {
+	release := make(chan struct{})
+	got := make(chan *SRTStats, 3)
+	m := newStatsMonitor(nil, time.Second, sampleStats, func(v interface{}) {
+		got <- v.(*SRTStats)
+		<-release
+	})
+	defer m.stop()
+
+	first, second, third := &SRTStats{}, &SRTStats{}, &SRTStats{}
+	m.deliver(first)
+	if s := <-got; s != first {
+		t.Fatal("first sample not delivered")
+	}
+	// The callback is busy: the third sample replaces the second.
+	m.deliver(second)
+	m.deliver(third)
+	release <- struct{}{}
+	if s := <-got; s != third {
+		t.Error("callback did not get the latest sample")
+	}
+	release <- struct{}{}
+	select {
+	case <-got:
+		t.Error("stale sample delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSRTConnStartMonitor(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	c, err := Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := <-accepted; s != nil {
+		defer s.Close()
+	}
+	sc := c.(*SRTConn)
+
+	if err := sc.StartMonitor(0, func(*SRTStats) {}); err == nil {
+		t.Error("StartMonitor with a zero interval succeeded")
+	}
+	if err := sc.StartMonitor(time.Millisecond, nil); err == nil {
+		t.Error("StartMonitor with a nil callback succeeded")
+	}
+
+	before := runtime.NumGoroutine()
+	samples := make(chan *SRTStats, 100)
+	if err := sc.StartMonitor(10*time.Millisecond, func(s *SRTStats) { samples <- s }); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case s := <-samples:
+			if s == nil {
+				t.Fatal("nil sample")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("sample %d not taken", i)
+		}
+	}
+
+	// Closing the connection stops the monitor, and with it the
+	// sampler, this being the only connection monitored.
+	c.Close()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left running after Close; want %d", n, before)
+	}
+	for len(samples) > 0 {
+		<-samples
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(samples) > 0 {
+		t.Error("sampled after Close")
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// The smallest MTUs IPv4 and IPv6 hosts must handle, which AutoMTU
+// does not go below.
+const (
+	minMTU4 = 576
+	minMTU6 = 1280
+)
+
+// defaultLivePayloadSize is the payload size of live mode in libsrt,
+// SRT_LIVE_DEF_PLSIZE.
+const defaultLivePayloadSize = 1316
+
+// A HandshakeTimeoutError is returned by dials that got no answer to
+// their handshake before the connect timeout. Besides an unreachable
+// or silent listener, a path MTU smaller than the MSS is a common
+// cause: the packets of that size are dropped, often without any ICMP
+// error making it back.
+type HandshakeTimeoutError struct {
+	// MSS is the MSS the handshake was attempted with.
+	MSS int
+}
+
+func (e *HandshakeTimeoutError) Error() string {
+	return "handshake timed out with MSS " + strconv.Itoa(e.MSS) + "; the path MTU may be smaller (see SetMSS and Dialer.AutoMTU)"
+}
+
+// Is reports whether target is srtapi.ENOSERVER, the error libsrt
+// reports connect timeouts with.
+func (e *HandshakeTimeoutError) Is(target error) bool {
+	return target == srtapi.ENOSERVER
+}
+
+// Timeout returns true.
+func (e *HandshakeTimeoutError) Timeout() bool { return true }
+
+// Temporary returns true.
+func (e *HandshakeTimeoutError) Temporary() bool { return true }
+
+// nextMSS returns the MSS to retry a handshake with after one with mss
+// timed out: half of it, but no less than floor. ok is false once
+// floor was tried.
+func nextMSS(mss, floor int) (next int, ok bool) {
+	if mss <= floor {
+		return 0, false
+	}
+	if next = mss / 2; next < floor {
+		next = floor
+	}
+	return next, true
+}
+
+// mssFloor returns the smallest MSS AutoMTU tries for raddr: the
+// smallest MTU of its family, or more in live mode, where a packet must
+// still hold a message of the payload size with its headers.
+func mssFloor(ctx context.Context, raddr *SRTAddr) int {
+	floor := minMTU4
+	if raddr.IP.To4() == nil {
+		floor = minMTU6
+	}
+	if transtype, _ := Option(ctx, "transtype"); transtypeValue(transtype) != "1" {
+		payload := defaultLivePayloadSize
+		if v, ok := Option(ctx, "payloadsize"); ok {
+			if n, err := strconv.Atoi(v); err == nil {
+				payload = n
+			}
+		}
+		if payload+srtHeaderSize > floor {
+			floor = payload + srtHeaderSize
+		}
+	}
+	return floor
+}
+
+// mssAttempts returns the number of handshakes AutoMTU has left to
+// try, from mss down to floor.
+func mssAttempts(mss, floor int) int {
+	n := 1
+	for ok := true; ; n++ {
+		if mss, ok = nextMSS(mss, floor); !ok {
+			return n
+		}
+	}
+}
+
+// attemptTimeout returns the connect timeout of each of the attempts
+// AutoMTU has left: the time to the deadline of ctx shared among them,
+// or the libsrt default without a deadline.
+func attemptTimeout(ctx context.Context, attempts int) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return defaultConnTimeout
+	}
+	d := time.Until(deadline) / time.Duration(attempts)
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return d
+}
+
+// dialSRTAutoMTU dials like dialSRT, retrying with a smaller MSS each
+// time the handshake times out, as set out for Dialer.AutoMTU.
+func dialSRTAutoMTU(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+	mss := maxMSS
+	if v, ok := Option(ctx, "mss"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			mss = n
+		}
+	}
+	floor := mssFloor(ctx, raddr)
+	_, conntimeo := Option(ctx, "conntimeo")
+	for {
+		opts := []string{"mss", strconv.Itoa(mss)}
+		if !conntimeo {
+			// Keep setConnTimeout from giving the whole deadline to
+			// the first attempt.
+			ms := attemptTimeout(ctx, mssAttempts(mss, floor)) / time.Millisecond
+			opts = append(opts, "conntimeo", strconv.FormatInt(int64(ms), 10))
+		}
+		c, err := dialSRT(WithOptions(ctx, Options(opts...)), network, laddr, raddr)
+		var herr *HandshakeTimeoutError
+		if err == nil || !errors.As(err, &herr) {
+			return c, err
+		}
+		var ok bool
+		if mss, ok = nextMSS(mss, floor); !ok {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		default:
+		}
+	}
+}
+
+// ConnectionInfo describes what the handshake of a connection settled
+// on.
+type ConnectionInfo struct {
+	// MSS is the maximum segment size, the smaller of those of both
+	// peers. With Dialer.AutoMTU, it is the one the handshake
+	// finally went through with.
+	MSS int
+
+	// PayloadSize is the maximum size of a message, or 0 if messages
+	// are not limited to one packet.
+	PayloadSize int
+
+	// StreamID is the stream ID the caller sent.
+	StreamID string
+
+	// PeerVersion is the libsrt version of the peer, as "1.4.2".
+	PeerVersion string
+}
+
+// ConnectionInfo returns what the handshake of the connection settled
+// on.
+func (c *SRTConn) ConnectionInfo() (*ConnectionInfo, error) {
+	if !c.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	var info ConnectionInfo
+	var err error
+	if info.MSS, err = c.MSS(); err != nil {
+		return nil, c.infoError(err)
+	}
+	if info.PayloadSize, err = c.PayloadSize(); err != nil {
+		return nil, c.infoError(err)
+	}
+	if info.StreamID, err = c.StreamID(); err != nil {
+		return nil, c.infoError(err)
+	}
+	v, err := c.peerVersion()
+	if err != nil {
+		return nil, err
+	}
+	info.PeerVersion = versionString(v)
+	return &info, nil
+}
+
+func (c *SRTConn) infoError(err error) error {
+	return &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockopt", err)}
+}
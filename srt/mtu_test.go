@@ -0,0 +1,168 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+func TestNextMSS(t *testing.T) {
+	for _, tt := range []struct {
+		floor int
+		want  []int
+	}{
+		{minMTU4, []int{1500, 750, 576}},
+		{minMTU6, []int{1500, 1280}},
+	} {
+		got := []int{maxMSS}
+		for mss, ok := nextMSS(maxMSS, tt.floor); ok; mss, ok = nextMSS(mss, tt.floor) {
+			got = append(got, mss)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("floor %d: got %v; want %v", tt.floor, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("floor %d: got %v; want %v", tt.floor, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMSSFloor(t *testing.T) {
+	v4 := &SRTAddr{IP: net.IPv4(192, 0, 2, 1)}
+	v6 := &SRTAddr{IP: net.ParseIP("2001:db8::1")}
+	for _, tt := range []struct {
+		options []string
+		raddr   *SRTAddr
+		want    int
+	}{
+		{nil, v4, defaultLivePayloadSize + srtHeaderSize},
+		{nil, v6, defaultLivePayloadSize + srtHeaderSize},
+		{[]string{"payloadsize", "1000"}, v4, 1000 + srtHeaderSize},
+		{[]string{"payloadsize", "500"}, v4, minMTU4},
+		{[]string{"payloadsize", "1000"}, v6, minMTU6},
+		{[]string{"transtype", "file"}, v4, minMTU4},
+		{[]string{"transtype", "1"}, v6, minMTU6},
+	} {
+		ctx := context.Background()
+		if tt.options != nil {
+			ctx = WithOptions(ctx, Options(tt.options...))
+		}
+		if got := mssFloor(ctx, tt.raddr); got != tt.want {
+			t.Errorf("%v to %v: got %d; want %d", tt.options, tt.raddr, got, tt.want)
+		}
+	}
+}
+
+func TestAutoMTUAttemptTimeout(t *testing.T) {
+	defer func() { testHookDialSRT = nil }()
+	errStop := errors.New("stop")
+	type attempt struct {
+		mss, conntimeo string
+	}
+	var attempts []attempt
+	testHookDialSRT = func(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+		mss, _ := Option(ctx, "mss")
+		conntimeo, _ := Option(ctx, "conntimeo")
+		attempts = append(attempts, attempt{mss, conntimeo})
+		if len(attempts) == 1 {
+			return nil, &HandshakeTimeoutError{MSS: maxMSS}
+		}
+		return nil, errStop
+	}
+	raddr := &SRTAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5000}
+
+	// A Timeout over the 3s default is shared among the 3 attempts
+	// down to 576 bytes, rather than all given to the first.
+	ctx, cancel := context.WithTimeout(WithOptions(context.Background(), Options("transtype", "file")), 10*time.Second)
+	defer cancel()
+	if _, err := dialSRTAutoMTU(ctx, "srt", nil, raddr); err != errStop {
+		t.Fatalf("dialSRTAutoMTU = %v; want the error of the second attempt", err)
+	}
+	if len(attempts) != 2 || attempts[1].mss != "750" {
+		t.Fatalf("attempts = %v; want a second one with MSS 750", attempts)
+	}
+	for i, a := range attempts {
+		ms, err := strconv.Atoi(a.conntimeo)
+		if err != nil || ms <= 0 || ms > 10000/(3-i) {
+			t.Errorf("attempt %d: conntimeo = %q; want at most a share of the time left", i, a.conntimeo)
+		}
+	}
+
+	// An explicit conntimeo is left alone.
+	attempts = nil
+	ctx = WithOptions(ctx, Options("conntimeo", "8000"))
+	dialSRTAutoMTU(ctx, "srt", nil, raddr)
+	for i, a := range attempts {
+		if a.conntimeo != "8000" {
+			t.Errorf("attempt %d: conntimeo = %q; want 8000", i, a.conntimeo)
+		}
+	}
+
+	// Without a deadline, each attempt takes the libsrt default.
+	if d := attemptTimeout(context.Background(), 3); d != defaultConnTimeout {
+		t.Errorf("attemptTimeout without a deadline = %v; want %v", d, defaultConnTimeout)
+	}
+}
+
+func TestHandshakeTimeoutError(t *testing.T) {
+	var err error = &OpError{Op: "dial", Net: "srt", Err: &HandshakeTimeoutError{MSS: 1500}}
+	if !errors.Is(err, srtapi.ENOSERVER) {
+		t.Errorf("%v is not ENOSERVER", err)
+	}
+	if !strings.Contains(err.Error(), "MTU") {
+		t.Errorf("%q does not hint at the MTU", err)
+	}
+	if nerr, ok := err.(interface{ Timeout() bool }); !ok || !nerr.Timeout() {
+		t.Errorf("%v is not a timeout", err)
+	}
+}
+
+func TestSRTConnConnectionInfo(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			var b [1]byte
+			c.Read(b[:])
+		}
+	}()
+
+	d := Dialer{AutoMTU: true}
+	if err := d.SetMSS(1360); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithOptions(context.Background(), Options("streamid", "info"))
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	info, err := c.(*SRTConn).ConnectionInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MSS != 1360 || info.StreamID != "info" || info.PayloadSize <= 0 {
+		t.Errorf("got %+v; want MSS 1360, stream ID \"info\" and a payload size", info)
+	}
+	if _, _, _, v := Version(); info.PeerVersion != v {
+		t.Errorf("got peer version %s; want %s, that of the local libsrt", info.PeerVersion, v)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// The methods below build an OptionSet option by option. Each returns
+// a new set, leaving its receiver alone, so that a set can be built
+// once, checked with Validate, and shared between goroutines:
+//
+//	opts := srt.Options().Latency(200 * time.Millisecond).StreamID("cam1")
+//	if err := opts.Validate(); err != nil {
+//		return err
+//	}
+//	c, err := d.DialContext(srt.WithOptions(ctx, opts), "srt", addr)
+
+// Set returns the set with the option name set to value.
+func (o OptionSet) Set(name, value string) OptionSet {
+	list := make([]option, len(o.list), len(o.list)+1)
+	copy(list, o.list)
+	return OptionSet{list: append(list, option{key: name, value: value})}
+}
+
+// Latency returns the set with the latency set to d, rounded down to
+// whole milliseconds.
+func (o OptionSet) Latency(d time.Duration) OptionSet {
+	return o.Set("latency", strconv.FormatInt(int64(d/time.Millisecond), 10))
+}
+
+// Passphrase returns the set with the passphrase set.
+func (o OptionSet) Passphrase(passphrase string) OptionSet {
+	return o.Set("passphrase", passphrase)
+}
+
+// StreamID returns the set with the stream ID set.
+func (o OptionSet) StreamID(id string) OptionSet {
+	return o.Set("streamid", id)
+}
+
+// MaxBW returns the set with the maximum send bandwidth set, in bytes
+// per second; -1 means no limit.
+func (o OptionSet) MaxBW(bytesPerSecond int64) OptionSet {
+	return o.Set("maxbw", strconv.FormatInt(bytesPerSecond, 10))
+}
+
+// MSS returns the set with the maximum segment size set, in bytes.
+func (o OptionSet) MSS(bytes int) OptionSet {
+	return o.Set("mss", strconv.Itoa(bytes))
+}
+
+// PayloadSize returns the set with the payload size set, in bytes.
+func (o OptionSet) PayloadSize(bytes int) OptionSet {
+	return o.Set("payloadsize", strconv.Itoa(bytes))
+}
+
+// FlowControlWindow returns the set with the flow control window set,
+// in packets.
+func (o OptionSet) FlowControlWindow(packets int) OptionSet {
+	return o.Set("fc", strconv.Itoa(packets))
+}
+
+// Buffers returns the set with the send and receive buffer sizes set,
+// in bytes.
+func (o OptionSet) Buffers(sndbuf, rcvbuf int) OptionSet {
+	return o.Set("sndbuf", strconv.Itoa(sndbuf)).Set("rcvbuf", strconv.Itoa(rcvbuf))
+}
+
+// optionMap returns the options of the set, later ones overriding
+// earlier ones with the same name.
+func (o OptionSet) optionMap() optionMap {
+	options := make(optionMap, len(o.list))
+	for _, opt := range o.list {
+		options[opt.key] = opt.value
+	}
+	return options
+}
+
+// Validate checks the options of the set as a whole: each must be
+// known and have a value of its type, and together they must not
+// break the rules between options, such as the flow control window
+// fitting the buffers, or the payload size the MSS. A broken rule is
+// reported as an OptionConflictError naming the options involved.
+func (o OptionSet) Validate() error {
+	return validateOptions(o.optionMap())
+}
+
+func validateOptions(options optionMap) error {
+	for name, value := range options {
+		opt := lookupOption(name)
+		if opt == nil {
+			return unknownOptionError(name)
+		}
+		if _, err := opt.extract(value); err != nil {
+			return errors.New("invalid value " + strconv.Quote(value) + " for option " + name)
+		}
+	}
+	return checkOptions(options)
+}
+
+// SetOptions sets the options of o on top of those already set, as
+// SetOption would one by one, after checking them together with
+// those, as Validate does. On error, none is set.
+func (sc *socketConfig) SetOptions(o OptionSet) error {
+	options := make(optionMap, len(sc.options)+len(o.list))
+	for k, v := range sc.options {
+		options[k] = v
+	}
+	set := o.optionMap()
+	for k, v := range set {
+		options[k] = v
+	}
+	if err := validateOptions(options); err != nil {
+		return err
+	}
+	sc.options = options
+	if _, ok := set["nakreport"]; ok {
+		sc.fecNakOff = false
+	}
+	return nil
+}
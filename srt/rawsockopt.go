@@ -0,0 +1,218 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// The RawSetSockOpt and RawGetSockOpt methods pass one of the SRTO_
+// constants of libsrt, as exported by srtapi as OptionXxx, straight to
+// srt_setsockopt and srt_getsockopt. They reach options this package
+// has no typed method or options table entry for, as newer libsrt
+// versions add them. They bypass the validation of the package: only
+// an option of the options table is checked to be set with the type
+// it takes, and a value to fit its C type. The rest is up to libsrt,
+// whose error is returned as is, wrapped in an *OpError.
+
+// rawOption is an option set by a RawSetSockOpt method of a Dialer or
+// ListenConfig.
+type rawOption struct {
+	opt int
+	val interface{} // int, string or bool
+}
+
+func (o rawOption) apply(s int) error {
+	switch v := o.val.(type) {
+	case int:
+		return srtapi.SetsockoptInt(s, 0, o.opt, v)
+	case string:
+		return srtapi.SetsockoptString(s, 0, o.opt, v)
+	case bool:
+		return srtapi.SetsockoptBool(s, 0, o.opt, v)
+	}
+	return nil
+}
+
+// rawOptionContextKey is the type of contextKeys used for the raw
+// options of a Dialer or ListenConfig.
+type rawOptionContextKey struct{}
+
+func rawOptionValue(ctx context.Context) []rawOption {
+	options, _ := ctx.Value(rawOptionContextKey{}).([]rawOption)
+	return options
+}
+
+var optionTypeNames = []string{
+	typeString: "a string",
+	typeInt:    "a 32-bit integer",
+	typeInt64:  "a 64-bit integer",
+	typeBool:   "a bool",
+}
+
+// checkRawOption returns an error if opt is in the options table and
+// takes another type than typ.
+func checkRawOption(opt, typ int) error {
+	for i := range srtOptions {
+		if o := &srtOptions[i]; o.sym == opt && o.typ != typ {
+			return errors.New("option " + o.name + " takes " + optionTypeNames[o.typ])
+		}
+	}
+	return nil
+}
+
+func checkRawInt(opt, val int) error {
+	if val < math.MinInt32 || val > math.MaxInt32 {
+		return errors.New("value " + strconv.Itoa(val) + " out of range for a 32-bit option")
+	}
+	return checkRawOption(opt, typeInt)
+}
+
+// setRawOption adds o to the raw options of sc. They are applied in
+// the order set, after the named options, before the socket binds.
+func (sc *socketConfig) setRawOption(o rawOption) {
+	raw := make([]rawOption, len(sc.raw), len(sc.raw)+1)
+	copy(raw, sc.raw)
+	sc.raw = append(raw, o)
+}
+
+// RawSetSockOptInt sets the 32-bit integer socket option opt to val
+// for every connection made, bypassing the validation of the package.
+func (sc *socketConfig) RawSetSockOptInt(opt int, val int) error {
+	if err := checkRawInt(opt, val); err != nil {
+		return err
+	}
+	sc.setRawOption(rawOption{opt, val})
+	return nil
+}
+
+// RawSetSockOptString sets the string socket option opt to val for
+// every connection made, bypassing the validation of the package.
+func (sc *socketConfig) RawSetSockOptString(opt int, val string) error {
+	if err := checkRawOption(opt, typeString); err != nil {
+		return err
+	}
+	sc.setRawOption(rawOption{opt, val})
+	return nil
+}
+
+// RawSetSockOptBool sets the bool socket option opt to val for every
+// connection made, bypassing the validation of the package.
+func (sc *socketConfig) RawSetSockOptBool(opt int, val bool) error {
+	if err := checkRawOption(opt, typeBool); err != nil {
+		return err
+	}
+	sc.setRawOption(rawOption{opt, val})
+	return nil
+}
+
+// RawSetSockOptInt sets the 32-bit integer socket option opt to val,
+// bypassing the validation of the package. A connection that is broken
+// or closed is refused before libsrt is called.
+func (c *SRTConn) RawSetSockOptInt(opt int, val int) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	return c.rawSet(checkRawInt(opt, val), func(s int) error {
+		return srtapi.SetsockoptInt(s, 0, opt, val)
+	})
+}
+
+// RawSetSockOptString sets the string socket option opt to val, as
+// RawSetSockOptInt does an integer one.
+func (c *SRTConn) RawSetSockOptString(opt int, val string) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	return c.rawSet(checkRawOption(opt, typeString), func(s int) error {
+		return srtapi.SetsockoptString(s, 0, opt, val)
+	})
+}
+
+// RawSetSockOptBool sets the bool socket option opt to val, as
+// RawSetSockOptInt does an integer one.
+func (c *SRTConn) RawSetSockOptBool(opt int, val bool) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	return c.rawSet(checkRawOption(opt, typeBool), func(s int) error {
+		return srtapi.SetsockoptBool(s, 0, opt, val)
+	})
+}
+
+// rawSet calls set on the socket of c unless err, from checking the
+// value, is set, or the connection is broken or closed.
+func (c *SRTConn) rawSet(err error, set func(s int) error) error {
+	if err == nil {
+		switch state := c.State(); {
+		case state == StateBroken:
+			err = srtapi.ECONNLOST
+		case state > StateBroken:
+			err = net.ErrClosed
+		default:
+			err = wrapSyscallError("setsockopt", set(c.fd.pfd.Sysfd))
+		}
+	}
+	if err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+// RawGetSockOptInt returns the value of the 32-bit integer socket
+// option opt.
+func (c *SRTConn) RawGetSockOptInt(opt int) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	if err := checkRawOption(opt, typeInt); err != nil {
+		return 0, c.rawGetError(err)
+	}
+	v, err := srtapi.GetsockoptInt(c.fd.pfd.Sysfd, 0, opt)
+	if err != nil {
+		return 0, c.rawGetError(wrapSyscallError("getsockopt", err))
+	}
+	return v, nil
+}
+
+// RawGetSockOptString returns the value of the string socket option
+// opt.
+func (c *SRTConn) RawGetSockOptString(opt int) (string, error) {
+	if !c.ok() {
+		return "", srtapi.EINVPARAM
+	}
+	if err := checkRawOption(opt, typeString); err != nil {
+		return "", c.rawGetError(err)
+	}
+	v, err := srtapi.GetsockoptString(c.fd.pfd.Sysfd, 0, opt)
+	if err != nil {
+		return "", c.rawGetError(wrapSyscallError("getsockopt", err))
+	}
+	return v, nil
+}
+
+// RawGetSockOptBool returns the value of the bool socket option opt.
+func (c *SRTConn) RawGetSockOptBool(opt int) (bool, error) {
+	if !c.ok() {
+		return false, srtapi.EINVPARAM
+	}
+	if err := checkRawOption(opt, typeBool); err != nil {
+		return false, c.rawGetError(err)
+	}
+	v, err := srtapi.GetsockoptInt(c.fd.pfd.Sysfd, 0, opt)
+	if err != nil {
+		return false, c.rawGetError(wrapSyscallError("getsockopt", err))
+	}
+	return v != 0, nil
+}
+
+func (c *SRTConn) rawGetError(err error) error {
+	return &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+}
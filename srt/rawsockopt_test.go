@@ -0,0 +1,120 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+func TestCheckRawOption(t *testing.T) {
+	for _, tt := range []struct {
+		opt, typ int
+		ok       bool
+	}{
+		{srtapi.OptionLatency, typeInt, true},
+		{srtapi.OptionLatency, typeString, false},
+		{srtapi.OptionStreamid, typeString, true},
+		{srtapi.OptionStreamid, typeBool, false},
+		{srtapi.OptionTlpktdrop, typeBool, true},
+		{srtapi.OptionMaxbw, typeInt, false},
+		// Not in the options table: trusted.
+		{srtapi.OptionSndsyn, typeBool, true},
+	} {
+		if err := checkRawOption(tt.opt, tt.typ); (err == nil) != tt.ok {
+			t.Errorf("checkRawOption(%d, %s) = %v", tt.opt, optionTypeNames[tt.typ], err)
+		}
+	}
+
+	var d Dialer
+	if err := d.RawSetSockOptString(srtapi.OptionLatency, "120"); err == nil {
+		t.Error("RawSetSockOptString on latency succeeded")
+	}
+	if d.raw != nil {
+		t.Errorf("failed RawSetSockOptString left raw options %v", d.raw)
+	}
+}
+
+func TestSRTConnRawSockOpt(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	if err := c.RawSetSockOptInt(srtapi.OptionSnddropdelay, 300); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := c.SndDropDelay(); err != nil || d != 300*time.Millisecond {
+		t.Errorf("SndDropDelay() after RawSetSockOptInt = %v, %v; want 300ms", d, err)
+	}
+	if v, err := c.RawGetSockOptInt(srtapi.OptionSnddropdelay); err != nil || v != 300 {
+		t.Errorf("RawGetSockOptInt() = %d, %v; want 300", v, err)
+	}
+	if on, err := c.RawGetSockOptBool(srtapi.OptionTlpktdrop); err != nil || !on {
+		t.Errorf("RawGetSockOptBool(tlpktdrop) = %v, %v; want true", on, err)
+	}
+	if _, err := c.RawGetSockOptString(srtapi.OptionLatency); err == nil {
+		t.Error("RawGetSockOptString on latency succeeded")
+	}
+
+	// libsrt refuses the transport type once connected.
+	err := c.RawSetSockOptInt(srtapi.OptionTranstype, 1)
+	var errno srtapi.Errno
+	if !errors.As(err, &errno) {
+		t.Errorf("setting transtype once connected: got %v; want a libsrt error", err)
+	}
+	if big := int64(1) << 40; int64(int(big)) == big {
+		if err := c.RawSetSockOptInt(srtapi.OptionSnddropdelay, int(big)); err == nil {
+			t.Error("RawSetSockOptInt with a value beyond 32 bits succeeded")
+		}
+	}
+
+	s.Close()
+	if err := s.RawSetSockOptInt(srtapi.OptionSnddropdelay, 300); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("RawSetSockOptInt after Close: got %v; want %v", err, net.ErrClosed)
+	}
+}
+
+func TestDialerRawSockOpt(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+
+	const streamID = "raw-stream-id"
+	var d Dialer
+	if err := d.RawSetSockOptString(srtapi.OptionStreamid, streamID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RawSetSockOptBool(srtapi.OptionTlpktdrop, false); err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.DialContext(context.Background(), "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+
+	if sid, err := s.(*SRTConn).StreamID(); err != nil || sid != streamID {
+		t.Errorf("StreamID() = %q, %v; want %q", sid, err, streamID)
+	}
+	if on, err := c.(*SRTConn).RawGetSockOptBool(srtapi.OptionTlpktdrop); err != nil || on {
+		t.Errorf("RawGetSockOptBool(tlpktdrop) = %v, %v; want false", on, err)
+	}
+}
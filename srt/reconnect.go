@@ -0,0 +1,406 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/openfresh/gosrt/internal/poll"
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A ReconnectPolicy controls how a ReconnectingConn redials after
+// losing its connection.
+type ReconnectPolicy struct {
+	// MaxRetries caps the number of consecutive redial attempts
+	// after a connection loss; it starts over once a redial
+	// succeeds, so a link that keeps failing and coming back is
+	// redialed forever. MaxTotalRetries caps the redial attempts
+	// over the life of the ReconnectingConn, whether they succeed or
+	// not. Once either is exhausted, Read and Write return the last
+	// dial error, or the error that broke the connection. Zero means
+	// no limit.
+	MaxRetries      int
+	MaxTotalRetries int
+
+	// MinBackoff and MaxBackoff bound the exponential backoff
+	// between redial attempts. They default to 100ms and 10s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// FailFast makes Read and Write return the error that broke
+	// the connection while a redial is in progress, instead of
+	// waiting for it to complete. Waiting is bounded by the read
+	// or write deadline.
+	FailFast bool
+
+	// OnReconnect, if non-nil, is called before each redial
+	// attempt, with the attempt number starting at 1 and the
+	// error that caused it.
+	OnReconnect func(attempt int, err error)
+}
+
+func (p *ReconnectPolicy) minBackoff() time.Duration {
+	if p.MinBackoff > 0 {
+		return p.MinBackoff
+	}
+	return 100 * time.Millisecond
+}
+
+func (p *ReconnectPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return 10 * time.Second
+}
+
+// ReconnectingConn is a caller connection that transparently redials
+// with the same Dialer and options, including stream ID, passphrase
+// and latency, when Read or Write fails because the connection was
+// lost or closed by the peer.
+//
+// Data in flight when the connection drops is lost: a new connection
+// starts a new SRT session, and nothing that was sent or buffered on
+// the old one is replayed. A Read or Write in progress on another
+// goroutine when the connection is replaced carries on with the new
+// one.
+type ReconnectingConn struct {
+	dialer  Dialer
+	network string
+	address string
+	policy  ReconnectPolicy
+	ctx     context.Context // options of the original dial, canceled by Close
+	cancel  context.CancelFunc
+
+	mu        sync.Mutex
+	c         *SRTConn      // nil while reconnecting
+	ready     chan struct{} // closed when a redial completes
+	lossErr   error         // error that broke the last connection
+	err       error         // set once the retries are exhausted
+	closed    bool
+	attempts  int // redial attempts so far, for MaxTotalRetries
+	laddr     net.Addr
+	raddr     net.Addr
+	rdeadline time.Time
+	wdeadline time.Time
+}
+
+// DialReconnecting connects to the address on the named network like
+// DialContext, and returns a connection that redials according to
+// policy whenever it is lost.
+//
+// ctx is only used for the initial dial; the options it carries are
+// reused for every redial.
+func (d *Dialer) DialReconnecting(ctx context.Context, network, address string, policy ReconnectPolicy) (*ReconnectingConn, error) {
+	rc := &ReconnectingConn{
+		dialer:  *d,
+		network: network,
+		address: address,
+		policy:  policy,
+	}
+	rc.ctx, rc.cancel = context.WithCancel(detachOptions(ctx))
+	c, err := rc.dial(ctx)
+	if err != nil {
+		rc.cancel()
+		return nil, err
+	}
+	rc.setConn(c)
+	return rc, nil
+}
+
+func (rc *ReconnectingConn) dial(ctx context.Context) (*SRTConn, error) {
+	c, err := rc.dialer.DialContext(ctx, rc.network, rc.address)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*SRTConn), nil
+}
+
+func (rc *ReconnectingConn) setConn(c *SRTConn) {
+	rc.c = c
+	rc.laddr = c.LocalAddr()
+	rc.raddr = c.RemoteAddr()
+	if !rc.rdeadline.IsZero() {
+		c.SetReadDeadline(rc.rdeadline)
+	}
+	if !rc.wdeadline.IsZero() {
+		c.SetWriteDeadline(rc.wdeadline)
+	}
+}
+
+// conn returns the current connection, waiting until deadline for a
+// redial in progress to complete unless the policy says to fail fast.
+func (rc *ReconnectingConn) conn(deadline time.Time) (*SRTConn, error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		rc.mu.Lock()
+		c, ready, lossErr, err := rc.c, rc.ready, rc.lossErr, rc.err
+		closed := rc.closed
+		rc.mu.Unlock()
+		switch {
+		case closed:
+			return nil, poll.ErrNetClosing
+		case err != nil:
+			return nil, err
+		case c != nil:
+			return c, nil
+		case rc.policy.FailFast:
+			return nil, lossErr
+		}
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			if timer == nil {
+				timer = time.NewTimer(time.Until(deadline))
+			}
+			expired = timer.C
+		}
+		select {
+		case <-ready:
+		case <-expired:
+			return nil, poll.ErrTimeout
+		}
+	}
+}
+
+// reconnect starts redialing, unless c has already been replaced.
+func (rc *ReconnectingConn) reconnect(c *SRTConn, err error) {
+	rc.mu.Lock()
+	if rc.closed || rc.c != c {
+		rc.mu.Unlock()
+		return
+	}
+	rc.c = nil
+	rc.lossErr = err
+	rc.ready = make(chan struct{})
+	ready := rc.ready
+	rc.mu.Unlock()
+
+	c.Close()
+	go rc.redial(ready, err)
+}
+
+func (rc *ReconnectingConn) redial(ready chan struct{}, err error) {
+	defer close(ready)
+	backoff := rc.policy.minBackoff()
+	for attempt := 1; rc.policy.MaxRetries == 0 || attempt <= rc.policy.MaxRetries; attempt++ {
+		if !rc.countAttempt() {
+			break
+		}
+		if rc.policy.OnReconnect != nil {
+			rc.policy.OnReconnect(attempt, err)
+		}
+		var c *SRTConn
+		if c, err = rc.dial(rc.ctx); err == nil {
+			rc.mu.Lock()
+			defer rc.mu.Unlock()
+			if rc.closed {
+				c.Close()
+				return
+			}
+			rc.setConn(c)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-rc.ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > rc.policy.maxBackoff() {
+			backoff = rc.policy.maxBackoff()
+		}
+	}
+	rc.mu.Lock()
+	rc.err = err
+	rc.mu.Unlock()
+}
+
+// countAttempt counts a redial attempt against MaxTotalRetries, and
+// reports whether it may be made.
+func (rc *ReconnectingConn) countAttempt() bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if max := rc.policy.MaxTotalRetries; max > 0 && rc.attempts >= max {
+		return false
+	}
+	rc.attempts++
+	return true
+}
+
+// isConnLoss reports whether err means the connection is gone and
+// worth redialing.
+func isConnLoss(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	var errno srtapi.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case srtapi.ECONNLOST, srtapi.ECONNFAIL, srtapi.ENOCONN, srtapi.EINVSOCK:
+			return true
+		}
+	}
+	return false
+}
+
+// lost reports whether err, returned by an operation on c, calls for
+// a redial: either c is lost, or reconnect closed it under the
+// operation because another goroutine found it lost.
+func (rc *ReconnectingConn) lost(c *SRTConn, err error) bool {
+	if isConnLoss(err) {
+		return true
+	}
+	if !errors.Is(err, net.ErrClosed) {
+		return false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return !rc.closed && rc.c != c
+}
+
+// Read implements the Conn Read method. It redials and retries when
+// the connection has been lost.
+func (rc *ReconnectingConn) Read(b []byte) (int, error) {
+	for {
+		rc.mu.Lock()
+		deadline := rc.rdeadline
+		rc.mu.Unlock()
+		c, err := rc.conn(deadline)
+		if err != nil {
+			return 0, rc.opError("read", err)
+		}
+		n, err := c.Read(b)
+		if err == nil || !rc.lost(c, err) {
+			return n, err
+		}
+		rc.reconnect(c, err)
+	}
+}
+
+// Write implements the Conn Write method. It redials and writes what
+// is left of b when the connection has been lost.
+//
+// In message mode, b is one message: it is written again whole if the
+// connection was lost before any of it was sent, but a message partly
+// sent is not completed on the new connection, where the rest would
+// arrive as a message of its own. Write then returns the count sent
+// and the error that broke the connection, and redials for the next
+// call.
+func (rc *ReconnectingConn) Write(b []byte) (int, error) {
+	var nn int
+	for {
+		rc.mu.Lock()
+		deadline := rc.wdeadline
+		rc.mu.Unlock()
+		c, err := rc.conn(deadline)
+		if err != nil {
+			return nn, rc.opError("write", err)
+		}
+		n, err := c.Write(b[nn:])
+		nn += n
+		if err == nil || !rc.lost(c, err) {
+			return nn, err
+		}
+		rc.reconnect(c, err)
+		if n > 0 && !c.fd.streamAPI {
+			return nn, err
+		}
+	}
+}
+
+func (rc *ReconnectingConn) opError(op string, err error) error {
+	if _, ok := err.(*OpError); ok {
+		return err
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return &OpError{Op: op, Net: rc.network, Source: rc.laddr, Addr: rc.raddr, Err: err}
+}
+
+// Close closes the current connection and stops any redial in
+// progress.
+func (rc *ReconnectingConn) Close() error {
+	rc.mu.Lock()
+	if rc.closed {
+		rc.mu.Unlock()
+		return rc.opError("close", poll.ErrNetClosing)
+	}
+	rc.closed = true
+	c := rc.c
+	rc.c = nil
+	rc.mu.Unlock()
+	rc.cancel()
+	if c != nil {
+		return c.Close()
+	}
+	return nil
+}
+
+// Conn returns the connection currently in use, or nil while a
+// redial is in progress.
+func (rc *ReconnectingConn) Conn() *SRTConn {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.c
+}
+
+// LocalAddr returns the local network address of the latest
+// connection.
+func (rc *ReconnectingConn) LocalAddr() net.Addr {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.laddr
+}
+
+// RemoteAddr returns the remote network address of the latest
+// connection.
+func (rc *ReconnectingConn) RemoteAddr() net.Addr {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.raddr
+}
+
+// SetDeadline implements the Conn SetDeadline method. Deadlines carry
+// over to the connections established by later redials and also
+// bound the time Read and Write wait for a redial.
+func (rc *ReconnectingConn) SetDeadline(t time.Time) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.rdeadline, rc.wdeadline = t, t
+	if rc.c != nil {
+		return rc.c.SetDeadline(t)
+	}
+	return nil
+}
+
+// SetReadDeadline implements the Conn SetReadDeadline method.
+func (rc *ReconnectingConn) SetReadDeadline(t time.Time) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.rdeadline = t
+	if rc.c != nil {
+		return rc.c.SetReadDeadline(t)
+	}
+	return nil
+}
+
+// SetWriteDeadline implements the Conn SetWriteDeadline method.
+func (rc *ReconnectingConn) SetWriteDeadline(t time.Time) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.wdeadline = t
+	if rc.c != nil {
+		return rc.c.SetWriteDeadline(t)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/internal/poll"
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+func TestReconnectingConn(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("streamid", "reconnect"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		// Drop the first connection, then greet the redialed one.
+		c, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		c.Close()
+		c, err = ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		if sid, err := c.(*SRTConn).StreamID(); err != nil || sid != "reconnect" {
+			errc <- errors.New("stream ID not preserved across redial: " + sid)
+			return
+		}
+		_, err = c.Write([]byte("HELLO"))
+		errc <- err
+		time.Sleep(time.Second)
+	}()
+
+	var attempts int32
+	policy := ReconnectPolicy{
+		MaxRetries:  5,
+		OnReconnect: func(int, error) { atomic.AddInt32(&attempts, 1) },
+	}
+	var d Dialer
+	rc, err := d.DialReconnecting(ctx, "srt", ln.Addr().String(), policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	rc.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	b := make([]byte, 16)
+	n, err := rc.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != "HELLO" {
+		t.Fatalf("got %q; want HELLO", b[:n])
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&attempts) == 0 {
+		t.Error("OnReconnect was not called")
+	}
+
+	rc.Close()
+	if _, err := rc.Read(b); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Read after Close = %v; want net.ErrClosed", err)
+	}
+}
+
+func TestReconnectingConnLost(t *testing.T) {
+	old, cur := newSRTConn(&netFD{net: "srt"}), newSRTConn(&netFD{net: "srt"})
+	rc := &ReconnectingConn{c: cur}
+	closed := &OpError{Op: "read", Net: "srt", Err: net.ErrClosed}
+
+	if !rc.lost(cur, classifyConnError(srtapi.ECONNLOST)) {
+		t.Error("lost(ECONNLOST) = false; want true")
+	}
+	if rc.lost(cur, closed) {
+		t.Error("lost of the current connection closed = true; want false")
+	}
+	if !rc.lost(old, closed) {
+		t.Error("lost of a connection closed by a reconnect = false; want true")
+	}
+	rc.c = nil // redialing
+	if !rc.lost(old, closed) {
+		t.Error("lost of a connection closed while redialing = false; want true")
+	}
+	rc.closed = true
+	if rc.lost(old, closed) {
+		t.Error("lost after Close = true; want false")
+	}
+	if rc.lost(cur, poll.ErrTimeout) {
+		t.Error("lost(ErrTimeout) = true; want false")
+	}
+}
+
+func TestReconnectPolicyMaxTotalRetries(t *testing.T) {
+	defer func() { testHookDialSRT = nil }()
+	errDown := errors.New("link down")
+	testHookDialSRT = func(context.Context, string, *SRTAddr, *SRTAddr) (*SRTConn, error) {
+		return nil, errDown
+	}
+	var attempts int
+	rc := &ReconnectingConn{
+		network: "srt",
+		address: "127.0.0.1:5000",
+		policy: ReconnectPolicy{
+			MaxRetries:      2,
+			MaxTotalRetries: 3,
+			MinBackoff:      time.Millisecond,
+			OnReconnect:     func(int, error) { attempts++ },
+		},
+	}
+	rc.ctx, rc.cancel = context.WithCancel(context.Background())
+	defer rc.cancel()
+
+	// Each loss takes up to MaxRetries attempts, and all of them
+	// together no more than MaxTotalRetries.
+	lossErr := srtapi.ECONNLOST
+	for i, want := range []int{2, 3, 3} {
+		rc.err = nil
+		rc.redial(make(chan struct{}), lossErr)
+		if attempts != want {
+			t.Errorf("loss #%d: %d attempts in all; want %d", i, attempts, want)
+		}
+		if rc.err == nil {
+			t.Errorf("loss #%d: no error once the retries are exhausted", i)
+		}
+	}
+	if !errors.Is(rc.err, lossErr) {
+		t.Errorf("error past MaxTotalRetries = %v; want %v", rc.err, lossErr)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A RejectReason tells why a connection was rejected, as in
+// SRT_REJECT_REASON.
+type RejectReason int
+
+// Reject reasons.
+const (
+	RejectUnknown    RejectReason = srtapi.RejectUnknown    // unknown reason
+	RejectSystem     RejectReason = srtapi.RejectSystem     // system function error
+	RejectPeer       RejectReason = srtapi.RejectPeer       // rejected by the peer
+	RejectResource   RejectReason = srtapi.RejectResource   // resource allocation problem
+	RejectRogue      RejectReason = srtapi.RejectRogue      // incorrect data in handshake
+	RejectBacklog    RejectReason = srtapi.RejectBacklog    // listener's backlog exceeded
+	RejectIPE        RejectReason = srtapi.RejectIPE        // internal program error
+	RejectClose      RejectReason = srtapi.RejectClose      // socket is closing
+	RejectVersion    RejectReason = srtapi.RejectVersion    // peer is older than the minimum version
+	RejectRdvCookie  RejectReason = srtapi.RejectRdvCookie  // rendezvous cookie collision
+	RejectBadSecret  RejectReason = srtapi.RejectBadSecret  // wrong passphrase
+	RejectUnsecure   RejectReason = srtapi.RejectUnsecure   // passphrase required or given without need
+	RejectMessageAPI RejectReason = srtapi.RejectMessageAPI // messageapi flags differ
+	RejectCongestion RejectReason = srtapi.RejectCongestion // congestion controllers differ
+	RejectFilter     RejectReason = srtapi.RejectFilter     // packet filters differ
+	RejectCrypto     RejectReason = srtapi.RejectCrypto     // crypto modes differ
+)
+
+func (r RejectReason) String() string {
+	return srtapi.RejectReasonStr(int(r))
+}
+
+// A RejectError is returned by dials whose connection the peer, or
+// libsrt on its behalf, rejected.
+type RejectError struct {
+	Reason RejectReason
+}
+
+func (e *RejectError) Error() string {
+	return "connection rejected: " + e.Reason.String()
+}
+
+// Is reports whether target is srtapi.ECONNREJ, the error libsrt
+// reports rejections with.
+func (e *RejectError) Is(target error) bool {
+	return target == srtapi.ECONNREJ
+}
+
+// Temporary reports whether the rejection may not recur: a full
+// backlog or a lack of resources on the listener.
+func (e *RejectError) Temporary() bool {
+	return e.Reason == RejectBacklog || e.Reason == RejectResource
+}
+
+// Timeout returns false.
+func (e *RejectError) Timeout() bool { return false }
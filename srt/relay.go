@@ -0,0 +1,76 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"io"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// srtHeaderSize is the size of the IP, UDP and SRT headers that the
+// payload of a packet shares the MSS with.
+const srtHeaderSize = 44
+
+// relayBufferSize is the size of the relay buffer when messages are
+// not limited to one packet, as in file mode.
+const relayBufferSize = 1 << 20
+
+// Relay copies from src to dst until either EOF is reached on src or
+// an error occurs. It returns the number of bytes copied and the
+// first error encountered while copying, if any. A successful Relay
+// returns err == nil, not err == EOF.
+//
+// Each message read from src is written to dst as one message,
+// through a single buffer allocated once and sized for the largest
+// message src can deliver. io.Copy from an SRTConn to an SRTConn
+// relays the same way.
+//
+// A dst that cannot keep up blocks Relay, which stops reading src, so
+// that the backpressure reaches the sender of src. To serve several
+// destinations from one source without one of them holding back the
+// others, give each its own queue instead.
+func Relay(dst, src *SRTConn) (int64, error) {
+	if !dst.ok() || !src.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	n, err := relay(dst.fd, src.fd)
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "relay", Net: src.fd.net, Source: src.fd.laddr, Addr: src.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+func relay(dst, src *netFD) (written int64, err error) {
+	buf := make([]byte, relayBufSize(src))
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			nw, ew := dst.Write(buf[:n])
+			written += int64(nw)
+			if ew != nil {
+				return written, ew
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// relayBufSize returns the size of a buffer that holds any message
+// read from fd. In live mode, a message is one packet, whose payload
+// the peer may have made as large as the MSS allows.
+func relayBufSize(fd *netFD) int {
+	if size, err := srtapi.GetsockoptInt(fd.pfd.Sysfd, 0, srtapi.OptionPayloadsize); err != nil || size == 0 {
+		return relayBufferSize
+	}
+	if mss, err := srtapi.GetsockoptInt(fd.pfd.Sysfd, 0, srtapi.OptionMss); err == nil && mss > srtHeaderSize {
+		return mss - srtHeaderSize
+	}
+	return maxMSS - srtHeaderSize
+}
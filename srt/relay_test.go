@@ -0,0 +1,126 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+// newSRTPair returns the two ends of an SRT connection.
+func newSRTPair(tb testing.TB) (client, server *SRTConn) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			tb.Error(err)
+		}
+		accepted <- c
+	}()
+	c, err := Dial("srt", ln.Addr().String())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s := <-accepted
+	if s == nil {
+		c.Close()
+		tb.FailNow()
+	}
+	return c.(*SRTConn), s.(*SRTConn)
+}
+
+func TestRelay(t *testing.T) {
+	for _, name := range []string{"Relay", "io.Copy"} {
+		t.Run(name, func(t *testing.T) {
+			upc, ups := newSRTPair(t)
+			defer upc.Close()
+			downc, downs := newSRTPair(t)
+			defer downs.Close()
+
+			errc := make(chan error, 1)
+			go func() {
+				var err error
+				if name == "Relay" {
+					_, err = Relay(downc, ups)
+				} else {
+					_, err = io.Copy(downc, ups)
+				}
+				ups.Close()
+				downc.Close()
+				errc <- err
+			}()
+
+			var want [][]byte
+			for i := 0; i < 10; i++ {
+				msg := bytes.Repeat([]byte{byte(i)}, 1000+i)
+				want = append(want, msg)
+				if _, err := upc.Write(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			buf := make([]byte, 1500)
+			for i, msg := range want {
+				n, err := downs.Read(buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf[:n], msg) {
+					t.Fatalf("message %d: got %d bytes; want %d bytes of %d", i, n, len(msg), i)
+				}
+			}
+			upc.Close()
+			<-errc
+		})
+	}
+}
+
+func BenchmarkRelay(b *testing.B) {
+	for _, name := range []string{"Relay", "Generic"} {
+		b.Run(name, func(b *testing.B) {
+			upc, ups := newSRTPair(b)
+			defer upc.Close()
+			downc, downs := newSRTPair(b)
+			defer downs.Close()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				if name == "Relay" {
+					Relay(downc, ups)
+				} else {
+					genericReadFrom(downc, ups)
+				}
+				ups.Close()
+				downc.Close()
+			}()
+			go func() {
+				buf := make([]byte, 1500)
+				for {
+					if _, err := downs.Read(buf); err != nil {
+						return
+					}
+				}
+			}()
+
+			msg := make([]byte, 1316)
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := upc.Write(msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			upc.Close()
+			<-done
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build linux,!mips,!mipsle,!mips64,!mips64le,!sparc64
+
+package srt
+
+// soReusePort is SO_REUSEPORT, which package syscall lacks on Linux.
+const soReusePort = 0xf
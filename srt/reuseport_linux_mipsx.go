@@ -0,0 +1,10 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build linux,mips linux,mipsle linux,mips64 linux,mips64le linux,sparc64
+
+package srt
+
+// soReusePort is SO_REUSEPORT, which package syscall lacks on Linux;
+// MIPS and SPARC number it as on their original Unix systems.
+const soReusePort = 0x200
@@ -12,9 +12,12 @@ package srt
 
 import (
 	"context"
+	"math"
 	"net"
 	"os"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/openfresh/gosrt/internal/poll"
 	"github.com/openfresh/gosrt/srtapi"
@@ -53,14 +56,17 @@ func socket(ctx context.Context, net string, family, sotype, proto int, ipv6only
 		poll.CloseFunc(s)
 		return nil, err
 	}
-	configure(ctx, s, bindPre)
+	if err = configure(ctx, s, bindPre); err != nil {
+		poll.CloseFunc(s)
+		return nil, wrapSyscallError("setsockopt", err)
+	}
 	if fd, err = newFD(s, family, sotype, net); err != nil {
 		poll.CloseFunc(s)
 		return nil, err
 	}
 
 	if laddr != nil && raddr == nil {
-		if err := fd.listen(laddr, listenerBacklog); err != nil {
+		if err := fd.listen(ctx, laddr); err != nil {
 			fd.Close()
 			return nil, err
 		}
@@ -87,24 +93,68 @@ func (fd *netFD) addrFunc() func(syscall.Sockaddr) net.Addr {
 	return func(syscall.Sockaddr) net.Addr { return nil }
 }
 
+// defaultConnTimeout is the libsrt default of conntimeo.
+const defaultConnTimeout = 3 * time.Second
+
+// setConnTimeout extends the connection timeout of s to the deadline
+// of ctx, if any, unless conntimeo is set explicitly. Otherwise libsrt
+// gives up on the handshake after its default of 3s, however long
+// the dial timeout.
+func setConnTimeout(ctx context.Context, s int) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	if _, ok := Option(ctx, "conntimeo"); ok {
+		return nil
+	}
+	d := time.Until(deadline)
+	if d <= defaultConnTimeout {
+		return nil
+	}
+	ms := int64(d / time.Millisecond)
+	if ms > math.MaxInt32 {
+		ms = math.MaxInt32
+	}
+	return wrapSyscallError("setsockopt", srtapi.SetsockoptInt(s, 0, srtapi.OptionConntimeo, int(ms)))
+}
+
 func (fd *netFD) dial(ctx context.Context, laddr, raddr sockaddr) error {
 	var err error
 	var lsa syscall.Sockaddr
 	if laddr != nil {
 		if lsa, err = laddr.sockaddr(fd.family); err != nil {
 			return err
-		} else if lsa != nil {
-			if err := srtapi.Bind(fd.pfd.Sysfd, lsa); err != nil {
-				return os.NewSyscallError("bind", err)
-			}
 		}
 	}
+	// The options that must precede the bind were set by socket, so
+	// the order is options, bind, connect; the context is checked
+	// between the steps, so a canceled dial stops at the next one.
+	if err := ctx.Err(); err != nil {
+		return mapErr(err)
+	}
+	if err := fd.bind(ctx, lsa); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return mapErr(err)
+	}
+	if raddr != nil && deferHandshakeValue(ctx) {
+		// Handshake connects it later.
+		atomic.StoreInt32(&fd.handshakePending, 1)
+		lsa, _ = srtapi.Getsockname(fd.pfd.Sysfd)
+		fd.setAddr(fd.addrFunc()(lsa), nil)
+		return nil
+	}
 	var rsa syscall.Sockaddr  // remote address from the user
 	var crsa syscall.Sockaddr // remote address we actually connected to
 	if raddr != nil {
 		if rsa, err = raddr.sockaddr(fd.family); err != nil {
 			return err
 		}
+		if err := setConnTimeout(ctx, fd.pfd.Sysfd); err != nil {
+			return err
+		}
 		if crsa, err = fd.connect(ctx, lsa, rsa); err != nil {
 			return err
 		}
@@ -115,13 +165,38 @@ func (fd *netFD) dial(ctx context.Context, laddr, raddr sockaddr) error {
 			return err
 		}
 	}
+	fd.setConnectedAddr(raddr, crsa)
+	return nil
+}
+
+// handshake connects fd, which dial left unconnected, to raddr.
+func (fd *netFD) handshake(ctx context.Context, raddr sockaddr) error {
+	rsa, err := raddr.sockaddr(fd.family)
+	if err != nil {
+		return err
+	}
+	if err := setConnTimeout(ctx, fd.pfd.Sysfd); err != nil {
+		return err
+	}
+	crsa, err := fd.connect(ctx, nil, rsa)
+	if err != nil {
+		return err
+	}
+	fd.isConnected = true
+	configure(ctx, fd.pfd.Sysfd, bindPost)
+	fd.setConnectedAddr(raddr, crsa)
+	atomic.StoreInt32(&fd.handshakePending, 0)
+	return nil
+}
+
+func (fd *netFD) setConnectedAddr(raddr sockaddr, crsa syscall.Sockaddr) {
 	// Record the local and remote addresses from the actual socket.
 	// Get the local address by calling Getsockname.
 	// For the remote address, use
 	// 1) the one returned by the connect method, if any; or
 	// 2) the one from Getpeername, if it succeeds; or
 	// 3) the one passed to us as the raddr parameter.
-	lsa, _ = srtapi.Getsockname(fd.pfd.Sysfd)
+	lsa, _ := srtapi.Getsockname(fd.pfd.Sysfd)
 
 	// hack - if it could bit get zone ID, it forces to set 1
 	switch sa := lsa.(type) {
@@ -134,32 +209,71 @@ func (fd *netFD) dial(ctx context.Context, laddr, raddr sockaddr) error {
 
 	if crsa != nil {
 		fd.setAddr(fd.addrFunc()(lsa), fd.addrFunc()(crsa))
-	} else if rsa, _ = srtapi.Getpeername(fd.pfd.Sysfd); rsa != nil {
+	} else if rsa, _ := srtapi.Getpeername(fd.pfd.Sysfd); rsa != nil {
 		fd.setAddr(fd.addrFunc()(lsa), fd.addrFunc()(rsa))
 	} else {
 		fd.setAddr(fd.addrFunc()(lsa), raddr)
 	}
+}
+
+// bind binds the socket to lsa, if any, or to the UDP socket or
+// network interface that ctx carries, reusing the port if it says so.
+func (fd *netFD) bind(ctx context.Context, lsa syscall.Sockaddr) error {
+	if u := sharedUDPValue(ctx); u != nil {
+		if err := u.bind(fd.pfd.Sysfd); err != nil {
+			return err
+		}
+		fd.udp = keepUDP(u.fd)
+		return nil
+	}
+	if ifname, reuse := interfaceNameValue(ctx), reusePortValue(ctx); ifname != "" || reuse {
+		udp, err := bindUDP(fd.pfd.Sysfd, fd.family, lsa, ifname, reuse)
+		if err != nil {
+			return err
+		}
+		fd.udp = keepUDP(udp)
+		return nil
+	}
+	if lsa != nil {
+		if err := srtapi.Bind(fd.pfd.Sysfd, lsa); err != nil {
+			return os.NewSyscallError("bind", err)
+		}
+	}
 	return nil
 }
 
-func (fd *netFD) listen(laddr sockaddr, backlog int) error {
+func (fd *netFD) listen(ctx context.Context, laddr sockaddr) error {
 	if err := setDefaultListenerSockopts(fd.pfd.Sysfd); err != nil {
 		return err
 	}
-	if lsa, err := laddr.sockaddr(fd.family); err != nil {
-		return err
-	} else if lsa != nil {
-		if err := srtapi.Bind(fd.pfd.Sysfd, lsa); err != nil {
-			return os.NewSyscallError("bind", err)
+	if on, ok := ipv6OnlyValue(ctx); ok && fd.family == syscall.AF_INET6 {
+		v := 0
+		if on {
+			v = 1
+		}
+		if err := srtapi.SetsockoptInt(fd.pfd.Sysfd, 0, srtapi.OptionIpv60only, v); err != nil {
+			return wrapSyscallError("setsockopt", err)
 		}
 	}
-	if err := listenFunc(fd.pfd.Sysfd, backlog); err != nil {
+	lsa, err := laddr.sockaddr(fd.family)
+	if err != nil {
+		return err
+	}
+	if err := fd.bind(ctx, lsa); err != nil {
+		return err
+	}
+	if err := listenFunc(fd.pfd.Sysfd, backlogValue(ctx)); err != nil {
 		return os.NewSyscallError("listen", err)
 	}
 	if err := fd.init(); err != nil {
 		return err
 	}
-	lsa, _ := srtapi.Getsockname(fd.pfd.Sysfd)
+	// Report the address actually bound, so that a specific IP with
+	// port 0 comes back with the port that was chosen.
+	lsa, err = srtapi.Getsockname(fd.pfd.Sysfd)
+	if err != nil {
+		return os.NewSyscallError("getsockname", err)
+	}
 	fd.setAddr(fd.addrFunc()(lsa), nil)
 	return nil
 }
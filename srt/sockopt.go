@@ -5,8 +5,13 @@ package srt
 
 import (
 	"context"
+	"errors"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/openfresh/gosrt/internal/poll"
 	"github.com/openfresh/gosrt/srtapi"
 )
 
@@ -49,6 +54,9 @@ func (o *socketOption) apply(s int, v string) error {
 }
 
 func (o *socketOption) extract(v string) (ov interface{}, err error) {
+	if o.sym == srtapi.OptionTranstype {
+		v = transtypeValue(v)
+	}
 	switch o.typ {
 	case typeString:
 		ov = v
@@ -62,6 +70,18 @@ func (o *socketOption) extract(v string) (ov interface{}, err error) {
 	return
 }
 
+// transtypeValue returns the number of the transtype named "live" or
+// "file", as ffmpeg names them, and any other value as is.
+func transtypeValue(v string) string {
+	switch v {
+	case "live":
+		return "0"
+	case "file":
+		return "1"
+	}
+	return v
+}
+
 var srtOptions = []socketOption{
 	{"transtype", 0, srtapi.OptionTranstype, bindPre, typeInt},
 	{"maxbw", 0, srtapi.OptionMaxbw, bindPre, typeInt64},
@@ -72,6 +92,8 @@ var srtOptions = []socketOption{
 	{"fc", 0, srtapi.OptionFc, bindPre, typeInt},
 	{"sndbuf", 0, srtapi.OptionSndbuf, bindPre, typeInt},
 	{"rcvbuf", 0, srtapi.OptionRcvbuf, bindPre, typeInt},
+	{"udp_sndbuf", 0, srtapi.OptionUDPSndbuf, bindPre, typeInt},
+	{"udp_rcvbuf", 0, srtapi.OptionUDPRcvbuf, bindPre, typeInt},
 	{"ipttl", 0, srtapi.OptionIpttl, bindPre, typeInt},
 	{"iptos", 0, srtapi.OptionIptos, bindPre, typeInt},
 	{"inputbw", 0, srtapi.OptionInputbw, bindPost, typeInt64},
@@ -95,6 +117,21 @@ var srtOptions = []socketOption{
 	{"enforcedencryption", 0, srtapi.OptionEnforcedencryption, bindPre, typeBool},
 	{"peeridletimeo", 0, srtapi.OptionPeeridletimeo, bindPre, typeInt},
 	{"packetfilter", 0, srtapi.OptionPacketfilter, bindPre, typeString},
+	{"rendezvous", 0, srtapi.OptionRendezvous, bindPre, typeBool},
+	{"retransmitalgo", 0, srtapi.OptionRetransmitalgo, bindPre, typeInt},
+	{"cryptomode", 0, srtapi.OptionCryptomode, bindPre, typeInt},
+	{"drifttracer", 0, srtapi.OptionDrifttracer, bindPre, typeBool},
+}
+
+// lookupOption returns the entry of srtOptions with the given name, or
+// nil.
+func lookupOption(name string) *socketOption {
+	for i := range srtOptions {
+		if srtOptions[i].name == name {
+			return &srtOptions[i]
+		}
+	}
+	return nil
 }
 
 type option struct {
@@ -102,7 +139,9 @@ type option struct {
 	value string
 }
 
-// OptionSet is a set of options.
+// OptionSet is a set of options, made by Options or built up with
+// its methods, such as Latency. It is never modified once made, so it
+// is safe to share between goroutines.
 type OptionSet struct {
 	list []option
 }
@@ -123,6 +162,15 @@ type optionMap map[string]string
 
 // WithOptions returns a new context.Context with the given options added.
 // A option overwrites a prior option with the same key.
+//
+// The options of the context apply to the sockets of the Dial and
+// Listen calls it is passed to. They are merged, option by option,
+// with those set on the Dialer or ListenConfig, and take precedence
+// over them; options set in neither keep the libsrt defaults. This
+// lets a shared Dialer be used for dials that need, say, their own
+// stream ID or latency. The keys are the names of the options table,
+// such as "streamid" or "latency"; a key that names no option, or a
+// value the option does not take, makes the call fail.
 func WithOptions(ctx context.Context, options OptionSet) context.Context {
 	childOptions := make(optionMap)
 	parentOptions := optionValue(ctx)
@@ -136,9 +184,29 @@ func WithOptions(ctx context.Context, options OptionSet) context.Context {
 	return context.WithValue(ctx, optionContextKey{}, &childOptions)
 }
 
+// detachOptions returns a background context carrying the options of
+// ctx, but not its deadline or cancelation.
+func detachOptions(ctx context.Context) context.Context {
+	return withOptionsOf(context.Background(), ctx)
+}
+
+// withOptionsOf returns ctx carrying the options of from in place of
+// its own, if from has any, raw options included.
+func withOptionsOf(ctx, from context.Context) context.Context {
+	if options := from.Value(optionContextKey{}); options != nil {
+		ctx = context.WithValue(ctx, optionContextKey{}, options)
+	}
+	if raw := rawOptionValue(from); raw != nil {
+		ctx = context.WithValue(ctx, rawOptionContextKey{}, raw)
+	}
+	return ctx
+}
+
 // Options takes an even number of strings representing key-value pairs
 // and makes a OptionSet containing them.
 // A option overwrites a prior option with the same key.
+// The transtype option takes "live" and "file", as in ffmpeg URIs,
+// as well as the numbers of SRT_TRANSTYPE.
 func Options(args ...string) OptionSet {
 	if len(args)%2 != 0 {
 		panic("uneven number of arguments to gosrt.Options")
@@ -158,10 +226,647 @@ func Option(ctx context.Context, key string) (string, bool) {
 	return v, ok
 }
 
+// socketConfig holds the socket options set through the setters of
+// Dialer and ListenConfig. Options carried by the context take
+// precedence over them; see WithOptions.
+type socketConfig struct {
+	options   optionMap
+	raw       []rawOption
+	fecNakOff bool // nakreport was turned off by SetFEC, for ARQNever
+}
+
+// SetOption sets the socket option of the options table with the
+// given name, as a context carrying Options(name, value) would, for
+// every connection made. Options set by the context of a call take
+// precedence.
+func (sc *socketConfig) SetOption(name, value string) error {
+	o := lookupOption(name)
+	if o == nil {
+		return unknownOptionError(name)
+	}
+	if _, err := o.extract(value); err != nil {
+		return errors.New("invalid value " + strconv.Quote(value) + " for option " + name)
+	}
+	sc.setOption(name, value)
+	if name == "nakreport" {
+		sc.fecNakOff = false
+	}
+	return nil
+}
+
+func unknownOptionError(name string) error {
+	return errors.New("unknown option " + strconv.Quote(name))
+}
+
+// setOption sets the option name to value. The map is copied rather
+// than written to, so that a Dialer or ListenConfig copied from another,
+// as a template, can be set up without changing the original.
+func (sc *socketConfig) setOption(name, value string) {
+	options := make(optionMap, len(sc.options)+1)
+	for k, v := range sc.options {
+		options[k] = v
+	}
+	options[name] = value
+	sc.options = options
+}
+
+func (sc *socketConfig) deleteOption(name string) {
+	options := make(optionMap, len(sc.options))
+	for k, v := range sc.options {
+		if k != name {
+			options[k] = v
+		}
+	}
+	sc.options = options
+}
+
+// context returns ctx with the options of sc added below those ctx
+// already carries.
+func (sc *socketConfig) context(ctx context.Context) context.Context {
+	if len(sc.raw) > 0 {
+		raw := append(append([]rawOption(nil), sc.raw...), rawOptionValue(ctx)...)
+		ctx = context.WithValue(ctx, rawOptionContextKey{}, raw)
+	}
+	if len(sc.options) == 0 {
+		return ctx
+	}
+	options := make(optionMap)
+	for k, v := range sc.options {
+		options[k] = v
+	}
+	for k, v := range optionValue(ctx) {
+		options[k] = v
+	}
+	return context.WithValue(ctx, optionContextKey{}, &options)
+}
+
+// SetPeerIdleTimeout sets the time after which a connection is broken
+// when nothing is received from the peer, keepalives included. Reads
+// and writes then fail with srtapi.ECONNLOST, as when the peer closes
+// the connection: libsrt does not tell the two apart. The libsrt
+// default is 5s.
+//
+// Setting it close to the round-trip time risks breaking healthy
+// connections on a momentary loss burst; keep it at several RTTs, and
+// above the latency of live streams. d is rounded down to whole
+// milliseconds and must be at least one.
+func (sc *socketConfig) SetPeerIdleTimeout(d time.Duration) error {
+	if d < time.Millisecond {
+		return errors.New("peer idle timeout must be at least 1ms")
+	}
+	sc.setOption("peeridletimeo", strconv.FormatInt(int64(d/time.Millisecond), 10))
+	return nil
+}
+
+// The range of MSS values libsrt accepts.
+const (
+	minMSS = 76
+	maxMSS = 1500
+)
+
+// SetMSS sets the maximum segment size, the largest IP packet SRT
+// sends, headers included. The default of 1500 suits Ethernet; paths
+// with a smaller MTU, such as VPNs, need it lowered, to 1360 say, to
+// avoid fragmentation. The handshake settles on the smaller MSS of
+// the two peers, which the MSS method of the connection reports.
+//
+// The payload size must not exceed the MSS minus 44 bytes of IP, UDP
+// and SRT headers, 1456 for the default MSS.
+func (sc *socketConfig) SetMSS(bytes int) error {
+	if bytes < minMSS || bytes > maxMSS {
+		return errors.New("MSS must be between " + strconv.Itoa(minMSS) + " and " + strconv.Itoa(maxMSS))
+	}
+	sc.setOption("mss", strconv.Itoa(bytes))
+	return nil
+}
+
+// SetTSBPDMode turns timestamp-based packet delivery on or off. With
+// TSBPD, the receiver delivers each packet once the latency has
+// passed since it was sent, at the pace it was sent; without it,
+// packets are delivered as soon as they are in order. It is on by
+// default in live mode, and only possible there. Since dropping late
+// packets needs TSBPD, turning it off also turns off tlpktdrop.
+//
+// Each peer applies the mode it is configured with to the data it
+// receives, as agreed in the handshake.
+func (sc *socketConfig) SetTSBPDMode(on bool) {
+	sc.setOption("tsbpdmode", strconv.FormatBool(on))
+	if !on {
+		sc.setOption("tlpktdrop", "false")
+	}
+}
+
+// minFC is the smallest flow control window libsrt accepts.
+const minFC = 32
+
+// SetFlowControlWindow sets the flow control window, the largest
+// number of packets in flight unacknowledged. The libsrt default of
+// 25600 packets caps the throughput at high bandwidth-delay products;
+// a suitable window is
+//
+//	bandwidth in bits/s / 8 / payload size * RTT in seconds * safety
+//
+// with a safety factor of 1.5 or so. The send and receive buffers,
+// if set, must hold the window: packets times payload size. The
+// window in effect once connected, which depends on the peer, is
+// reported by the FlowControlWindow method of the connection.
+func (sc *socketConfig) SetFlowControlWindow(packets int) error {
+	if packets < minFC {
+		return errors.New("flow control window must be at least " + strconv.Itoa(minFC) + " packets")
+	}
+	sc.setOption("fc", strconv.Itoa(packets))
+	return nil
+}
+
+// SetUDPRecvBuffer sets the size of the kernel receive buffer,
+// SO_RCVBUF, of the UDP socket of the connection, in bytes. It is not
+// the SRT receive buffer: at high rates a kernel buffer too small
+// drops packets before libsrt sees them, which shows as loss on a
+// path that has none.
+//
+// It returns the size the kernel grants, as a UDP socket reports it
+// once set. Linux caps the request at net.core.rmem_max, which the
+// administrator may have to raise with sysctl for large buffers, and
+// reports twice the size granted, the extra being for its own
+// bookkeeping.
+func (sc *socketConfig) SetUDPRecvBuffer(bytes int) (int, error) {
+	return sc.setUDPBuffer("udp_rcvbuf", syscall.SO_RCVBUF, bytes)
+}
+
+// SetUDPSendBuffer sets the size of the kernel send buffer, SO_SNDBUF,
+// of the UDP socket of the connection, like SetUDPRecvBuffer. Linux
+// caps it at net.core.wmem_max.
+func (sc *socketConfig) SetUDPSendBuffer(bytes int) (int, error) {
+	return sc.setUDPBuffer("udp_sndbuf", syscall.SO_SNDBUF, bytes)
+}
+
+func (sc *socketConfig) setUDPBuffer(name string, opt, bytes int) (int, error) {
+	if bytes <= 0 {
+		return 0, errors.New("UDP buffer size must be positive")
+	}
+	effective, err := probeUDPBuffer(opt, bytes)
+	if err != nil {
+		return 0, err
+	}
+	sc.setOption(name, strconv.Itoa(bytes))
+	return effective, nil
+}
+
+// SetLossMaxTTL sets the reorder tolerance of the receiver: how many
+// packets past a gap in the sequence it waits for the missing packet
+// before reporting it lost. Paths that reorder packets, such as bonded
+// or multipath links, otherwise trigger a NAK, and a needless
+// retransmission, for every packet that is merely late. The libsrt
+// default of 0 reports gaps at once.
+//
+// Waiting delays the retransmission of packets that are really lost,
+// eating into the latency, so keep it a little above the largest
+// reorder distance seen, which the PktReorderDistance statistic
+// reports. packets must not be negative.
+func (sc *socketConfig) SetLossMaxTTL(packets int) error {
+	if packets < 0 {
+		return errors.New("loss max TTL must not be negative")
+	}
+	sc.setOption("lossmaxttl", strconv.Itoa(packets))
+	return nil
+}
+
+// SetCongestionController sets the congestion controller, "live" or
+// "file" with the libsrt releases to date. The transport type picks
+// the controller of its name, along with its other defaults such as
+// TSBPD and the message API; setting the controller overrides that
+// choice alone, so that a live connection can, say, send at the pace
+// of file transfers. Both peers must use the same controller: the
+// handshake otherwise fails with a RejectError of reason
+// RejectCongestion.
+//
+// It returns an error if the linked libsrt does not know name.
+func (sc *socketConfig) SetCongestionController(name string) error {
+	if !supportsCongestion(name) {
+		return errors.New("congestion controller " + strconv.Quote(name) + " is not supported by libsrt")
+	}
+	sc.setOption("congestion", name)
+	return nil
+}
+
+// supportsCongestion reports whether libsrt has the congestion
+// controller name, by trying to set it on a fresh socket.
+func supportsCongestion(name string) bool {
+	s, err := srtSocket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return false
+	}
+	defer poll.CloseFunc(s)
+	return srtapi.SetsockoptString(s, 0, srtapi.OptionCongestion, name) == nil
+}
+
+// A RetransmitAlgo is a retransmission algorithm of libsrt.
+type RetransmitAlgo int
+
+const (
+	// RetransmitDefault retransmits lost packets on every NAK and
+	// periodic NAK report, the libsrt default.
+	RetransmitDefault RetransmitAlgo = 0
+
+	// RetransmitReduced retransmits a lost packet at most once per
+	// round trip, which lowers the overhead on links with little
+	// loss, at the cost of slower recovery from bursts.
+	RetransmitReduced RetransmitAlgo = 1
+)
+
+// SetRetransmitAlgo sets the retransmission algorithm. It returns an
+// error for unknown algorithms, and if the linked libsrt predates the
+// option, added in 1.4.2.
+func (sc *socketConfig) SetRetransmitAlgo(algo RetransmitAlgo) error {
+	if algo != RetransmitDefault && algo != RetransmitReduced {
+		return errors.New("unknown retransmission algorithm " + strconv.Itoa(int(algo)))
+	}
+	if !VersionAtLeast(1, 4, 2) {
+		_, _, _, v := Version()
+		return errors.New("retransmission algorithm needs libsrt 1.4.2 or later, linked libsrt is " + v)
+	}
+	sc.setOption("retransmitalgo", strconv.Itoa(int(algo)))
+	return nil
+}
+
+// SetDriftTracer sets whether the receiver traces the drift between
+// its clock and that of the sender, and moves the delivery times of
+// TSBPD to follow it. It is on by default, which keeps the latency
+// steady between clocks that run at slightly different rates, at the
+// cost of small, slow shifts of the delivery times. Between clocks
+// kept in step, as by PTP, turning it off leaves the delivery times
+// exactly where the timestamps and latency put them. It returns an
+// error if the linked libsrt predates the option, added in 1.4.2.
+func (sc *socketConfig) SetDriftTracer(on bool) error {
+	if !VersionAtLeast(1, 4, 2) {
+		_, _, _, v := Version()
+		return errors.New("drift tracer needs libsrt 1.4.2 or later, linked libsrt is " + v)
+	}
+	sc.setOption("drifttracer", strconv.FormatBool(on))
+	return nil
+}
+
+// An ARQMode tells when a connection with FEC also retransmits lost
+// packets, as in the arq parameter of the FEC filter.
+type ARQMode string
+
+const (
+	// ARQAlways retransmits lost packets as without FEC, while FEC
+	// recovers what it can alongside.
+	ARQAlways ARQMode = "always"
+
+	// ARQOnRequest only retransmits the packets FEC could not
+	// recover, the libsrt default.
+	ARQOnRequest ARQMode = "onreq"
+
+	// ARQNever never retransmits: what FEC cannot recover is lost.
+	ARQNever ARQMode = "never"
+)
+
+// FECConfig configures the builtin FEC packet filter of libsrt, in
+// which every row of Cols packets, and every column of Rows packets,
+// is protected by one FEC packet that recovers one loss in it.
+type FECConfig struct {
+	// Cols is the number of packets in a row. It is required.
+	Cols int
+
+	// Rows is the number of packets in a column. 0 or 1, the
+	// default, turns column FEC off, which leaves row FEC alone.
+	Rows int
+
+	// Layout is the arrangement of columns, "even" or "staircase";
+	// empty leaves the libsrt default, "even".
+	Layout string
+
+	// ARQ is the retransmission mode; empty leaves the libsrt
+	// default, ARQOnRequest.
+	ARQ ARQMode
+}
+
+// String returns the packetfilter option value of the configuration,
+// such as "fec,cols:10,rows:5,arq:never".
+func (f FECConfig) String() string {
+	s := "fec,cols:" + strconv.Itoa(f.Cols)
+	if f.Rows != 0 {
+		s += ",rows:" + strconv.Itoa(f.Rows)
+	}
+	if f.Layout != "" {
+		s += ",layout:" + f.Layout
+	}
+	if f.ARQ != "" {
+		s += ",arq:" + string(f.ARQ)
+	}
+	return s
+}
+
+func (f FECConfig) validate() error {
+	if f.Cols < 1 {
+		return errors.New("fec needs at least one column")
+	}
+	if f.Rows < 0 {
+		return errors.New("fec rows must not be negative")
+	}
+	switch f.Layout {
+	case "", "even", "staircase":
+	default:
+		return errors.New("unknown fec layout " + strconv.Quote(f.Layout))
+	}
+	switch f.ARQ {
+	case "", ARQAlways, ARQOnRequest, ARQNever:
+	default:
+		return errors.New("unknown fec arq mode " + strconv.Quote(string(f.ARQ)))
+	}
+	return nil
+}
+
+// SetFEC turns the builtin FEC filter on with cfg. The peers must not
+// set conflicting configurations; a peer that sets none takes on the
+// other's.
+//
+// With ARQNever, NAK reports are turned off too, as nothing would
+// answer them: the connection then recovers losses with FEC alone,
+// and the PktRetransTotal statistic of the sender stays 0. This suits
+// links where a retransmission cannot arrive in time. The handshake,
+// ACKs and keepalives still need a return path, so a link without any
+// remains out of reach of SRT. A later SetFEC with another ARQ mode
+// turns them back on, unless nakreport was set with SetOption since.
+func (sc *socketConfig) SetFEC(cfg FECConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	sc.setOption("packetfilter", cfg.String())
+	switch {
+	case cfg.ARQ == ARQNever:
+		sc.setOption("nakreport", "false")
+		sc.fecNakOff = true
+	case sc.fecNakOff:
+		sc.deleteOption("nakreport")
+		sc.fecNakOff = false
+	}
+	return nil
+}
+
+// defaultKMRefreshRate is the libsrt default of kmrefreshrate.
+const defaultKMRefreshRate = 1 << 24
+
+// SetKeyRefreshRate sets the number of packets sent with a key before
+// the sender switches to a new one, for encrypted connections. The
+// libsrt default is 2^24 packets; streams that run for hours under a
+// key lifetime policy need it lowered.
+//
+// The pre-announce period set by SetKeyPreAnnounce must be less than
+// half the refresh rate. If it is not set, libsrt lowers its default
+// as needed.
+func (sc *socketConfig) SetKeyRefreshRate(packets int) error {
+	if packets <= 0 {
+		return errors.New("key refresh rate must be positive")
+	}
+	sc.setOption("kmrefreshrate", strconv.Itoa(packets))
+	return nil
+}
+
+// SetKeyPreAnnounce sets the number of packets before and after a key
+// switch during which both the old and the new key are in place: the
+// new key is sent to the receiver this long before it is used, and
+// the old one is removed this long after. The libsrt default is 2^16
+// packets.
+//
+// It must be less than half the refresh rate set by
+// SetKeyRefreshRate, or the default of 2^24 packets.
+func (sc *socketConfig) SetKeyPreAnnounce(packets int) error {
+	if packets <= 0 {
+		return errors.New("key pre-announce period must be positive")
+	}
+	sc.setOption("kmpreannounce", strconv.Itoa(packets))
+	return nil
+}
+
+// SetEnforcedEncryption sets whether the handshake fails when the
+// encryption settings of the peers do not match, as when only one of
+// them has a passphrase, or the passphrases differ: the dial then
+// returns a RejectError of reason RejectUnsecure or RejectBadSecret.
+// It is on by default. Turned off, on both peers, such connections go
+// through, but what is sent from a side that has no passphrase, or
+// meets a wrong one, is not decrypted; the Encrypted method of the
+// connection tells whether the connection is secure.
+func (sc *socketConfig) SetEnforcedEncryption(on bool) {
+	sc.setOption("enforcedencryption", strconv.FormatBool(on))
+}
+
+// SetCryptoMode sets the cipher mode of the encryption, which applies
+// once a passphrase is set. Both peers must agree: a caller and a
+// listener set to different modes, or a listener set to CryptoAESGCM
+// and a caller whose libsrt predates it, fail the handshake with a
+// RejectError of reason RejectCrypto or RejectUnsecure. It returns an
+// error for unknown modes, and if the linked libsrt predates the
+// option, added in 1.5.2. The CryptoMode method of the connection
+// reports the mode in effect.
+func (sc *socketConfig) SetCryptoMode(mode CryptoMode) error {
+	if mode != CryptoAuto && mode != CryptoAESCTR && mode != CryptoAESGCM {
+		return errors.New("unknown crypto mode " + strconv.Itoa(int(mode)))
+	}
+	if !VersionAtLeast(1, 5, 2) {
+		_, _, _, v := Version()
+		return errors.New("crypto mode needs libsrt 1.5.2 or later, linked libsrt is " + v)
+	}
+	sc.setOption("cryptomode", strconv.Itoa(int(mode)))
+	return nil
+}
+
+// SetMinPeerVersion sets the oldest version of libsrt the peer may
+// run, major.minor.patch, each from 0 to 255. The handshake with an
+// older peer fails, and the dial returns a RejectError of reason
+// RejectVersion. The version of the peer of a connection is told by
+// its PeerVersion method.
+func (sc *socketConfig) SetMinPeerVersion(major, minor, patch int) error {
+	for _, n := range []int{major, minor, patch} {
+		if n < 0 || n > 0xff {
+			return errors.New("version components must be between 0 and 255")
+		}
+	}
+	sc.setOption("minversion", strconv.Itoa(int(packVersion(major, minor, patch))))
+	return nil
+}
+
+// SetMessageAPI sets whether the connection uses the message API, where
+// each Write sends one message and each Read returns one, or the
+// buffer API, where data is a byte stream as with TCP. Only file mode,
+// transtype 1, has the buffer API; live mode always uses messages, and
+// the dial or listen fails if it is turned off there. Both peers must
+// agree, or the handshake fails with a RejectError of reason
+// RejectMessageAPI.
+func (sc *socketConfig) SetMessageAPI(on bool) {
+	sc.setOption("messageapi", strconv.FormatBool(on))
+}
+
+// BufferingMode is the transport type of a connection, which sets how
+// it buffers what it sends and receives.
+type BufferingMode int
+
+const (
+	// LiveMode paces messages out at the rate they are written and
+	// delivers each at its timestamp plus the latency, dropping
+	// those that arrive too late. It suits media streams. It is the
+	// default.
+	LiveMode BufferingMode = iota
+	// FileMode sends as fast as congestion control allows and
+	// delivers everything, in order, as a byte stream unless the
+	// message API is on; Read returns io.EOF once the peer closes and
+	// all has been read.
+	FileMode
+)
+
+func (m BufferingMode) String() string {
+	switch m {
+	case LiveMode:
+		return "live"
+	case FileMode:
+		return "file"
+	}
+	return "BufferingMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// SetBufferingMode sets the transport type, transtype, of the
+// connections made. It is fixed once a connection is made, and belongs
+// to that connection alone: a process can hold connections of both
+// modes at once, as a relay from a file-mode upstream to a live-mode
+// downstream does, with a Dialer or ListenConfig for each.
+func (sc *socketConfig) SetBufferingMode(m BufferingMode) error {
+	switch m {
+	case LiveMode:
+		sc.setOption("transtype", "0")
+	case FileMode:
+		sc.setOption("transtype", "1")
+	default:
+		return errors.New("unknown buffering mode " + m.String())
+	}
+	return nil
+}
+
+var (
+	errTSBPDNotLive     = errors.New("tsbpdmode requires the live transtype")
+	errStreamAPILive    = errors.New("messageapi off requires the file transtype")
+	errTLPktDropNoTSBPD = errors.New("tlpktdrop requires tsbpdmode")
+	errFCBuffer         = errors.New("flow control window exceeds buffer size")
+	errKMPreAnnounce    = errors.New("kmpreannounce must be less than half of kmrefreshrate")
+	errPayloadMSS       = errors.New("payloadsize exceeds the room the mss leaves for payload")
+)
+
+// An OptionConflictError reports options whose values do not go
+// together, such as a flow control window larger than the receive
+// buffer.
+type OptionConflictError struct {
+	Options []string // names of the conflicting options
+	Err     error    // the rule they break
+}
+
+func (e *OptionConflictError) Error() string {
+	return "options " + strings.Join(e.Options, ", ") + ": " + e.Err.Error()
+}
+
+// Unwrap returns the rule the options break.
+func (e *OptionConflictError) Unwrap() error { return e.Err }
+
+// checkOptions reports combinations of options that libsrt would not
+// honor, as an OptionConflictError naming them.
+func checkOptions(options optionMap) error {
+	for name := range options {
+		if lookupOption(name) == nil {
+			return unknownOptionError(name)
+		}
+	}
+	live := transtypeValue(options["transtype"]) != "1"
+	isSet := func(name string) bool {
+		v, ok := options[name]
+		if !ok {
+			return false
+		}
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	isCleared := func(name string) bool {
+		v, ok := options[name]
+		if !ok {
+			return false
+		}
+		b, err := strconv.ParseBool(v)
+		return err == nil && !b
+	}
+	// conflict names the options of names that are set, as the
+	// defaults of the others take part in the rule too.
+	conflict := func(err error, names ...string) error {
+		var set []string
+		for _, name := range names {
+			if _, ok := options[name]; ok {
+				set = append(set, name)
+			}
+		}
+		return &OptionConflictError{Options: set, Err: err}
+	}
+	if isSet("tsbpdmode") && !live {
+		return conflict(errTSBPDNotLive, "tsbpdmode", "transtype")
+	}
+	if isCleared("messageapi") && live {
+		return conflict(errStreamAPILive, "messageapi", "transtype")
+	}
+	if isCleared("tsbpdmode") && live && !isCleared("tlpktdrop") {
+		return conflict(errTLPktDropNoTSBPD, "tlpktdrop", "tsbpdmode")
+	}
+	mss := maxMSS
+	if v, err := strconv.Atoi(options["mss"]); err == nil {
+		mss = v
+	}
+	if fc, err := strconv.Atoi(options["fc"]); err == nil {
+		window := fc * (mss - srtHeaderSize)
+		for _, name := range []string{"sndbuf", "rcvbuf"} {
+			if size, err := strconv.Atoi(options[name]); err == nil && window > size {
+				return conflict(errFCBuffer, "fc", "mss", name)
+			}
+		}
+	}
+	if size, err := strconv.Atoi(options["payloadsize"]); err == nil && live && size > mss-srtHeaderSize {
+		return conflict(errPayloadMSS, "payloadsize", "mss")
+	}
+	if preannounce, err := strconv.Atoi(options["kmpreannounce"]); err == nil {
+		refresh := defaultKMRefreshRate
+		if v, err := strconv.Atoi(options["kmrefreshrate"]); err == nil {
+			refresh = v
+		}
+		if preannounce > (refresh-1)/2 {
+			return conflict(errKMPreAnnounce, "kmpreannounce", "kmrefreshrate")
+		}
+	}
+	return nil
+}
+
 func configure(ctx context.Context, s int, binding int) error {
 	ctxOptions := optionValue(ctx)
+	if binding == bindPre {
+		if err := checkOptions(ctxOptions); err != nil {
+			return err
+		}
+	}
+	// Setting transtype resets the options that differ between live and
+	// file mode, such as tsbpdmode, tlpktdrop, latency and payloadsize,
+	// to the defaults of the mode, so it goes before the others, whatever
+	// the order of the table or of the raw options.
+	raw := rawOptionValue(ctx)
+	if binding == bindPre {
+		if v, ok := ctxOptions["transtype"]; ok {
+			if err := lookupOption("transtype").apply(s, v); err != nil {
+				return err
+			}
+		}
+		for _, o := range raw {
+			if o.opt == srtapi.OptionTranstype {
+				if err := o.apply(s); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	for _, o := range srtOptions {
-		if o.binding == binding {
+		if o.binding == binding && o.sym != srtapi.OptionTranstype {
 			if v, ok := ctxOptions[o.name]; ok {
 				if err := o.apply(s, v); err != nil {
 					return err
@@ -169,5 +874,14 @@ func configure(ctx context.Context, s int, binding int) error {
 			}
 		}
 	}
+	if binding == bindPre {
+		for _, o := range raw {
+			if o.opt != srtapi.OptionTranstype {
+				if err := o.apply(s); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,1239 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+var checkOptionsTests = []struct {
+	options optionMap
+	err     error
+}{
+	{optionMap{}, nil},
+	{optionMap{"tsbpdmode": "true"}, nil},
+	{optionMap{"tsbpdmode": "true", "transtype": "1"}, errTSBPDNotLive},
+	{optionMap{"tsbpdmode": "false", "transtype": "1"}, nil},
+	{optionMap{"tsbpdmode": "true", "transtype": "file"}, errTSBPDNotLive},
+	{optionMap{"tsbpdmode": "true", "transtype": "live"}, nil},
+	{optionMap{"tsbpdmode": "false"}, errTLPktDropNoTSBPD},
+	{optionMap{"tsbpdmode": "false", "tlpktdrop": "true"}, errTLPktDropNoTSBPD},
+	{optionMap{"tsbpdmode": "false", "tlpktdrop": "false"}, nil},
+	{optionMap{"fc": "1000"}, nil},
+	{optionMap{"fc": "1000", "rcvbuf": "1456000"}, nil},
+	{optionMap{"fc": "1000", "rcvbuf": "1455999"}, errFCBuffer},
+	{optionMap{"fc": "1000", "mss": "1360", "sndbuf": "1316000"}, nil},
+	{optionMap{"fc": "1000", "mss": "1360", "sndbuf": "1315999"}, errFCBuffer},
+	{optionMap{"kmrefreshrate": "1000"}, nil},
+	{optionMap{"kmrefreshrate": "1000", "kmpreannounce": "499"}, nil},
+	{optionMap{"kmrefreshrate": "1000", "kmpreannounce": "500"}, errKMPreAnnounce},
+	{optionMap{"kmpreannounce": "4096"}, nil},
+	{optionMap{"kmpreannounce": "8388608"}, errKMPreAnnounce},
+	{optionMap{"messageapi": "false"}, errStreamAPILive},
+	{optionMap{"messageapi": "false", "transtype": "1"}, nil},
+	{optionMap{"messageapi": "false", "transtype": "file"}, nil},
+	{optionMap{"messageapi": "true"}, nil},
+	{optionMap{"payloadsize": "1456"}, nil},
+	{optionMap{"payloadsize": "1457"}, errPayloadMSS},
+	{optionMap{"payloadsize": "1316", "mss": "1300"}, errPayloadMSS},
+	{optionMap{"payloadsize": "4096", "transtype": "1"}, nil},
+}
+
+func TestCheckOptions(t *testing.T) {
+	for i, tt := range checkOptionsTests {
+		if err := checkOptions(tt.options); !errors.Is(err, tt.err) {
+			t.Errorf("#%d: got %v; want %v", i, err, tt.err)
+		}
+	}
+
+	var sc socketConfig
+	sc.SetTSBPDMode(false)
+	if err := checkOptions(sc.options); err != nil {
+		t.Errorf("SetTSBPDMode(false): got %v; want nil", err)
+	}
+}
+
+func TestConfigureTranstypeFirst(t *testing.T) {
+	// Setting transtype resets latency and messageapi to the defaults
+	// of the mode, so either would be lost if set before it.
+	var raw Dialer
+	raw.SetOption("latency", "300")
+	raw.SetOption("messageapi", "true")
+	if err := raw.RawSetSockOptInt(srtapi.OptionTranstype, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"options", WithOptions(context.Background(), Options("latency", "300", "messageapi", "true", "transtype", "file"))},
+		{"numeric", WithOptions(context.Background(), Options("messageapi", "true", "latency", "300", "transtype", "1"))},
+		{"raw", raw.context(context.Background())},
+	} {
+		s, err := srtapi.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := configure(tt.ctx, s, bindPre); err != nil {
+			srtapi.Close(s)
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if typ, err := srtapi.GetsockoptInt(s, 0, srtapi.OptionTranstype); err != nil || typ != 1 {
+			t.Errorf("%s: transtype = %d, %v; want 1", tt.name, typ, err)
+		}
+		if ms, err := srtapi.GetsockoptInt(s, 0, srtapi.OptionLatency); err != nil || ms != 300 {
+			t.Errorf("%s: latency = %d, %v; want 300", tt.name, ms, err)
+		}
+		if on, err := srtapi.GetsockoptInt(s, 0, srtapi.OptionMessageapi); err != nil || on == 0 {
+			t.Errorf("%s: messageapi = %d, %v; want on", tt.name, on, err)
+		}
+		srtapi.Close(s)
+	}
+
+	u, err := ParseSRTURI("srt://127.0.0.1:5000?latency=300&transtype=file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := Option(WithOptions(context.Background(), u.Options), "transtype"); !ok || v != "1" {
+		t.Errorf("transtype from URI = %q, %v; want 1", v, ok)
+	}
+}
+
+func TestReadMessageCtrl(t *testing.T) {
+	for _, tsbpd := range []bool{true, false} {
+		var lc ListenConfig
+		lc.SetTSBPDMode(tsbpd)
+		ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			for i := 0; i < 3; i++ {
+				if _, err := c.Write([]byte("message")); err != nil {
+					return
+				}
+			}
+			var b [1]byte
+			c.Read(b[:])
+		}()
+
+		var d Dialer
+		d.SetTSBPDMode(tsbpd)
+		c, err := d.Dial("srt", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var prev MessageCtrl
+		for i := 0; i < 3; i++ {
+			b := make([]byte, 1500)
+			n, mc, err := c.(*SRTConn).ReadMessageCtrl(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b[:n]) != "message" {
+				t.Fatalf("got %q; want %q", b[:n], "message")
+			}
+			if i > 0 && (mc.MsgNo != prev.MsgNo+1 || mc.PktSeq != prev.PktSeq+1) {
+				t.Errorf("tsbpd=%v: got message %d, packet %d after %d, %d", tsbpd, mc.MsgNo, mc.PktSeq, prev.MsgNo, prev.PktSeq)
+			}
+			if tsbpd && mc.SrcTime <= 0 {
+				t.Errorf("got source time %d", mc.SrcTime)
+			}
+			prev = mc
+		}
+	}
+}
+
+func TestReadMessageCtrlRecvTime(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	const count, interval = 5, 20 * time.Millisecond
+	go func() {
+		for i := 0; i < count; i++ {
+			if _, err := s.Write([]byte("paced")); err != nil {
+				return
+			}
+			time.Sleep(interval)
+		}
+	}()
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var prev MessageCtrl
+	for i := 0; i < count; i++ {
+		b := make([]byte, 1500)
+		_, mc, err := c.ReadMessageCtrl(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mc.RecvTime.IsZero() {
+			t.Fatalf("message %d: zero receive time", i)
+		}
+		if i > 0 && !mc.RecvTime.After(prev.RecvTime) {
+			t.Errorf("message %d received at %v, not after %v", i, mc.RecvTime, prev.RecvTime)
+		}
+		prev = mc
+	}
+}
+
+func TestSetFlowControlWindow(t *testing.T) {
+	var d Dialer
+	if err := d.SetFlowControlWindow(minFC - 1); err == nil {
+		t.Errorf("SetFlowControlWindow(%d) succeeded; want error", minFC-1)
+	}
+	var lc ListenConfig
+	if err := lc.SetFlowControlWindow(1000); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			var b [1]byte
+			c.Read(b[:])
+		}
+	}()
+	c, err := d.Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	fc, err := c.(*SRTConn).FlowControlWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc <= 0 || fc > 1000 {
+		t.Errorf("got flow control window %d; want within the 1000 of the listener", fc)
+	}
+}
+
+func TestSetLossMaxTTL(t *testing.T) {
+	var d Dialer
+	if err := d.SetLossMaxTTL(-1); err == nil {
+		t.Error("SetLossMaxTTL(-1) succeeded; want error")
+	}
+	if err := d.SetLossMaxTTL(10); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			var b [1]byte
+			c.Read(b[:])
+		}
+	}()
+	c, err := d.Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ttl, err := srtapi.GetsockoptInt(c.(*SRTConn).fd.pfd.Sysfd, 0, srtapi.OptionLossmaxttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl != 10 {
+		t.Errorf("got loss max TTL %d; want 10", ttl)
+	}
+}
+
+func TestKMStateString(t *testing.T) {
+	for _, tt := range []struct {
+		s    KMState
+		want string
+	}{
+		{KMUnsecured, "unsecured"},
+		{KMSecured, "secured"},
+		{KMBadSecret, "bad secret"},
+		{KMState(9), "KMState(9)"},
+	} {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("KMState(%d).String() = %q; want %q", int(tt.s), got, tt.want)
+		}
+	}
+}
+
+func TestSocketConfigContext(t *testing.T) {
+	var sc socketConfig
+	if err := sc.SetOption("streamid", "shared"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.SetOption("latency", "200"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.SetOption("nosuchoption", "1"); err == nil {
+		t.Error("SetOption with unknown name succeeded")
+	}
+	if err := sc.SetOption("latency", "high"); err == nil {
+		t.Error("SetOption with invalid value succeeded")
+	}
+
+	ctx := sc.context(WithOptions(context.Background(), Options("streamid", "per-call")))
+	for _, tt := range []struct {
+		key, want string
+	}{
+		{"streamid", "per-call"},
+		{"latency", "200"},
+	} {
+		if v, _ := Option(ctx, tt.key); v != tt.want {
+			t.Errorf("%s = %q; want %q", tt.key, v, tt.want)
+		}
+	}
+
+	ctx = sc.context(WithOptions(context.Background(), Options("nosuchoption", "1")))
+	if err := checkOptions(optionValue(ctx)); err == nil || !strings.Contains(err.Error(), "nosuchoption") {
+		t.Errorf("checkOptions with unknown option = %v; want error naming it", err)
+	}
+}
+
+func TestDialUnknownContextOption(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var d Dialer
+	ctx := WithOptions(context.Background(), Options("nosuchoption", "1"))
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatal("dial with unknown context option succeeded")
+	}
+	if !strings.Contains(err.Error(), "nosuchoption") {
+		t.Errorf("got %v; want error naming the option", err)
+	}
+}
+
+func TestKeyStateMismatchedPassphrases(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("passphrase", "listener-secret", "enforcedencryption", "false"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			var b [1]byte
+			c.Read(b[:])
+		}
+	}()
+
+	ctx = WithOptions(context.Background(), Options("passphrase", "caller-secret", "enforcedencryption", "false"))
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := c.(*SRTConn)
+	rcv, err := sc.RcvKeyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcv != KMBadSecret {
+		t.Errorf("RcvKeyState() = %v; want %v", rcv, KMBadSecret)
+	}
+	snd, err := sc.SndKeyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snd == KMSecured {
+		t.Errorf("SndKeyState() = %v; want other than %v", snd, KMSecured)
+	}
+
+	// With encryption enforced, the handshake fails instead.
+	ln2, err := ListenContext(WithOptions(context.Background(), Options("passphrase", "listener-secret")), "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln2.Close()
+	go func() {
+		c, err := ln2.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+	c, err = d.DialContext(WithOptions(context.Background(), Options("passphrase", "caller-secret")), "srt", ln2.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatal("dial with mismatched passphrase and enforced encryption succeeded")
+	}
+	var rerr *RejectError
+	if !errors.As(err, &rerr) || rerr.Reason != RejectBadSecret {
+		t.Errorf("got %v; want reject for %v", err, RejectBadSecret)
+	}
+}
+
+func TestSetEnforcedEncryption(t *testing.T) {
+	for _, enforced := range []bool{true, false} {
+		var lc ListenConfig
+		lc.SetEnforcedEncryption(enforced)
+		ctx := WithOptions(context.Background(), Options("passphrase", "listener-secret"))
+		ln, err := lc.Listen(ctx, "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			c, err := ln.Accept()
+			if err == nil {
+				defer c.Close()
+				var b [1]byte
+				c.Read(b[:])
+			}
+		}()
+
+		// The caller has no passphrase.
+		var d Dialer
+		d.SetEnforcedEncryption(enforced)
+		c, err := d.Dial("srt", ln.Addr().String())
+		if enforced {
+			if err == nil {
+				c.Close()
+				t.Fatal("enforced: unencrypted caller got through")
+			}
+			var rerr *RejectError
+			if !errors.As(err, &rerr) || rerr.Reason != RejectUnsecure {
+				t.Errorf("enforced: got %v; want reject for %v", err, RejectUnsecure)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("relaxed: %v", err)
+		}
+		defer c.Close()
+		encrypted, err := c.(*SRTConn).Encrypted()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted {
+			t.Error("relaxed: connection without passphrase reported as encrypted")
+		}
+	}
+}
+
+func TestSetMinPeerVersion(t *testing.T) {
+	var d Dialer
+	for _, v := range [][3]int{{-1, 0, 0}, {1, 256, 0}, {1, 5, 1000}} {
+		if err := d.SetMinPeerVersion(v[0], v[1], v[2]); err == nil {
+			t.Errorf("SetMinPeerVersion(%d, %d, %d) succeeded; want error", v[0], v[1], v[2])
+		}
+	}
+
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	// The peer runs the same libsrt as the caller.
+	major, minor, patch, _ := Version()
+	for _, tt := range []struct {
+		major, minor, patch int
+		ok                  bool
+	}{
+		{major, minor, patch, true},
+		{1, 0, 0, true},
+		{major, minor, patch + 1, false},
+		{255, 0, 0, false},
+	} {
+		var d Dialer
+		if err := d.SetMinPeerVersion(tt.major, tt.minor, tt.patch); err != nil {
+			t.Fatal(err)
+		}
+		c, err := d.Dial("srt", ln.Addr().String())
+		if tt.ok {
+			if err != nil {
+				t.Errorf("min %d.%d.%d: %v", tt.major, tt.minor, tt.patch, err)
+				continue
+			}
+			c.Close()
+			continue
+		}
+		if err == nil {
+			c.Close()
+			t.Errorf("min %d.%d.%d: dial to %d.%d.%d succeeded", tt.major, tt.minor, tt.patch, major, minor, patch)
+			continue
+		}
+		var rerr *RejectError
+		if !errors.As(err, &rerr) || rerr.Reason != RejectVersion {
+			t.Errorf("min %d.%d.%d: got %v; want reject for %v", tt.major, tt.minor, tt.patch, err, RejectVersion)
+		}
+	}
+}
+
+func TestSetUDPBuffers(t *testing.T) {
+	var d Dialer
+	if _, err := d.SetUDPRecvBuffer(0); err == nil {
+		t.Error("SetUDPRecvBuffer(0) succeeded; want error")
+	}
+	if _, err := d.SetUDPSendBuffer(-1); err == nil {
+		t.Error("SetUDPSendBuffer(-1) succeeded; want error")
+	}
+
+	const size = 4 << 20
+	for _, tt := range []struct {
+		name string
+		set  func(int) (int, error)
+		opt  int
+	}{
+		{"SetUDPRecvBuffer", d.SetUDPRecvBuffer, syscall.SO_RCVBUF},
+		{"SetUDPSendBuffer", d.SetUDPSendBuffer, syscall.SO_SNDBUF},
+	} {
+		effective, err := tt.set(size)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// The kernel may cap the size, but not down to its default.
+		if effective <= 0 || effective == defaultUDPBuffer(t, tt.opt) {
+			t.Errorf("%s(%d) = %d; want a size other than the default", tt.name, size, effective)
+		}
+	}
+
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+	c, err := d.Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, opt := range []int{srtapi.OptionUDPRcvbuf, srtapi.OptionUDPSndbuf} {
+		if n, err := srtapi.GetsockoptInt(c.(*SRTConn).fd.pfd.Sysfd, 0, opt); err != nil || n != size {
+			t.Errorf("option %d = %d, %v; want %d", opt, n, err, size)
+		}
+	}
+}
+
+// defaultUDPBuffer returns the size the kernel gives the socket buffer
+// opt of a new UDP socket.
+func defaultUDPBuffer(t *testing.T, opt int) int {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	rc, err := pc.(*net.UDPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	var gerr error
+	if err := rc.Control(func(fd uintptr) {
+		n, gerr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, opt)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if gerr != nil {
+		t.Fatal(gerr)
+	}
+	return n
+}
+
+func TestSetCongestionController(t *testing.T) {
+	var d Dialer
+	if err := d.SetCongestionController("nosuchcc"); err == nil {
+		t.Error("SetCongestionController(\"nosuchcc\") succeeded; want error")
+	}
+	if err := d.SetCongestionController("file"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cc := range []string{"file", "live"} {
+		var lc ListenConfig
+		if err := lc.SetCongestionController(cc); err != nil {
+			t.Fatal(err)
+		}
+		ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			c, err := ln.Accept()
+			if err == nil {
+				defer c.Close()
+				var b [1]byte
+				c.Read(b[:])
+			}
+		}()
+
+		c, err := d.Dial("srt", ln.Addr().String())
+		if cc != "file" {
+			if err == nil {
+				c.Close()
+				t.Fatalf("dial with file congestion control to a %s listener succeeded", cc)
+			}
+			var rerr *RejectError
+			if !errors.As(err, &rerr) || rerr.Reason != RejectCongestion {
+				t.Errorf("got %v; want reject for %v", err, RejectCongestion)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		got, err := srtapi.GetsockoptString(c.(*SRTConn).fd.pfd.Sysfd, 0, srtapi.OptionCongestion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "file" {
+			t.Errorf("got congestion controller %q; want \"file\"", got)
+		}
+	}
+}
+
+func TestSetRetransmitAlgo(t *testing.T) {
+	var d Dialer
+	if err := d.SetRetransmitAlgo(2); err == nil {
+		t.Error("SetRetransmitAlgo(2) succeeded; want error")
+	}
+	if !VersionAtLeast(1, 4, 2) {
+		if err := d.SetRetransmitAlgo(RetransmitReduced); err == nil {
+			t.Error("SetRetransmitAlgo succeeded with a libsrt that lacks it")
+		}
+		_, _, _, v := Version()
+		t.Skipf("libsrt %s lacks the retransmitalgo option", v)
+	}
+	if err := d.SetRetransmitAlgo(RetransmitReduced); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			var b [1]byte
+			c.Read(b[:])
+		}
+	}()
+	c, err := d.Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	algo, err := srtapi.GetsockoptInt(c.(*SRTConn).fd.pfd.Sysfd, 0, srtapi.OptionRetransmitalgo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if RetransmitAlgo(algo) != RetransmitReduced {
+		t.Errorf("got retransmission algorithm %d; want %d", algo, RetransmitReduced)
+	}
+}
+
+func TestSetDriftTracer(t *testing.T) {
+	if !VersionAtLeast(1, 4, 2) {
+		var d Dialer
+		if err := d.SetDriftTracer(false); err == nil {
+			t.Error("SetDriftTracer succeeded with a libsrt that lacks it")
+		}
+		_, _, _, v := Version()
+		t.Skipf("libsrt %s lacks the drifttracer option", v)
+	}
+	for _, on := range []bool{false, true} {
+		var lc ListenConfig
+		if err := lc.SetDriftTracer(on); err != nil {
+			t.Fatal(err)
+		}
+		ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			c, err := ln.Accept()
+			if err == nil {
+				defer c.Close()
+				b := make([]byte, 1500)
+				n, err := c.Read(b)
+				if err == nil {
+					c.Write(b[:n])
+				}
+			}
+		}()
+
+		var d Dialer
+		if err := d.SetDriftTracer(on); err != nil {
+			t.Fatal(err)
+		}
+		c, err := d.Dial("srt", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("drift tracer %v: %v", on, err)
+		}
+		defer c.Close()
+		v, err := srtapi.GetsockoptInt(c.(*SRTConn).fd.pfd.Sysfd, 0, srtapi.OptionDrifttracer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (v != 0) != on {
+			t.Errorf("drift tracer set to %v; got %d", on, v)
+		}
+		msg := []byte("drift")
+		if _, err := c.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 1500)
+		n, err := c.Read(b)
+		if err != nil {
+			t.Fatalf("drift tracer %v: %v", on, err)
+		}
+		if !bytes.Equal(b[:n], msg) {
+			t.Errorf("drift tracer %v: echoed %q; want %q", on, b[:n], msg)
+		}
+	}
+}
+
+func TestSRTConnCryptoMode(t *testing.T) {
+	var version uint32
+	km, mode := KMSecured, 0
+	oldVersion, oldGet := getVersionFunc, getsockoptIntFunc
+	defer func() { getVersionFunc, getsockoptIntFunc = oldVersion, oldGet }()
+	getVersionFunc = func() uint32 { return version }
+	getsockoptIntFunc = func(fd, level, opt int) (int, error) {
+		switch opt {
+		case srtapi.OptionSndkmstate, srtapi.OptionRcvkmstate:
+			return int(km), nil
+		case srtapi.OptionCryptomode:
+			return mode, nil
+		}
+		return oldGet(fd, level, opt)
+	}
+
+	c := newSRTConn(&netFD{net: "srt"})
+	for _, tt := range []struct {
+		version uint32
+		km      KMState
+		mode    int
+		want    CryptoMode
+	}{
+		{packVersion(1, 5, 2), KMUnsecured, 2, CryptoAuto},
+		{packVersion(1, 4, 1), KMSecured, 2, CryptoAESCTR},
+		{packVersion(1, 5, 2), KMSecured, 0, CryptoAESCTR},
+		{packVersion(1, 5, 2), KMSecured, 1, CryptoAESCTR},
+		{packVersion(1, 5, 2), KMSecured, 2, CryptoAESGCM},
+	} {
+		version, km, mode = tt.version, tt.km, tt.mode
+		if got, err := c.CryptoMode(); err != nil || got != tt.want {
+			t.Errorf("version %#x, %v, mode %d: got %v, %v; want %v", tt.version, tt.km, tt.mode, got, err, tt.want)
+		}
+	}
+}
+
+func TestSetCryptoMode(t *testing.T) {
+	var d Dialer
+	if err := d.SetCryptoMode(3); err == nil {
+		t.Error("SetCryptoMode(3) succeeded; want error")
+	}
+	if !VersionAtLeast(1, 5, 2) {
+		if err := d.SetCryptoMode(CryptoAESGCM); err == nil {
+			t.Error("SetCryptoMode succeeded with a libsrt that lacks it")
+		}
+	}
+
+	dial := func(ln net.Listener, mode CryptoMode) (*SRTConn, error) {
+		var d Dialer
+		if mode != CryptoAuto {
+			if err := d.SetCryptoMode(mode); err != nil {
+				return nil, err
+			}
+		}
+		ctx := WithOptions(context.Background(), Options("passphrase", "crypto-secret"))
+		c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+		if err != nil {
+			return nil, err
+		}
+		return c.(*SRTConn), nil
+	}
+	for _, mode := range []CryptoMode{CryptoAuto, CryptoAESCTR, CryptoAESGCM} {
+		if mode == CryptoAESGCM && !VersionAtLeast(1, 5, 2) {
+			_, _, _, v := Version()
+			t.Logf("libsrt %s lacks AES-GCM; skipping it", v)
+			continue
+		}
+		var lc ListenConfig
+		if mode != CryptoAuto {
+			if err := lc.SetCryptoMode(mode); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ctx := WithOptions(context.Background(), Options("passphrase", "crypto-secret"))
+		ln, err := lc.Listen(ctx, "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer c.Close()
+			}
+		}()
+
+		c, err := dial(ln, mode)
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		got, err := c.CryptoMode()
+		c.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := mode
+		if want == CryptoAuto {
+			want = CryptoAESCTR
+		}
+		if got != want {
+			t.Errorf("%v: got crypto mode %v; want %v", mode, got, want)
+		}
+
+		if mode == CryptoAESGCM {
+			// A caller set to another mode is rejected.
+			c, err := dial(ln, CryptoAESCTR)
+			if err == nil {
+				c.Close()
+				t.Fatal("AES-CTR caller got through to an AES-GCM listener")
+			}
+			var rerr *RejectError
+			if !errors.As(err, &rerr) || rerr.Reason != RejectCrypto {
+				t.Errorf("got %v; want reject for %v", err, RejectCrypto)
+			}
+		}
+	}
+}
+
+func TestSetMessageAPI(t *testing.T) {
+	for _, tt := range []struct {
+		listener, caller bool
+	}{
+		{true, true},
+		{false, false},
+		{true, false},
+	} {
+		var lc ListenConfig
+		lc.SetOption("transtype", "1")
+		lc.SetMessageAPI(tt.listener)
+		ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			c, _ := ln.Accept()
+			accepted <- c
+		}()
+
+		var d Dialer
+		d.SetOption("transtype", "1")
+		d.SetMessageAPI(tt.caller)
+		c, err := d.Dial("srt", ln.Addr().String())
+		if tt.listener != tt.caller {
+			if err == nil {
+				c.Close()
+				t.Fatalf("messageapi %v to %v: dial succeeded", tt.caller, tt.listener)
+			}
+			var rerr *RejectError
+			if !errors.As(err, &rerr) || rerr.Reason != RejectMessageAPI {
+				t.Errorf("messageapi %v to %v: got %v; want reject for %v", tt.caller, tt.listener, err, RejectMessageAPI)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("messageapi %v to %v: %v", tt.caller, tt.listener, err)
+		}
+		defer c.Close()
+		s := <-accepted
+		if s == nil {
+			t.FailNow()
+		}
+		defer s.Close()
+
+		if _, err := c.Write([]byte("MESSAGEAPI TEST")); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 64)
+		_, _, err = s.(*SRTConn).ReadMessageCtrl(b)
+		if tt.listener && err != nil {
+			t.Errorf("ReadMessageCtrl with the message API: %v", err)
+		}
+		if !tt.listener && !errors.Is(err, errStreamAPI) {
+			t.Errorf("ReadMessageCtrl with the buffer API: got %v; want %v", err, errStreamAPI)
+		}
+		if _, err := NewSRTMessageConn(s.(*SRTConn)); (err == nil) != tt.listener {
+			t.Errorf("NewSRTMessageConn with messageapi %v: got %v", tt.listener, err)
+		}
+	}
+
+	var d Dialer
+	d.SetMessageAPI(false)
+	if c, err := d.Dial("srt", "127.0.0.1:1"); err == nil || !errors.Is(err, errStreamAPILive) {
+		if c != nil {
+			c.Close()
+		}
+		t.Errorf("live dial with the buffer API: got %v; want %v", err, errStreamAPILive)
+	}
+}
+
+func TestSetBufferingMode(t *testing.T) {
+	// Dialers copied from a template must not share their options.
+	var tmpl Dialer
+	tmpl.SetOption("latency", "120")
+	live, file := tmpl, tmpl
+	if err := file.SetBufferingMode(FileMode); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tmpl.options["transtype"]; ok {
+		t.Errorf("SetBufferingMode on a copy changed the template: %v", tmpl.options)
+	}
+	if _, ok := live.options["transtype"]; ok {
+		t.Errorf("SetBufferingMode on a copy changed another copy: %v", live.options)
+	}
+	if err := live.SetBufferingMode(BufferingMode(2)); err == nil {
+		t.Error("SetBufferingMode(2) succeeded")
+	}
+
+	// Hold a connection of each mode at once.
+	connect := func(m BufferingMode, d Dialer) (c, s net.Conn) {
+		var lc ListenConfig
+		lc.SetBufferingMode(m)
+		ln, err := lc.Listen(context.Background(), "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			c, _ := ln.Accept()
+			accepted <- c
+		}()
+		c, err = d.Dial("srt", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("%v: %v", m, err)
+		}
+		if s = <-accepted; s == nil {
+			c.Close()
+			t.FailNow()
+		}
+		return c, s
+	}
+	fc, fs := connect(FileMode, file)
+	defer fs.Close()
+	lc, ls := connect(LiveMode, live)
+	defer lc.Close()
+	defer ls.Close()
+
+	// Live mode sends each Write as one message, and refuses those
+	// larger than a packet.
+	if _, err := lc.Write(make([]byte, 4096)); err == nil {
+		t.Error("live mode: 4096-byte Write succeeded")
+	}
+	if _, err := lc.Write(make([]byte, 1316)); err != nil {
+		t.Fatalf("live mode: %v", err)
+	}
+	b := make([]byte, 4096)
+	if n, err := ls.Read(b); err != nil || n != 1316 {
+		t.Errorf("live mode: Read = %d, %v; want 1316, <nil>", n, err)
+	}
+
+	// File mode streams any amount, and ends in io.EOF on close.
+	want := make([]byte, 64<<10)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	go func() {
+		fc.Write(want)
+		fc.Close()
+	}()
+	fs.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, err := io.ReadAll(fs)
+	if err != nil {
+		t.Errorf("file mode: ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("file mode: read %d bytes; want the %d written", len(got), len(want))
+	}
+}
+
+func TestFECConfig(t *testing.T) {
+	for _, tt := range []struct {
+		cfg  FECConfig
+		want string
+		ok   bool
+	}{
+		{FECConfig{Cols: 10}, "fec,cols:10", true},
+		{FECConfig{Cols: 10, Rows: 5, Layout: "staircase", ARQ: ARQNever}, "fec,cols:10,rows:5,layout:staircase,arq:never", true},
+		{FECConfig{Cols: 4, ARQ: ARQOnRequest}, "fec,cols:4,arq:onreq", true},
+		{FECConfig{}, "", false},
+		{FECConfig{Cols: 10, Rows: -1}, "", false},
+		{FECConfig{Cols: 10, Layout: "diagonal"}, "", false},
+		{FECConfig{Cols: 10, ARQ: "sometimes"}, "", false},
+	} {
+		var sc socketConfig
+		err := sc.SetFEC(tt.cfg)
+		if (err == nil) != tt.ok {
+			t.Errorf("SetFEC(%+v) = %v; want ok %v", tt.cfg, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if got := sc.options["packetfilter"]; got != tt.want {
+			t.Errorf("SetFEC(%+v) set packetfilter %q; want %q", tt.cfg, got, tt.want)
+		}
+		if nak, ok := sc.options["nakreport"]; (tt.cfg.ARQ == ARQNever) != (ok && nak == "false") {
+			t.Errorf("SetFEC(%+v) set nakreport %q", tt.cfg, nak)
+		}
+		if err := checkOptions(sc.options); err != nil {
+			t.Errorf("SetFEC(%+v): checkOptions: %v", tt.cfg, err)
+		}
+	}
+
+	// Another ARQ mode turns NAK reports back on, unless they were
+	// set explicitly since.
+	var sc socketConfig
+	sc.SetFEC(FECConfig{Cols: 10, ARQ: ARQNever})
+	sc.SetFEC(FECConfig{Cols: 10, ARQ: ARQAlways})
+	if nak, ok := sc.options["nakreport"]; ok {
+		t.Errorf("SetFEC with ARQAlways after ARQNever left nakreport %q", nak)
+	}
+	sc.SetFEC(FECConfig{Cols: 10, ARQ: ARQNever})
+	sc.SetOption("nakreport", "false")
+	sc.SetFEC(FECConfig{Cols: 10, ARQ: ARQOnRequest})
+	if nak := sc.options["nakreport"]; nak != "false" {
+		t.Errorf("SetFEC cleared nakreport set with SetOption, now %q", nak)
+	}
+	sc = socketConfig{}
+	sc.SetFEC(FECConfig{Cols: 10, ARQ: ARQNever})
+	if err := sc.SetOptions(Options("nakreport", "false")); err != nil {
+		t.Fatal(err)
+	}
+	sc.SetFEC(FECConfig{Cols: 10, ARQ: ARQOnRequest})
+	if nak := sc.options["nakreport"]; nak != "false" {
+		t.Errorf("SetFEC cleared nakreport set with SetOptions, now %q", nak)
+	}
+}
+
+// lossyRelay forwards datagrams between the first peer that sends to it
+// and target, dropping every nth data packet on the way to target.
+func lossyRelay(t *testing.T, target net.Addr, n int) net.Addr {
+	var data int
+	return testRelay(t, target, func(toTarget bool, b []byte) bool {
+		// The high bit of the first byte is set on control
+		// packets, which are left alone.
+		if !toTarget || len(b) == 0 || b[0]&0x80 != 0 {
+			return false
+		}
+		data++
+		return data%n == 0
+	})
+}
+
+// testRelay forwards datagrams between the first peer that sends to it
+// and target, except those drop returns true for.
+func testRelay(t *testing.T, target net.Addr, drop func(toTarget bool, b []byte) bool) net.Addr {
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	dst, err := net.ResolveUDPAddr("udp4", target.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		var src net.Addr
+		b := make([]byte, 2048)
+		for {
+			m, from, err := pc.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			toTarget := from.String() != dst.String()
+			if drop(toTarget, b[:m]) {
+				continue
+			}
+			if !toTarget {
+				if src != nil {
+					pc.WriteTo(b[:m], src)
+				}
+				continue
+			}
+			src = from
+			pc.WriteTo(b[:m], dst)
+		}
+	}()
+	return pc.LocalAddr()
+}
+
+func TestSetFECWithoutARQ(t *testing.T) {
+	fec := FECConfig{Cols: 10, ARQ: ARQNever}
+	var lc ListenConfig
+	if err := lc.SetFEC(fec); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := lc.Listen(context.Background(), "srt4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const count = 200
+	received := make(chan []int, 1)
+	go func() {
+		var got []int
+		defer func() { received <- got }()
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.SetReadDeadline(time.Now().Add(10 * time.Second))
+		b := make([]byte, 1500)
+		for len(got) < count {
+			n, err := c.Read(b)
+			if err != nil {
+				return
+			}
+			i, _ := strconv.Atoi(string(b[:n]))
+			got = append(got, i)
+		}
+	}()
+
+	// Every 25th data packet is dropped, at most one per row of 10
+	// and its FEC packet, so row FEC recovers all of them.
+	var d Dialer
+	if err := d.SetFEC(fec); err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.Dial("srt4", lossyRelay(t, ln.Addr(), 25).String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < count; i++ {
+		if _, err := c.Write([]byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	got := <-received
+	if len(got) != count {
+		t.Fatalf("received %d of %d messages", len(got), count)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("message %d is %d; want %d", i, v, i)
+		}
+	}
+	stats, err := c.(*SRTConn).SRTStats(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.PktRetransTotal != 0 {
+		t.Errorf("sender retransmitted %d packets; want 0 with FEC alone", stats.PktRetransTotal)
+	}
+}
+
+func TestOptionSetBuilder(t *testing.T) {
+	base := Options().Latency(200 * time.Millisecond).StreamID("cam1")
+	withPass := base.Passphrase("0123456789")
+	if len(base.list) != 2 {
+		t.Errorf("building on a set changed it: %v", base.list)
+	}
+	want := optionMap{"latency": "200", "streamid": "cam1", "passphrase": "0123456789"}
+	if got := withPass.optionMap(); len(got) != len(want) || got["latency"] != "200" || got["streamid"] != "cam1" || got["passphrase"] != "0123456789" {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if err := withPass.MaxBW(-1).Validate(); err != nil {
+		t.Errorf("Validate() = %v; want nil", err)
+	}
+
+	err := Options().FlowControlWindow(1000).Buffers(8192000, 1000000).Validate()
+	var cerr *OptionConflictError
+	if !errors.As(err, &cerr) || !errors.Is(err, errFCBuffer) {
+		t.Fatalf("Validate() = %v; want %v", err, errFCBuffer)
+	}
+	if got := strings.Join(cerr.Options, ","); got != "fc,rcvbuf" {
+		t.Errorf("conflict names %q; want %q", got, "fc,rcvbuf")
+	}
+	if err := Options().Set("latency", "soon").Validate(); err == nil {
+		t.Error("Validate() with a bad value succeeded")
+	}
+
+	var d Dialer
+	d.SetOption("mss", "1300")
+	if err := d.SetOptions(Options().PayloadSize(1316).Latency(time.Second)); !errors.Is(err, errPayloadMSS) {
+		t.Errorf("SetOptions() = %v; want %v", err, errPayloadMSS)
+	}
+	if _, ok := d.options["latency"]; ok {
+		t.Error("failed SetOptions set some options")
+	}
+	if err := d.SetOptions(Options().PayloadSize(1000)); err != nil {
+		t.Fatal(err)
+	}
+	if d.options["payloadsize"] != "1000" || d.options["mss"] != "1300" {
+		t.Errorf("options after SetOptions = %v", d.options)
+	}
+}
@@ -20,9 +20,12 @@ package srt
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/openfresh/gosrt/conf"
@@ -43,6 +46,21 @@ func (c *conn) ok() bool { return c != nil && c.fd != nil }
 // Implementation of the Conn interface.
 
 // Read implements the Conn Read method.
+//
+// Each Read is a single srt_recv into b, with no intermediate buffer:
+// in live mode, or with the message API, it returns one message, and
+// with the buffer API as much of the receive buffer as fits in b. It
+// only waits, without further calls into libsrt, when nothing is
+// available, so a large b is filled with all that is available in one
+// call rather than in chunks.
+//
+// In live mode, and with the message API, a message is only ever
+// returned whole, so b should hold the largest message the peer sends.
+// A read deadline that passes returns 0 bytes and the timeout: a
+// message is either received whole before it, or stays in the receive
+// buffer for the next Read, never split across the two. With the
+// buffer API, a Read returns what is available, so the deadline only
+// passes while nothing is.
 func (c *conn) Read(b []byte) (int, error) {
 	if !c.ok() {
 		return 0, srtapi.EINVPARAM
@@ -55,6 +73,19 @@ func (c *conn) Read(b []byte) (int, error) {
 }
 
 // Write implements the Conn Write method.
+//
+// Like that of net.TCPConn, Write is safe for concurrent use: each
+// call holds the send lock of the connection until all of b is
+// written, as does WriteBuffers, so the data of concurrent calls is
+// never interleaved. ReadFrom only holds it for each chunk it copies,
+// so concurrent writes may land between its chunks. In message mode,
+// each Write sends b as one whole message.
+//
+// While the send buffer is full, as with a peer that does not read,
+// Write waits for room until the write deadline. If the deadline
+// passes first, it returns the number of bytes libsrt accepted so far
+// with an error matching os.ErrDeadlineExceeded; those bytes are
+// still sent, the rest of b is not.
 func (c *conn) Write(b []byte) (int, error) {
 	if !c.ok() {
 		return 0, srtapi.EINVPARAM
@@ -81,11 +112,16 @@ func (c *conn) Close() error {
 // LocalAddr returns the local network address.
 // The Addr returned is shared by all invocations of LocalAddr, so
 // do not modify it.
+//
+// For a connection accepted by a listener on a wildcard address, the
+// first call looks up the address with the route back to the peer,
+// which is wrong where replies leave from another address than
+// requests arrive on, as with a virtual or anycast address.
 func (c *conn) LocalAddr() net.Addr {
 	if !c.ok() {
 		return nil
 	}
-	return c.fd.laddr
+	return c.fd.localAddr()
 }
 
 // RemoteAddr returns the remote network address.
@@ -106,6 +142,7 @@ func (c *conn) SetDeadline(t time.Time) error {
 	if err := c.fd.pfd.SetDeadline(t); err != nil {
 		return &OpError{Op: "set", Net: c.fd.net, Source: nil, Addr: c.fd.laddr, Err: err}
 	}
+	c.fd.setWriteDeadline(t)
 	return nil
 }
 
@@ -128,12 +165,92 @@ func (c *conn) SetWriteDeadline(t time.Time) error {
 	if err := c.fd.pfd.SetWriteDeadline(t); err != nil {
 		return &OpError{Op: "set", Net: c.fd.net, Source: nil, Addr: c.fd.laddr, Err: err}
 	}
+	c.fd.setWriteDeadline(t)
 	return nil
 }
 
-// StreamID return stream ID
+// File returns a copy of the UDP socket libsrt uses underneath the
+// connection. It is the caller's responsibility to close f when
+// finished. Closing c does not affect f, and closing f does not
+// affect c.
+//
+// The SRT protocol state lives in libsrt, in user space, so the
+// descriptor alone is not a usable SRT connection, for instance in
+// another process. It is meant for inspection such as SO_* queries.
+// Connections multiplexed over the same UDP socket, like those
+// accepted by one listener, return copies of the same socket.
+//
+// libsrt does not expose the UDP sockets it makes itself, so File only
+// works for connections whose socket was handed to libsrt: those of
+// DialSRTOnConn and ListenSRTOnConn, and of a Dialer with ReusePort or
+// InterfaceName set. It fails for others.
+func (c *conn) File() (f *os.File, err error) {
+	if !c.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	f, err = c.fd.dup()
+	if err != nil {
+		err = &OpError{Op: "file", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return
+}
+
+// StreamID returns the stream ID the caller presented in the
+// handshake. It is read once the connection is established, so the
+// call costs no round trip to libsrt.
 func (c *conn) StreamID() (string, error) {
-	return srtapi.GetsockflagString(c.fd.pfd.Sysfd, srtapi.OptionStreamid)
+	if !c.ok() {
+		return "", srtapi.EINVPARAM
+	}
+	return c.fd.streamID, nil
+}
+
+// StreamIDParsed returns the keys and values of the stream ID, if it
+// follows the access control convention of SRT, as in
+// "#!::r=live/feed,m=publish,u=alice". It returns nil otherwise.
+func (c *conn) StreamIDParsed() map[string]string {
+	if !c.ok() {
+		return nil
+	}
+	return parseStreamID(c.fd.streamID)
+}
+
+// parseStreamID parses a stream ID of the form "#!::k1=v1,k2=v2".
+// Nested forms such as "#!:{...}" are not supported.
+func parseStreamID(s string) map[string]string {
+	const prefix = "#!::"
+	if !strings.HasPrefix(s, prefix) {
+		return nil
+	}
+	kvs := make(map[string]string)
+	for _, kv := range strings.Split(s[len(prefix):], ",") {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 {
+			return nil
+		}
+		kvs[kv[:i]] = kv[i+1:]
+	}
+	return kvs
+}
+
+// PayloadSize returns the maximum size of a message on the
+// connection, or 0 if messages are not limited to one packet, as in
+// file mode. Buffers of this size hold any message read in message
+// mode.
+func (c *conn) PayloadSize() (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	return srtapi.GetsockoptInt(c.fd.pfd.Sysfd, 0, srtapi.OptionPayloadsize)
+}
+
+// MSS returns the maximum segment size of the connection, the
+// smaller of those of both peers once connected.
+func (c *conn) MSS() (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	return srtapi.GetsockoptInt(c.fd.pfd.Sysfd, 0, srtapi.OptionMss)
 }
 
 func (c *conn) Stats() map[string]interface{} {
@@ -150,10 +267,52 @@ var (
 	// For connection setup and write operations.
 	errMissingAddress = errors.New("missing address")
 
+	// For dial operations with a local address.
+	errLocalAddrFamily = errors.New("local address family does not match network")
+
+	// For dial operations that require a literal IP address.
+	errNotLiteralIP = errors.New("not a literal IP address, and the dialer must not resolve host names")
+
+	// For dial operations with an interface name.
+	errBindToDevice = errors.New("binding to a network interface is not supported on this platform")
+	errReusePort    = errors.New("reusing a local port is not supported on this platform")
+
 	// For both read and write operations.
 	errCanceled = errors.New("operation was canceled")
 )
 
+// ErrListenerClosed is returned by Accept and AcceptSRT once the
+// listener has been closed. It wraps net.ErrClosed.
+var ErrListenerClosed = fmt.Errorf("listener closed: %w", net.ErrClosed)
+
+// ErrConnectionBroken is matched, with errors.Is, by the errors of
+// the reads and writes of a connection that broke under them, as the
+// peer closed it or stopped responding: srtapi.ECONNLOST,
+// srtapi.ECONNFAIL and srtapi.ENOCONN, which the errors still wrap.
+// Dialing again may get through.
+var ErrConnectionBroken = errors.New("connection broken")
+
+// ErrConnectionClosed is matched, with errors.Is, by the errors of
+// the reads and writes of a connection closed on this side, which
+// wrap net.ErrClosed or srtapi.EINVSOCK. Unlike ErrConnectionBroken,
+// it calls for no retry. Reads and writes that time out match
+// os.ErrDeadlineExceeded instead.
+var ErrConnectionClosed = errors.New("connection closed")
+
+// ErrNotConnected is returned by the reads and writes of a connection
+// made by DialSRTDeferred before its Handshake succeeds. It wraps
+// syscall.ENOTCONN.
+var ErrNotConnected = fmt.Errorf("handshake not done: %w", syscall.ENOTCONN)
+
+// ErrSendClosed is returned by the writes of a connection once
+// CloseSend was called on it. It wraps syscall.EPIPE.
+var ErrSendClosed = fmt.Errorf("send side closed: %w", syscall.EPIPE)
+
+// ErrWouldBlock is returned by the reads and writes of a connection
+// set not to block by SetBlocking, when they would have to wait. It
+// wraps syscall.EAGAIN.
+var ErrWouldBlock = fmt.Errorf("operation would block: %w", syscall.EAGAIN)
+
 func mapErr(err error) error {
 	switch err {
 	case context.Canceled:
@@ -217,6 +376,9 @@ func (e *OpError) Error() string {
 	return s
 }
 
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error { return e.Err }
+
 var (
 	// aLongTimeAgo is a non-zero time, far in the past, used for
 	// immediate cancelation of dials.
@@ -264,9 +426,26 @@ func genericReadFrom(w io.Writer, r io.Reader) (n int64, err error) {
 	return io.Copy(writerOnly{w}, r)
 }
 
-// SetLoggingHandler set logging handler
+// SetLoggingHandler routes the internal logs of libsrt to handler,
+// or back to their default destination if handler is nil. It is safe
+// for concurrent use. The handler runs on a goroutine of its own, so
+// it may call into this package freely; lines logged while it lags
+// far behind are dropped.
+//
+// If the linked libsrt is older than the package supports, a warning
+// saying so is logged to every handler set.
 func SetLoggingHandler(handler LoggingHandlerFunc) {
 	logging.SetHandler(logging.HandlerFunc(handler))
+	if handler != nil {
+		checkVersion()
+	}
+}
+
+// SetLogLevel sets the most verbose level of the libsrt logs, from
+// srtapi.LogFatal to srtapi.LogDebug. The default is taken from the
+// SRT_LOGLEVEL environment variable.
+func SetLogLevel(level int) {
+	logging.SetLevel(level)
 }
 
 // Shutdown clean up srt library
@@ -8,13 +8,19 @@
 package srt
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/openfresh/gosrt/conf"
+	"github.com/openfresh/gosrt/srtapi"
 )
 
 func TestConnClose(t *testing.T) {
@@ -50,6 +56,180 @@ func TestConnClose(t *testing.T) {
 	}
 }
 
+func TestConnState(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	if st := c.State(); st != StateConnected {
+		t.Fatalf("got %v; want %v", st, StateConnected)
+	}
+
+	s.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.State() == StateConnected && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := c.State(); st != StateBroken && st != StateClosed {
+		t.Errorf("after peer close: got %v; want %v or %v", st, StateBroken, StateClosed)
+	}
+
+	c.Close()
+	if st := c.State(); st != StateNonexist {
+		t.Errorf("after close: got %v; want %v", st, StateNonexist)
+	}
+	if got := SocketState(99).String(); got != "SocketState(99)" {
+		t.Errorf("got %q; want %q", got, "SocketState(99)")
+	}
+}
+
+func TestPing(t *testing.T) {
+	var lc ListenConfig
+	lc.SetPeerIdleTimeout(time.Second)
+	ln, err := lc.Listen(context.Background(), "srt4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			var b [1]byte
+			c.Read(b[:])
+		}
+	}()
+
+	// The peer goes silent once the relay starts dropping everything.
+	var silent int32
+	raddr := testRelay(t, ln.Addr(), func(bool, []byte) bool {
+		return atomic.LoadInt32(&silent) != 0
+	})
+	var d Dialer
+	d.SetPeerIdleTimeout(time.Second)
+	c, err := d.Dial("srt4", raddr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := c.(*SRTConn)
+	if err := sc.Ping(); err != nil {
+		t.Fatalf("Ping() on a live connection = %v", err)
+	}
+
+	atomic.StoreInt32(&silent, 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for sc.Ping() == nil && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := sc.Ping(); !errors.Is(err, ErrConnectionBroken) || !errors.Is(err, srtapi.ECONNLOST) {
+		t.Errorf("Ping() after the peer went silent = %v; want %v", err, ErrConnectionBroken)
+	}
+}
+
+func TestReadyEvents(t *testing.T) {
+	var ev int
+	var getErr error
+	oldGet := getsockoptIntFunc
+	defer func() { getsockoptIntFunc = oldGet }()
+	getsockoptIntFunc = func(fd, level, opt int) (int, error) {
+		if opt == srtapi.OptionEvent {
+			return ev, getErr
+		}
+		return oldGet(fd, level, opt)
+	}
+
+	c := newSRTConn(&netFD{net: "srt"})
+	for _, tt := range []struct {
+		ev                 int
+		readable, writable bool
+	}{
+		{0, false, false},
+		{srtapi.EpollIn, true, false},
+		{srtapi.EpollOut, false, true},
+		{srtapi.EpollIn | srtapi.EpollOut, true, true},
+	} {
+		ev = tt.ev
+		r, w, err := c.Ready()
+		if err != nil || r != tt.readable || w != tt.writable {
+			t.Errorf("events %#x: got %v, %v, %v; want %v, %v", tt.ev, r, w, err, tt.readable, tt.writable)
+		}
+	}
+
+	ev = srtapi.EpollIn | srtapi.EpollOut | srtapi.EpollErr
+	if r, w, err := c.Ready(); !r || !w || !errors.Is(err, ErrConnectionBroken) {
+		t.Errorf("broken: got %v, %v, %v; want true, true, %v", r, w, err, ErrConnectionBroken)
+	}
+	getErr = srtapi.EINVSOCK
+	if _, _, err := c.Ready(); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("closed: got %v; want %v", err, ErrConnectionClosed)
+	}
+}
+
+func TestReady(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("transtype", "1", "messageapi", "false"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+	client, server := c.(*SRTConn), s.(*SRTConn)
+
+	if r, w, err := server.Ready(); err != nil || r || !w {
+		t.Fatalf("idle connection: got %v, %v, %v; want not readable, writable", r, w, err)
+	}
+
+	// Data buffered on the server makes it readable, and Ready
+	// leaves it there for Read.
+	if _, err := client.Write([]byte("ready")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	var r bool
+	for !r && time.Now().Before(deadline) {
+		if r, _, err = server.Ready(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !r {
+		t.Fatal("connection with data buffered not readable")
+	}
+	if r, _, _ := server.Ready(); !r {
+		t.Error("Ready consumed the data")
+	}
+	b := make([]byte, 16)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := server.Read(b); err != nil || string(b[:n]) != "ready" {
+		t.Fatalf("got %q, %v; want %q", b[:n], err, "ready")
+	}
+
+	// The server does not read, so the send buffer of the client
+	// fills up.
+	wb := make([]byte, 64<<20)
+	client.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := client.Write(wb); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Write error = %v; want os.ErrDeadlineExceeded", err)
+	}
+	if _, w, err := client.Ready(); err != nil || w {
+		t.Errorf("full send buffer: got writable %v, %v; want not writable", w, err)
+	}
+}
+
 func TestListenerClose(t *testing.T) {
 	for _, network := range []string{"srt"} {
 		if !testableNetwork(network) {
@@ -87,6 +267,240 @@ func TestListenerClose(t *testing.T) {
 	}
 }
 
+func TestListenerShutdown(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const N = 4
+	errc := make(chan error, N)
+	for i := 0; i < N; i++ {
+		go func() {
+			c, err := ln.Accept()
+			if err == nil {
+				c.Close()
+			}
+			errc <- err
+		}()
+	}
+	time.Sleep(100 * time.Millisecond) // let the Accepts block
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ln.(*SRTListener).Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		select {
+		case err := <-errc:
+			if !errors.Is(err, ErrListenerClosed) || !errors.Is(err, net.ErrClosed) {
+				t.Errorf("Accept after Shutdown = %v; want ErrListenerClosed", err)
+			}
+		default:
+			t.Fatal("Shutdown returned before pending Accepts")
+		}
+	}
+	if _, err := ln.Accept(); !errors.Is(err, ErrListenerClosed) {
+		t.Errorf("Accept on shut down listener = %v; want ErrListenerClosed", err)
+	}
+	if err := ln.(*SRTListener).Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown = %v; want nil", err)
+	}
+}
+
+func TestListenerAcceptContext(t *testing.T) {
+	ln, err := newLocalListener("srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sl := ln.(*SRTListener)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 2)
+	go func() {
+		_, err := sl.AcceptContext(ctx)
+		errc <- err
+	}()
+	// A second call waits for the first one to return; it must give
+	// up all the same.
+	go func() {
+		_, err := sl.AcceptContext(ctx)
+		errc <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("got %v; want %v", err, context.Canceled)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("AcceptContext still blocked after cancel")
+		}
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := sl.AcceptContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	// The listener still accepts.
+	go func() {
+		c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+		if err == nil {
+			defer c.Close()
+			var b [1]byte
+			c.Read(b[:])
+		}
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := sl.AcceptContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestListenerAcceptN(t *testing.T) {
+	lc := ListenConfig{Backlog: 8}
+	ln, err := lc.Listen(context.Background(), "srt4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sl := ln.(*SRTListener)
+
+	if _, err := sl.AcceptN(0); err == nil {
+		t.Error("AcceptN(0) succeeded")
+	}
+
+	// Nobody accepts yet, so all four callers are ready at once.
+	d := Dialer{Timeout: 5 * time.Second}
+	for i := 0; i < 4; i++ {
+		c, err := d.Dial("srt4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		defer c.Close()
+	}
+	for _, want := range []int{3, 1} {
+		conns, err := sl.AcceptN(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range conns {
+			c.Close()
+		}
+		if len(conns) != want {
+			t.Errorf("AcceptN(3) returned %d connections; want %d", len(conns), want)
+		}
+	}
+}
+
+// BenchmarkAcceptBurst accepts bursts of 1000 simultaneous callers,
+// one Accept at a time or with AcceptN, and reports the cgo calls of
+// the process per burst, those of the callers included.
+func BenchmarkAcceptBurst(b *testing.B) {
+	const burst = 1000
+	for _, bb := range []struct {
+		name   string
+		accept func(*SRTListener) (int, error)
+	}{
+		{"Accept", func(ln *SRTListener) (int, error) {
+			c, err := ln.Accept()
+			if err != nil {
+				return 0, err
+			}
+			c.Close()
+			return 1, nil
+		}},
+		{"AcceptN", func(ln *SRTListener) (int, error) {
+			conns, err := ln.AcceptN(burst)
+			for _, c := range conns {
+				c.Close()
+			}
+			return len(conns), err
+		}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			lc := ListenConfig{Backlog: burst}
+			ln, err := lc.Listen(context.Background(), "srt4", "127.0.0.1:0")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer ln.Close()
+			sl := ln.(*SRTListener)
+
+			var calls int64
+			for i := 0; i < b.N; i++ {
+				start := make(chan struct{})
+				done := make(chan struct{})
+				for j := 0; j < burst; j++ {
+					go func() {
+						<-start
+						d := Dialer{Timeout: 10 * time.Second}
+						if c, err := d.Dial("srt4", ln.Addr().String()); err == nil {
+							<-done
+							c.Close()
+						}
+					}()
+				}
+				n := runtime.NumCgoCall()
+				close(start)
+				for accepted := 0; accepted < burst; {
+					k, err := bb.accept(sl)
+					if err != nil {
+						b.Fatal(err)
+					}
+					accepted += k
+				}
+				calls += runtime.NumCgoCall() - n
+				close(done)
+			}
+			b.ReportMetric(float64(calls)/float64(b.N), "cgocalls/op")
+		})
+	}
+}
+
+func TestListenerCloseConcurrentAccept(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		ln, err := newLocalListener("srt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		const N = 50
+		errc := make(chan error, N)
+		for j := 0; j < N; j++ {
+			go func() {
+				c, err := ln.Accept()
+				if err == nil {
+					c.Close()
+				}
+				errc <- err
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := ln.Close(); err != nil {
+			t.Fatal(err)
+		}
+		timeout := time.After(5 * time.Second)
+		for j := 0; j < N; j++ {
+			select {
+			case err := <-errc:
+				if !errors.Is(err, net.ErrClosed) {
+					t.Errorf("Accept after Close = %v; want net.ErrClosed", err)
+				}
+			case <-timeout:
+				t.Fatalf("%d Accepts still blocked after Close", N-j)
+			}
+		}
+	}
+}
+
 // nacl was previous failing to reuse an address.
 func TestListenCloseListen(t *testing.T) {
 	const maxTries = 10
@@ -247,3 +661,58 @@ func TestReadTimeoutUnblocksRead(t *testing.T) {
 	}
 	withSRTConnPair(t, client, server)
 }
+
+func TestSetLoggingHandler(t *testing.T) {
+	lines := make(chan string, 1)
+	SetLoggingHandler(func(level int, file string, line int, area string, message string) {
+		// Calling back into the library from the handler must not
+		// deadlock.
+		SetLogLevel(srtapi.LogDebug)
+		select {
+		case lines <- message:
+		default:
+		}
+	})
+	defer SetLoggingHandler(nil)
+	SetLogLevel(srtapi.LogDebug)
+	defer SetLogLevel(conf.SystemConf().LogLevel())
+
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+	c, err := Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	select {
+	case <-lines:
+	case <-time.After(5 * time.Second):
+		t.Skip("libsrt logged nothing; it may be built without logging")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	major, minor, patch, str := Version()
+	if want := fmt.Sprintf("%d.%d.%d", major, minor, patch); str != want {
+		t.Errorf("Version string %q; want %q", str, want)
+	}
+	if !VersionAtLeast(major, minor, patch) {
+		t.Errorf("VersionAtLeast(%d, %d, %d) = false for libsrt %s", major, minor, patch, str)
+	}
+	if VersionAtLeast(major, minor, patch+1) || VersionAtLeast(major, minor+1, 0) || VersionAtLeast(major+1, 0, 0) {
+		t.Errorf("VersionAtLeast reports a later version than libsrt %s", str)
+	}
+	if got := versionString(0x010402); got != "1.4.2" {
+		t.Errorf("versionString(0x010402) = %q; want \"1.4.2\"", got)
+	}
+}
@@ -10,10 +10,15 @@ package srt
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/openfresh/gosrt/internal/poll"
 	"github.com/openfresh/gosrt/srtapi"
 )
 
@@ -56,6 +61,21 @@ func (a *SRTAddr) matchAddrFamily(x net.IP) bool {
 	return a.IP.To4() != nil && x.To4() != nil || a.IP.To16() != nil && a.IP.To4() == nil && x.To16() != nil && x.To4() == nil
 }
 
+// matchNetwork reports whether the family of a fits network, a
+// wildcard address fitting any SRT network.
+func (a *SRTAddr) matchNetwork(network string) bool {
+	if a.isWildcard() {
+		return true
+	}
+	switch network {
+	case "srt4":
+		return a.IP.To4() != nil
+	case "srt6":
+		return a.IP.To4() == nil
+	}
+	return true
+}
+
 // ResolveSRTAddr returns an address of SRT end point.
 //
 // The network must be a SRT network name.
@@ -86,13 +106,49 @@ func ResolveSRTAddr(network, address string) (*SRTAddr, error) {
 	return addrs.forResolve(network, address).(*SRTAddr), nil
 }
 
+// Conn is the part of an SRT connection that applications commonly
+// build on: a net.Conn, plus the stream ID, the statistics and the
+// latencies the handshake settled on. *SRTConn implements it, and so
+// does the in-memory fake of package srttest, so that code written
+// against Conn can be tested without a network. The latency is fixed
+// by the handshake, so there is no method to set it.
+type Conn interface {
+	net.Conn
+
+	StreamID() (string, error)
+	SocketID() uint32
+	SRTStats(clear bool) (*SRTStats, error)
+	NegotiatedRcvLatency() (time.Duration, error)
+	NegotiatedSndLatency() (time.Duration, error)
+}
+
+var _ Conn = (*SRTConn)(nil)
+
 // SRTConn is an implementation of the Conn interface for SRT network
 // connections.
 type SRTConn struct {
 	conn
+
+	routeKey string // set by the OnAccept function of the listener
+
+	lossMu sync.Mutex
+	loss   *lossSample // counters at the last LossRate call
+
+	hsMu  sync.Mutex
+	hs    *pendingHandshake // set by DialSRTDeferred until Handshake
+	hsErr error             // result of the first Handshake
 }
 
-// ReadFrom implements the io.ReaderFrom ReadFrom method.
+// pendingHandshake is what Handshake needs to connect a caller made by
+// DialSRTDeferred.
+type pendingHandshake struct {
+	options context.Context // carries the options of the dial
+	raddr   *SRTAddr
+}
+
+// ReadFrom implements the io.ReaderFrom ReadFrom method. It writes
+// what it reads from r chunk by chunk, each like a Write, so that the
+// writes of other goroutines may come between two chunks.
 func (c *SRTConn) ReadFrom(r io.Reader) (int64, error) {
 	if !c.ok() {
 		return 0, srtapi.EINVPARAM
@@ -104,12 +160,373 @@ func (c *SRTConn) ReadFrom(r io.Reader) (int64, error) {
 	return n, err
 }
 
+// ReadContext reads like Read, but gives up once ctx is done, returning
+// an error that wraps ctx.Err(). Nothing is lost then, and the
+// connection stays usable: what arrives later is returned by the next
+// read. The read deadline still applies. This lets reads be canceled
+// along with the work they serve, rather than through
+// SetReadDeadline.
+func (c *SRTConn) ReadContext(ctx context.Context, b []byte) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	n, err := c.fd.readContext(ctx, b)
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// SetBlocking sets whether Read and Write, and the other reads and
+// writes of the connection, wait when they cannot proceed at once, as
+// they do by default. A read that does not wait fails with
+// ErrWouldBlock when nothing is available; a write, when the send
+// buffer is full, having written what fit. Deadlines do not apply to
+// them. This is the non-blocking mode of SRTO_RCVSYN and SRTO_SNDSYN,
+// for a caller that multiplexes connections itself, as with an event
+// loop. libsrt's own flags are kept off whatever is set here: the
+// package never lets libsrt block a thread, and waits in its poller
+// when blocking.
+func (c *SRTConn) SetBlocking(recv, send bool) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	c.fd.pfd.SetBlocking(recv, send)
+	return nil
+}
+
+// ReadAvailable reads into b what the receive buffer holds, up to
+// len(b), like Read, but returns at once rather than wait when there is
+// nothing to read: n is then 0 and err nil. The read deadline does not
+// apply. It suits consumers that poll the connection on a schedule of
+// their own, such as once per output frame.
+func (c *SRTConn) ReadAvailable(b []byte) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	n, err := c.fd.readNoWait(b)
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// ReadBuffers reads into the buffers of bufs in turn and returns the
+// number of buffers filled, truncating each of them to the length of
+// the data read into it. It blocks until the first buffer can be
+// filled, then fills more only while data is available without
+// waiting, so a burst of messages costs a single wakeup.
+//
+// In message mode each buffer receives exactly one message; sizing
+// the buffers by PayloadSize makes every message fit. A message larger
+// than its buffer makes the read fail. In stream mode
+// each buffer receives whatever is available, up to its length.
+//
+// The data is copied into the buffers by libsrt during the call and
+// no pointer to them is retained afterwards, so they may be reused or
+// released as soon as ReadBuffers returns. They need no particular
+// alignment, but none of them may be empty.
+func (c *SRTConn) ReadBuffers(bufs net.Buffers) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	n, err := c.fd.readBuffers(bufs)
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// WriteBuffers writes the contents of bufs, concatenated, and returns
+// the number of bytes written. The buffers are coalesced into as few
+// srt_send calls as possible: in live mode each call sends one message
+// of PayloadSize bytes, the last one excepted, so the message
+// boundaries of the buffers are not kept; in file mode calls take up
+// to 1MB.
+//
+// The buffers may be reused as soon as WriteBuffers returns.
+func (c *SRTConn) WriteBuffers(bufs net.Buffers) (int64, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	n, err := c.fd.writeBuffers(&bufs)
+	if err != nil {
+		err = &OpError{Op: "write", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// WriteMessagev sends the contents of bufs, concatenated, as a single
+// message, as writev does for a datagram. libsrt takes no iovec, so
+// more than one non-empty buffer is gathered into a pooled buffer,
+// and sent with a single srt_sendmsg call. A message larger than the
+// connection takes fails with a MessageSizeError, as with Write. It
+// fails for a connection that uses the buffer API, which keeps no
+// message boundaries.
+func (c *SRTConn) WriteMessagev(bufs ...[]byte) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	if c.fd.streamAPI {
+		return 0, &OpError{Op: "write", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: errStreamAPI}
+	}
+	var msg []byte
+	size, parts := 0, 0
+	for _, b := range bufs {
+		if len(b) > 0 {
+			size += len(b)
+			parts++
+			msg = b
+		}
+	}
+	if parts > 1 {
+		p := getMessageBuf(size)
+		defer putMessageBuf(p)
+		msg = (*p)[:0]
+		for _, b := range bufs {
+			msg = append(msg, b...)
+		}
+	}
+	n, err := c.fd.Write(msg)
+	if err != nil {
+		err = &OpError{Op: "write", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// maxPooledMessage is the size of the largest buffer WriteMessagev
+// keeps for reuse; the messages of file mode can be much larger than
+// those of live mode.
+const maxPooledMessage = 64 << 10
+
+var messageBufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, maxMSS)
+		return &b
+	},
+}
+
+func getMessageBuf(size int) *[]byte {
+	if size > maxPooledMessage {
+		b := make([]byte, 0, size)
+		return &b
+	}
+	p := messageBufPool.Get().(*[]byte)
+	if cap(*p) < size {
+		*p = make([]byte, 0, size)
+	}
+	return p
+}
+
+func putMessageBuf(p *[]byte) {
+	if cap(*p) <= maxPooledMessage {
+		messageBufPool.Put(p)
+	}
+}
+
+// MaxPayloadSize returns the payload size in effect on the
+// connection, as PayloadSize does, or 0 if it cannot be read. In live
+// mode every message is one packet, so it is the largest message
+// Write takes; Write fails with a MessageSizeError for larger ones
+// instead of truncating them. Buffers of this size hold any message
+// read in message mode.
+func (c *SRTConn) MaxPayloadSize() int {
+	n, err := c.PayloadSize()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// MessageCtrl holds the control data of a message read by
+// ReadMessageCtrl.
+type MessageCtrl struct {
+	// SrcTime is the time the sender stamped the message with, in
+	// microseconds on the libsrt clock, translated to the local
+	// clock when TSBPD is on. That clock is the wall clock, counted
+	// from the Unix epoch, unless libsrt was built with
+	// ENABLE_MONOTONIC_CLOCK. The difference between the time of
+	// the read and SrcTime is then the end-to-end delay, which TSBPD
+	// holds close to the latency.
+	SrcTime int64
+
+	// PktSeq is the sequence number of the first packet of the
+	// message.
+	PktSeq int32
+
+	// MsgNo is the message number.
+	MsgNo int32
+
+	// RecvTime is the local time libsrt delivered the message at,
+	// taken as soon as the read returns, with a monotonic clock
+	// reading. With TSBPD, libsrt holds each message until its
+	// SrcTime plus the latency, so the variation of RecvTime minus
+	// SrcTime from one message to the next is the jitter TSBPD left,
+	// and without TSBPD, the jitter of the network.
+	RecvTime time.Time
+}
+
+// ReadMessageCtrl reads one message into b like Read, and returns its
+// control data along with it. It is meant for message mode, and fails
+// for a connection that uses the buffer API.
+func (c *SRTConn) ReadMessageCtrl(b []byte) (int, MessageCtrl, error) {
+	if !c.ok() {
+		return 0, MessageCtrl{}, srtapi.EINVPARAM
+	}
+	if c.fd.streamAPI {
+		return 0, MessageCtrl{}, &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: errStreamAPI}
+	}
+	var mc srtapi.MsgCtrl
+	n, err := c.fd.readMsg(b, &mc)
+	recvTime := time.Now()
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, MessageCtrl{SrcTime: mc.SrcTime, PktSeq: mc.PktSeq, MsgNo: mc.MsgNo, RecvTime: recvTime}, err
+}
+
+// SetPacing turns the pacing of the sender on or off.
+//
+// With pacing, SRT sends at the input rate plus overhead, spreading
+// packets out instead of bursting them: it sets SRTO_MAXBW to 0,
+// meaning relative to the input rate, and SRTO_INPUTBW to 0, meaning
+// that rate is measured. The overhead is SRTO_OHEADBW, 25% unless set
+// otherwise. This is what live streams want.
+//
+// Without pacing, SRT sends as fast as the flow control and
+// congestion windows allow: it sets SRTO_MAXBW to -1, meaning no
+// limit, and leaves SRTO_INPUTBW alone. This is what file transfers
+// want.
+func (c *SRTConn) SetPacing(enabled bool) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if err := setPacing(c.fd, enabled); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+// SetSndDropDelay sets the extra time, beyond the latency, that the
+// sender keeps a packet it has not got through before dropping it,
+// when too-late packet drop is on. A longer delay gives retransmissions
+// more time during brief congestion, at the cost of delivering late
+// packets the receiver may drop anyway. It takes effect at once, and
+// may be changed while the connection is in use.
+//
+// A negative d sets libsrt's special value -1, which turns the drop
+// on the sender off, leaving late packets to the receiver. d is
+// rounded down to whole milliseconds.
+func (c *SRTConn) SetSndDropDelay(d time.Duration) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	ms := -1
+	if d >= 0 {
+		ms = int(d / time.Millisecond)
+	}
+	if err := srtapi.SetsockoptInt(c.fd.pfd.Sysfd, 0, srtapi.OptionSnddropdelay, ms); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("setsockopt", err)}
+	}
+	return nil
+}
+
+// SndDropDelay returns the sender drop delay set by SetSndDropDelay,
+// or -1ms if the drop on the sender is off.
+func (c *SRTConn) SndDropDelay() (time.Duration, error) {
+	return c.msOption(srtapi.OptionSnddropdelay)
+}
+
+// NegotiatedRcvLatency returns the latency this side receives with, as
+// the handshake settled it: the larger of the receive latency asked
+// for here, by the rcvlatency or latency option, and the peer latency
+// asked for by the peer. A jitter buffer downstream of Read should
+// allow for this value rather than the one set.
+func (c *SRTConn) NegotiatedRcvLatency() (time.Duration, error) {
+	return c.msOption(srtapi.OptionRcvlatency)
+}
+
+// NegotiatedSndLatency returns the latency the peer receives what this
+// side sends with, as the handshake settled it: the larger of the peer
+// latency asked for here, by the peerlatency or latency option, and
+// the receive latency asked for by the peer.
+func (c *SRTConn) NegotiatedSndLatency() (time.Duration, error) {
+	return c.msOption(srtapi.OptionPeerlatency)
+}
+
+// msOption returns the value of the option opt, in milliseconds.
+func (c *SRTConn) msOption(opt int) (time.Duration, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	ms, err := srtapi.GetsockoptInt(c.fd.pfd.Sysfd, 0, opt)
+	if err != nil {
+		return 0, &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockopt", err)}
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func setPacing(fd *netFD, enabled bool) error {
+	if !enabled {
+		return wrapSyscallError("setsockopt", srtapi.SetsockoptInt64(fd.pfd.Sysfd, 0, srtapi.OptionMaxbw, -1))
+	}
+	if err := srtapi.SetsockoptInt64(fd.pfd.Sysfd, 0, srtapi.OptionInputbw, 0); err != nil {
+		return wrapSyscallError("setsockopt", err)
+	}
+	return wrapSyscallError("setsockopt", srtapi.SetsockoptInt64(fd.pfd.Sysfd, 0, srtapi.OptionMaxbw, 0))
+}
+
 func newSRTConn(fd *netFD) *SRTConn {
-	c := &SRTConn{conn{fd}}
+	c := &SRTConn{conn: conn{fd}}
 	return c
 }
 
-// DialSRT acts like Dial for SRT networks.
+// SocketID returns the libsrt socket ID of the connection, the one the
+// libsrt log messages name it by, as "@" followed by the number. It is
+// set when the socket is created, so a connection of DialSRTDeferred
+// has it before the handshake, and it does not change, even once the
+// connection is closed. libsrt hands IDs out in sequence, so that one
+// is not reused for a long time, which lets it serve as a key for the
+// connection.
+func (c *SRTConn) SocketID() uint32 {
+	if !c.ok() {
+		return 0
+	}
+	return c.fd.socketID
+}
+
+// RouteKey returns the key the OnAccept function of the ListenConfig
+// returned for the connection, or "" if the connection was not
+// accepted by a listener with one.
+func (c *SRTConn) RouteKey() string {
+	return c.routeKey
+}
+
+// ISN returns the initial sequence number of the connection, the
+// sequence number of the first data packet the local side sends, to
+// match packet captures against. libsrt reads it from SRTO_ISN since
+// 1.4.2; with an older libsrt, ISN fails with an error that wraps
+// syscall.ENOTSUP.
+func (c *SRTConn) ISN() (int32, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	if !VersionAtLeast(1, 4, 2) {
+		_, _, _, v := Version()
+		return 0, c.infoError(fmt.Errorf("initial sequence number needs libsrt 1.4.2 or later, linked libsrt is %s: %w", v, syscall.ENOTSUP))
+	}
+	isn, err := getsockoptIntFunc(c.fd.pfd.Sysfd, 0, srtapi.OptionIsn)
+	if err != nil {
+		if errors.Is(err, srtapi.EINVOP) {
+			err = fmt.Errorf("initial sequence number not readable: %w", syscall.ENOTSUP)
+		}
+		return 0, c.infoError(err)
+	}
+	return int32(isn), nil
+}
+
+// DialSRT acts like Dial for SRT networks, returning the concrete
+// *SRTConn.
 //
 // The network must be a SRT network name; see func Dial for details.
 //
@@ -117,6 +534,17 @@ func newSRTConn(fd *netFD) *SRTConn {
 // If the IP field of raddr is nil or an unspecified IP address, the
 // local system is assumed.
 func DialSRT(network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+	return DialSRTContext(context.Background(), network, laddr, raddr)
+}
+
+// DialSRTContext acts like DialSRT using the provided context, whose
+// socket options, as set by WithOptions, apply to the connection as
+// they do for Dialer.DialContext. The context also bounds the time
+// the connection takes to establish.
+func DialSRTContext(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
 	switch network {
 	case "srt", "srt4", "srt6":
 	default:
@@ -125,20 +553,87 @@ func DialSRT(network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
 	if raddr == nil {
 		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
 	}
+	if laddr != nil && !laddr.matchNetwork(network) {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr, Addr: raddr, Err: &net.AddrError{Err: errLocalAddrFamily.Error(), Addr: laddr.String()}}
+	}
+
+	c, err := dialSRT(ctx, network, laddr, raddr)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
+	}
+	return c, nil
+}
 
-	c, err := dialSRT(context.Background(), network, laddr, raddr)
+// DialSRTDeferred acts like DialSRTContext, but returns before the
+// connection is established: the socket is created, configured with
+// the options of ctx and bound to laddr, and Handshake connects it to
+// raddr. In between, options can still be set on the connection, as
+// with SetPacing, and Read and Write fail with ErrNotConnected. This
+// separates the handshake, to be timed or tested on its own, the way
+// crypto/tls does with Conn.Handshake.
+//
+// ctx is only used to set the connection up; the context passed to
+// Handshake bounds the handshake.
+func DialSRTDeferred(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	switch network {
+	case "srt", "srt4", "srt6":
+	default:
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: net.UnknownNetworkError(network)}
+	}
+	if raddr == nil {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
+	}
+	if laddr != nil && !laddr.matchNetwork(network) {
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr, Addr: raddr, Err: &net.AddrError{Err: errLocalAddrFamily.Error(), Addr: laddr.String()}}
+	}
 
+	c, err := dialSRTDeferred(ctx, network, laddr, raddr)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: network, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
 	}
 	return c, nil
 }
 
+// Handshake runs the SRT handshake of a connection made by
+// DialSRTDeferred, and returns once it is established, rejected, or
+// ctx is done. Only the first call does the handshake; later calls
+// return its result. It does nothing for other connections, whose
+// handshake is done by the time Dial returns.
+//
+// A failed handshake leaves the connection unusable; it must still
+// be closed.
+func (c *SRTConn) Handshake(ctx context.Context) error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	if ctx == nil {
+		panic("nil context")
+	}
+	c.hsMu.Lock()
+	defer c.hsMu.Unlock()
+	hs := c.hs
+	if hs == nil {
+		return c.hsErr
+	}
+	c.hs = nil
+	if err := c.fd.handshake(withOptionsOf(ctx, hs.options), hs.raddr); err != nil {
+		c.hsErr = &OpError{Op: "dial", Net: c.fd.net, Source: c.fd.laddr, Addr: hs.raddr, Err: err}
+	}
+	return c.hsErr
+}
+
 // SRTListener is a SRT network listener. Clients should typically
 // use variables of type Listener instead of assuming SRT.
 type SRTListener struct {
 	fd  *netFD
 	ctx context.Context
+
+	mu      sync.Mutex // guards closed and additions to accepts
+	closed  bool
+	accepts sync.WaitGroup // Accept calls in progress
 }
 
 // AcceptSRT accepts the next incoming call and returns the new
@@ -147,7 +642,7 @@ func (l *SRTListener) AcceptSRT() (*SRTConn, error) {
 	if !l.ok() {
 		return nil, srtapi.EINVPARAM
 	}
-	c, err := l.accept()
+	c, err := l.accept(context.Background())
 	if err != nil {
 		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
 	}
@@ -160,13 +655,46 @@ func (l *SRTListener) Accept() (net.Conn, error) {
 	if !l.ok() {
 		return nil, srtapi.EINVPARAM
 	}
-	c, err := l.accept()
+	c, err := l.accept(context.Background())
 	if err != nil {
 		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
 	}
 	return c, nil
 }
 
+// AcceptContext waits for and returns the next connection like
+// Accept, but gives up once ctx is done, returning an error that
+// wraps ctx.Err(). The listener is left open, and other Accept calls
+// are not affected. This allows servers to put deadlines on single
+// Accept calls, or stop accepting, without closing the listener.
+func (l *SRTListener) AcceptContext(ctx context.Context) (net.Conn, error) {
+	if !l.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	c, err := l.accept(ctx)
+	if err != nil {
+		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return c, nil
+}
+
+// AcceptN waits for the next incoming call like Accept, then accepts
+// the others that are ready at the same time, returning up to max
+// connections for a single wait of the poller. Under a burst of
+// callers, as when many reconnect at once, this saves a wakeup per
+// connection. An error met after the first connection is returned by
+// the next call. It is best paired with a Backlog that fits the burst.
+func (l *SRTListener) AcceptN(max int) ([]net.Conn, error) {
+	if !l.ok() || max < 1 {
+		return nil, srtapi.EINVPARAM
+	}
+	conns, err := l.acceptN(max)
+	if err != nil {
+		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return conns, nil
+}
+
 // Close stops listening on the SRT address.
 // Already Accepted connections are not closed.
 func (l *SRTListener) Close() error {
@@ -179,11 +707,50 @@ func (l *SRTListener) Close() error {
 	return nil
 }
 
+// Shutdown closes the listener, if it has not been closed already,
+// and then waits for every Accept call in progress to return, or for
+// ctx to be done, whichever happens first. It returns ctx.Err() if
+// ctx is done before the pending Accepts have drained.
+//
+// Closing the listener makes libsrt answer handshakes that are still
+// in progress with SRT_REJ_CLOSE, and connections that completed
+// their handshake but were never accepted are closed rather than
+// leaked. Accept calls interrupted by Close or Shutdown report
+// ErrListenerClosed.
+func (l *SRTListener) Shutdown(ctx context.Context) error {
+	if !l.ok() {
+		return srtapi.EINVPARAM
+	}
+	if err := l.close(); err != nil && err != poll.ErrNetClosing {
+		return &OpError{Op: "close", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	done := make(chan struct{})
+	go func() {
+		l.accepts.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Addr returns the listener's network address, a *SRTAddr.
 // The Addr returned is shared by all invocations of Addr, so
 // do not modify it.
 func (l *SRTListener) Addr() net.Addr { return l.fd.laddr }
 
+// SocketID returns the libsrt socket ID of the listener, as
+// SRTConn.SocketID does for a connection.
+func (l *SRTListener) SocketID() uint32 {
+	if !l.ok() {
+		return 0
+	}
+	return l.fd.socketID
+}
+
 // SetDeadline sets the deadline associated with the listener.
 // A zero time value disables the deadline.
 func (l *SRTListener) SetDeadline(t time.Time) error {
@@ -202,9 +769,9 @@ func (l *SRTListener) SetDeadline(t time.Time) error {
 //
 // If the IP field of laddr is nil or an unspecified IP address,
 // ListenSRT listens on all available unicast and anycast IP addresses
-// of the local system.
+// of the local system. Otherwise it listens on that address only.
 // If the Port field of laddr is 0, a port number is automatically
-// chosen.
+// chosen, and reported by the Addr method of the listener.
 func ListenSRT(network string, laddr *SRTAddr) (*SRTListener, error) {
 	switch network {
 	case "srt", "srt4", "srt6":
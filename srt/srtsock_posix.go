@@ -13,6 +13,8 @@ import (
 	"io"
 	"net"
 	"syscall"
+
+	"github.com/openfresh/gosrt/internal/poll"
 )
 
 func sockaddrToSRT(sa syscall.Sockaddr) net.Addr {
@@ -42,11 +44,40 @@ func (a *SRTAddr) sockaddr(family int) (syscall.Sockaddr, error) {
 	return ipToSockaddr(family, a.IP, a.Port, a.Zone)
 }
 
+// routeLocalAddr returns the address on the port of the wildcard
+// address laddr that packets to raddr leave from, as chosen by the
+// routing table. libsrt reports the bound address as the local
+// address of connections accepted by a wildcard listener, and owns
+// the UDP socket, so the destination address of the handshake, as
+// IP_PKTINFO would give it, is out of reach. With symmetric routing,
+// the common case, the route back to the peer leaves from the
+// address its packets arrived on. It does not hold for a virtual or
+// anycast address that the peer reached, or under policy routing,
+// where the route leaves from another address. Connecting a UDP
+// socket sends nothing, but it takes a socket, so the lookup is left
+// to the first LocalAddr call. laddr is returned as is if the lookup
+// fails.
+func routeLocalAddr(laddr, raddr *SRTAddr) *SRTAddr {
+	if raddr == nil {
+		return laddr
+	}
+	c, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: raddr.IP, Port: raddr.Port, Zone: raddr.Zone})
+	if err != nil {
+		return laddr
+	}
+	defer c.Close()
+	a := c.LocalAddr().(*net.UDPAddr)
+	return &SRTAddr{IP: a.IP, Port: laddr.Port, Zone: a.Zone}
+}
+
 func (a *SRTAddr) toLocal(net string) sockaddr {
 	return &SRTAddr{loopbackIP(net), a.Port, a.Zone}
 }
 
 func (c *SRTConn) readFrom(r io.Reader) (int64, error) {
+	if src, ok := r.(*SRTConn); ok && src.ok() {
+		return relay(c.fd, src.fd)
+	}
 	if n, err, handled := sendFile(c.fd, r); handled {
 		return n, err
 	}
@@ -68,25 +99,130 @@ func doDialSRT(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRT
 	return newSRTConn(fd), nil
 }
 
+// deferHandshakeContextKey is the type of contextKeys used to make
+// dial leave a caller unconnected, for DialSRTDeferred.
+type deferHandshakeContextKey struct{}
+
+func deferHandshakeValue(ctx context.Context) bool {
+	deferred, _ := ctx.Value(deferHandshakeContextKey{}).(bool)
+	return deferred
+}
+
+func dialSRTDeferred(ctx context.Context, network string, laddr, raddr *SRTAddr) (*SRTConn, error) {
+	ctx = context.WithValue(ctx, deferHandshakeContextKey{}, true)
+	fd, err := internetSocket(ctx, network, laddr, raddr, syscall.SOCK_DGRAM, 0, "dial")
+	if err != nil {
+		return nil, err
+	}
+	c := newSRTConn(fd)
+	c.hs = &pendingHandshake{options: detachOptions(ctx), raddr: raddr}
+	return c, nil
+}
+
 func (ln *SRTListener) ok() bool { return ln != nil && ln.fd != nil }
 
-func (ln *SRTListener) accept() (*SRTConn, error) {
-	fd, err := ln.fd.accept()
+func (ln *SRTListener) accept(ctx context.Context) (*SRTConn, error) {
+	ln.mu.Lock()
+	if ln.closed {
+		ln.mu.Unlock()
+		return nil, ErrListenerClosed
+	}
+	ln.accepts.Add(1)
+	ln.mu.Unlock()
+	defer ln.accepts.Done()
+
+	fd, err := ln.fd.accept(ctx)
+	if ln.isClosed() {
+		// The listener was closed while we were waiting. Nobody is
+		// going to serve a connection handed over in the meantime,
+		// so drop it instead of leaking it.
+		if fd != nil {
+			fd.Close()
+		}
+		return nil, ErrListenerClosed
+	}
 	if err != nil {
 		return nil, err
 	}
+	return ln.newConn(fd), nil
+}
+
+// acceptN waits for a connection like accept, then accepts those that
+// are ready too, up to max in all, without waiting again. An error
+// after the first is left for the next call to meet.
+func (ln *SRTListener) acceptN(max int) ([]net.Conn, error) {
+	c, err := ln.accept(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	conns := []net.Conn{c}
+	ln.mu.Lock()
+	if ln.closed {
+		ln.mu.Unlock()
+		return conns, nil
+	}
+	ln.accepts.Add(1)
+	ln.mu.Unlock()
+	defer ln.accepts.Done()
+
+	for len(conns) < max {
+		fd, err := ln.fd.acceptNoWait()
+		if fd == nil || err != nil {
+			break
+		}
+		if ln.isClosed() {
+			fd.Close()
+			break
+		}
+		conns = append(conns, ln.newConn(fd))
+	}
+	return conns, nil
+}
+
+// newConn returns the connection of fd, accepted on ln.
+func (ln *SRTListener) newConn(fd *netFD) *SRTConn {
 	configure(ln.ctx, fd.pfd.Sysfd, bindPost)
-	return newSRTConn(fd), nil
+	c := newSRTConn(fd)
+	if l := recvDropLimitValue(ln.ctx); l != (DropLimit{}) {
+		fd.setDropLimit(l)
+	}
+	if d := idleTimeoutValue(ln.ctx); d > 0 {
+		fd.setIdleTimeout(d)
+	}
+	if onAccept := onAcceptValue(ln.ctx); onAccept != nil {
+		raddr, _ := fd.raddr.(*SRTAddr)
+		c.routeKey = onAccept(raddr, fd.streamID)
+	}
+	return c
+}
+
+func (ln *SRTListener) isClosed() bool {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	return ln.closed
 }
 
 func (ln *SRTListener) close() error {
+	ln.mu.Lock()
+	if ln.closed {
+		ln.mu.Unlock()
+		return poll.ErrNetClosing
+	}
+	ln.closed = true
+	ln.mu.Unlock()
 	return ln.fd.Close()
 }
 
 func listenSRT(ctx context.Context, network string, laddr *SRTAddr) (*SRTListener, error) {
+	// The network picks the family of the socket, so an address of
+	// the other family would otherwise be bound as it maps into
+	// that family, if at all.
+	if !laddr.matchNetwork(network) {
+		return nil, &net.AddrError{Err: errLocalAddrFamily.Error(), Addr: laddr.String()}
+	}
 	fd, err := internetSocket(ctx, network, laddr, nil, syscall.SOCK_DGRAM, 0, "listen")
 	if err != nil {
 		return nil, err
 	}
-	return &SRTListener{fd, ctx}, nil
+	return &SRTListener{fd: fd, ctx: ctx}, nil
 }
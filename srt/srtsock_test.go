@@ -9,15 +9,22 @@
 package srt
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"net"
 	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/openfresh/gosrt/internal/testenv"
+	"github.com/openfresh/gosrt/srtapi"
 )
 
 func BenchmarkSRT4OneShot(b *testing.B) {
@@ -363,6 +370,39 @@ func TestSRTListenerName(t *testing.T) {
 	}
 }
 
+func TestSRTListenerSpecificIP(t *testing.T) {
+	testenv.MustHaveExternalNetwork(t)
+
+	ip := nonLoopbackIPv4Addr()
+	if ip == nil {
+		t.Skip("no non-loopback IPv4 interface address")
+	}
+	ln, err := ListenSRT("srt4", &SRTAddr{IP: ip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	la := ln.Addr().(*SRTAddr)
+	if !la.IP.Equal(ip) || la.Port == 0 {
+		t.Fatalf("got %v; want %v with a non-zero port", la, ip)
+	}
+
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+	c, err := Dial("srt4", la.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if ra := c.RemoteAddr().(*SRTAddr); !ra.IP.Equal(ip) || ra.Port != la.Port {
+		t.Errorf("got remote address %v; want %v", ra, la)
+	}
+}
+
 func TestIPv6LinkLocalUnicastSRT(t *testing.T) {
 	testenv.MustHaveExternalNetwork(t)
 
@@ -420,6 +460,368 @@ func TestIPv6LinkLocalUnicastSRT(t *testing.T) {
 	}
 }
 
+func TestSRTReadWriteAllocs(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts OptionSet
+	}{
+		{"stream", Options("transtype", "1", "messageapi", "false")},
+		{"message", Options("payloadsize", "128")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithOptions(context.Background(), tt.opts)
+			ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			var server net.Conn
+			errc := make(chan error, 1)
+			go func() {
+				var err error
+				server, err = ln.Accept()
+				errc <- err
+			}()
+			var d Dialer
+			client, err := d.DialContext(ctx, "srt", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+			defer server.Close()
+
+			var buf [128]byte
+			allocs := testing.AllocsPerRun(1000, func() {
+				_, err := server.Write(buf[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = io.ReadFull(client, buf[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs > 0 {
+				t.Fatalf("got %v; want 0", allocs)
+			}
+		})
+	}
+}
+
+func TestSRTConnReadBuffers(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	errc := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		for i := 0; i < 3; i++ {
+			if _, err := c.Write(bytes.Repeat([]byte{byte(i)}, 100+i)); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- nil
+		// Keep the connection open until the client is done.
+		var b [1]byte
+		c.Read(b[:])
+	}()
+	c, err := Dial("srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	sc := c.(*SRTConn)
+	size, err := sc.PayloadSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size <= 0 {
+		t.Fatalf("got payload size %d; want > 0", size)
+	}
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msgs [][]byte
+	for len(msgs) < 3 {
+		bufs := make(net.Buffers, 4)
+		for i := range bufs {
+			bufs[i] = make([]byte, size)
+		}
+		n, err := sc.ReadBuffers(bufs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, bufs[:n]...)
+	}
+	for i, msg := range msgs {
+		if want := bytes.Repeat([]byte{byte(i)}, 100+i); !bytes.Equal(msg, want) {
+			t.Errorf("message %d: got %d bytes of %v; want %d bytes of %d", i, len(msg), msg[0], len(want), i)
+		}
+	}
+}
+
+func TestAcceptStreamID(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	const streamID = "#!::r=live/feed,m=publish,u=alice"
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	ctx := WithOptions(context.Background(), Options("streamid", streamID))
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+
+	sc := s.(*SRTConn)
+	if sid, err := sc.StreamID(); err != nil || sid != streamID {
+		t.Errorf("StreamID() = %q, %v; want %q", sid, err, streamID)
+	}
+	want := map[string]string{"r": "live/feed", "m": "publish", "u": "alice"}
+	if got := sc.StreamIDParsed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamIDParsed() = %v; want %v", got, want)
+	}
+}
+
+func TestParseStreamID(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want map[string]string
+	}{
+		{"", nil},
+		{"live/feed", nil},
+		{"#!::r=live", map[string]string{"r": "live"}},
+		{"#!::r=a=b,s=", map[string]string{"r": "a=b", "s": ""}},
+		{"#!::r=live,bogus", nil},
+		{"#!:{r=live}", nil},
+	} {
+		if got := parseStreamID(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseStreamID(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSRTConnConcurrentWrite(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+	size, err := c.PayloadSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers, msgs = 8, 200
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < msgs; i++ {
+				msg := bytes.Repeat([]byte{byte(w)}, size)
+				msg[0], msg[1] = byte(w), byte(i)
+				if _, err := c.Write(msg); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	s.SetReadDeadline(time.Now().Add(10 * time.Second))
+	buf := make([]byte, size)
+	next := make([]int, writers)
+	for n := 0; n < writers*msgs; n++ {
+		m, err := s.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := int(buf[0])
+		if m != size || w >= writers || !bytes.Equal(buf[2:m], bytes.Repeat([]byte{byte(w)}, size-2)) {
+			t.Fatalf("message %d is corrupt", n)
+		}
+		// Each writer's messages arrive in the order it wrote them.
+		if int(buf[1]) != next[w]%256 {
+			t.Fatalf("writer %d: got message %d; want %d", w, buf[1], next[w]%256)
+		}
+		next[w]++
+	}
+	wg.Wait()
+}
+
+func TestSRTConnWriteBuffers(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+	size, err := c.PayloadSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bufs net.Buffers
+	var want []byte
+	for i := 0; i < 30; i++ {
+		b := bytes.Repeat([]byte{byte(i)}, 100)
+		bufs = append(bufs, b)
+		want = append(want, b...)
+	}
+	n, err := c.WriteBuffers(bufs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(want)) {
+		t.Fatalf("WriteBuffers wrote %d bytes; want %d", n, len(want))
+	}
+
+	s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var got []byte
+	buf := make([]byte, size)
+	for i := 0; len(got) < len(want); i++ {
+		n, err := s.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wantn := len(want) - len(got); wantn > size && n != size || wantn <= size && n != wantn {
+			t.Errorf("message %d: got %d bytes; want messages of %d bytes", i, n, size)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("data read differs from data written")
+	}
+}
+
+func TestSRTConnWriteMessagev(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+	size, err := c.PayloadSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, size)
+	for _, bufs := range [][][]byte{
+		{[]byte("header:"), []byte("payload")},
+		{[]byte("one buffer")},
+		{nil, []byte("empty "), {}, []byte("buffers skipped")},
+		{bytes.Repeat([]byte{1}, 16), bytes.Repeat([]byte{2}, size-16)},
+	} {
+		want := bytes.Join(bufs, nil)
+		n, err := c.WriteMessagev(bufs...)
+		if err != nil || n != len(want) {
+			t.Fatalf("WriteMessagev(%d bytes) = %d, %v", len(want), n, err)
+		}
+		n, err = s.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[:n], want) {
+			t.Errorf("got message of %d bytes; want the %d bytes of the concatenation", n, len(want))
+		}
+	}
+
+	_, err = c.WriteMessagev(make([]byte, size), []byte{0})
+	var mse *MessageSizeError
+	if !errors.As(err, &mse) || mse.Size != size+1 {
+		t.Errorf("oversized message: got %v; want a MessageSizeError of %d bytes", err, size+1)
+	}
+}
+
+func TestMessageBufPool(t *testing.T) {
+	p := getMessageBuf(100)
+	if cap(*p) < 100 {
+		t.Fatalf("got capacity %d; want at least 100", cap(*p))
+	}
+	putMessageBuf(p)
+	big := getMessageBuf(maxPooledMessage + 1)
+	if cap(*big) < maxPooledMessage+1 {
+		t.Fatalf("got capacity %d; want at least %d", cap(*big), maxPooledMessage+1)
+	}
+	putMessageBuf(big)
+	if n := testing.AllocsPerRun(100, func() {
+		putMessageBuf(getMessageBuf(1316))
+	}); n > 0 {
+		t.Errorf("got %v allocations per pooled buffer; want 0", n)
+	}
+}
+
+func TestSRTConnWriteBuffersKeepsBuffers(t *testing.T) {
+	bufs := net.Buffers{[]byte("abc"), []byte("de"), []byte("fgh")}
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+	if _, err := c.WriteBuffers(bufs); err != nil {
+		t.Fatal(err)
+	}
+	// The buffers of the caller are left alone.
+	if len(bufs) != 3 || string(bufs[0]) != "abc" {
+		t.Errorf("WriteBuffers modified bufs: %q", bufs)
+	}
+}
+
+func benchmarkWriteBuffers(b *testing.B, writev bool) {
+	c, s := newSRTPair(b)
+	defer c.Close()
+	go io.Copy(io.Discard, s)
+
+	const count = 10000
+	bufs := make(net.Buffers, count)
+	for i := range bufs {
+		bufs[i] = make([]byte, 16)
+	}
+	b.SetBytes(16 * count)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if writev {
+			if _, err := c.WriteBuffers(bufs); err != nil {
+				b.Fatal(err)
+			}
+			continue
+		}
+		for _, buf := range bufs {
+			if _, err := c.Write(buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.StopTimer()
+	s.Close()
+}
+
+func BenchmarkWriteBuffers(b *testing.B) {
+	b.Run("writev", func(b *testing.B) { benchmarkWriteBuffers(b, true) })
+	b.Run("loop", func(b *testing.B) { benchmarkWriteBuffers(b, false) })
+}
+
 func TestSRTConcurrentAccept(t *testing.T) {
 	if testing.Short() {
 		t.Skip("known-broken test")
@@ -595,3 +997,649 @@ func TestSRTSelfConnect(t *testing.T) {
 		}
 	}
 }
+
+func TestSRTConnSetPacing(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	for _, tt := range []struct {
+		enabled bool
+		maxbw   int64
+	}{
+		{false, -1},
+		{true, 0},
+	} {
+		if err := c.SetPacing(tt.enabled); err != nil {
+			t.Fatal(err)
+		}
+		maxbw, err := srtapi.GetsockoptInt64(c.fd.pfd.Sysfd, 0, srtapi.OptionMaxbw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if maxbw != tt.maxbw {
+			t.Errorf("SetPacing(%v): got maxbw %d; want %d", tt.enabled, maxbw, tt.maxbw)
+		}
+	}
+}
+
+func TestNegotiatedLatency(t *testing.T) {
+	lctx := WithOptions(context.Background(), Options("rcvlatency", "300", "peerlatency", "50"))
+	ln, err := ListenContext(lctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	dctx := WithOptions(context.Background(), Options("rcvlatency", "120", "peerlatency", "400"))
+	var d Dialer
+	c, err := d.DialContext(dctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+
+	// Each direction takes the larger of what its receiver asked for
+	// and what its sender asked for.
+	for _, tt := range []struct {
+		name     string
+		c        *SRTConn
+		rcv, snd time.Duration
+	}{
+		{"caller", c.(*SRTConn), 120 * time.Millisecond, 400 * time.Millisecond},
+		{"listener", s.(*SRTConn), 400 * time.Millisecond, 120 * time.Millisecond},
+	} {
+		if rcv, err := tt.c.NegotiatedRcvLatency(); err != nil || rcv != tt.rcv {
+			t.Errorf("%s: NegotiatedRcvLatency() = %v, %v; want %v", tt.name, rcv, err, tt.rcv)
+		}
+		if snd, err := tt.c.NegotiatedSndLatency(); err != nil || snd != tt.snd {
+			t.Errorf("%s: NegotiatedSndLatency() = %v, %v; want %v", tt.name, snd, err, tt.snd)
+		}
+	}
+}
+
+func TestSRTConnSetSndDropDelay(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	for _, tt := range []struct {
+		in, want time.Duration
+	}{
+		{250 * time.Millisecond, 250 * time.Millisecond},
+		{1500 * time.Microsecond, time.Millisecond},
+		{0, 0},
+		{-time.Second, -time.Millisecond},
+	} {
+		if err := c.SetSndDropDelay(tt.in); err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.SndDropDelay()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("SetSndDropDelay(%v): got %v; want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSRTMessageConn(t *testing.T) {
+	client, server := newSRTPair(t)
+	defer client.Close()
+	defer server.Close()
+	cc, err := NewSRTMessageConn(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := NewSRTMessageConn(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	max := cc.MaxMessageSize()
+	if want, _ := client.PayloadSize(); max != want {
+		t.Errorf("got max message size %d; want payload size %d", max, want)
+	}
+
+	msgs := [][]byte{[]byte("first"), bytes.Repeat([]byte{'x'}, max)}
+	for _, msg := range msgs {
+		if n, err := cc.WriteTo(msg, client.RemoteAddr()); err != nil || n != len(msg) {
+			t.Fatalf("WriteTo = %d, %v; want %d, nil", n, err, len(msg))
+		}
+	}
+	sc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, sc.MaxMessageSize())
+	for _, msg := range msgs {
+		n, addr, err := sc.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b[:n], msg) {
+			t.Errorf("got message of %d bytes; want %d", n, len(msg))
+		}
+		if addr.String() != server.RemoteAddr().String() {
+			t.Errorf("got addr %v; want %v", addr, server.RemoteAddr())
+		}
+	}
+
+	_, err = cc.WriteTo(make([]byte, max+1), client.RemoteAddr())
+	var serr *MessageSizeError
+	if !errors.As(err, &serr) || serr.Size != max+1 || serr.Max != max {
+		t.Errorf("oversized WriteTo: got %v; want MessageSizeError", err)
+	}
+	if !errors.Is(err, srtapi.ELARGEMSG) {
+		t.Errorf("oversized WriteTo: %v does not match ELARGEMSG", err)
+	}
+
+	other := &SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+	if _, err := cc.WriteTo([]byte("x"), other); err == nil {
+		t.Error("WriteTo to another address succeeded")
+	}
+}
+
+func TestDialSRTContext(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	raddr := ln.Addr().(*SRTAddr)
+
+	bad := WithOptions(context.Background(), Options("nosuchoption", "1"))
+	if c, err := DialSRTContext(bad, "srt", nil, raddr); err == nil {
+		c.Close()
+		t.Fatal("dial with an unknown context option succeeded")
+	}
+
+	const streamID = "typed-dial"
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	ctx := WithOptions(context.Background(), Options("streamid", streamID))
+	c, err := DialSRTContext(ctx, "srt", nil, raddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+	if sid, err := s.(*SRTConn).StreamID(); err != nil || sid != streamID {
+		t.Errorf("StreamID() = %q, %v; want %q", sid, err, streamID)
+	}
+}
+
+func TestDialSRTDeferred(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const streamID = "deferred-dial"
+	ctx := WithOptions(context.Background(), Options("streamid", streamID))
+	c, err := DialSRTDeferred(ctx, "srt", nil, ln.Addr().(*SRTAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Write([]byte("EARLY")); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Write before Handshake got %v; want %v", err, ErrNotConnected)
+	}
+	if _, err := c.Read(make([]byte, 16)); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Read before Handshake got %v; want %v", err, ErrNotConnected)
+	}
+	if err := c.SetPacing(true); err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	if err := c.Handshake(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Handshake(context.Background()); err != nil {
+		t.Errorf("second Handshake got %v; want nil", err)
+	}
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+	if sid, err := s.(*SRTConn).StreamID(); err != nil || sid != streamID {
+		t.Errorf("StreamID() = %q, %v; want %q", sid, err, streamID)
+	}
+	if ra := c.RemoteAddr(); ra == nil || ra.String() != ln.Addr().String() {
+		t.Errorf("RemoteAddr() = %v; want %v", ra, ln.Addr())
+	}
+
+	if _, err := c.Write([]byte("DEFERRED TEST")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 16)
+	n, err := s.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != "DEFERRED TEST" {
+		t.Errorf("got %q; want %q", b[:n], "DEFERRED TEST")
+	}
+}
+
+func TestSocketID(t *testing.T) {
+	ln, err := ListenSRT("srt", &SRTAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan *SRTConn, 1)
+	go func() {
+		c, err := ln.AcceptSRT()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	c, err := DialSRTDeferred(context.Background(), "srt", nil, ln.Addr().(*SRTAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	id := c.SocketID()
+	if id == 0 {
+		t.Fatal("SocketID() before Handshake = 0")
+	}
+	if err := c.Handshake(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	if got := c.SocketID(); got != id {
+		t.Errorf("SocketID() after Handshake = %d; want %d", got, id)
+	}
+	ids := map[uint32]string{}
+	for _, x := range []struct {
+		name string
+		id   uint32
+	}{
+		{"listener", ln.SocketID()},
+		{"caller", id},
+		{"accepted", s.SocketID()},
+	} {
+		if other, ok := ids[x.id]; ok || x.id == 0 {
+			t.Errorf("%s SocketID() = %d, same as %s", x.name, x.id, other)
+		}
+		ids[x.id] = x.name
+	}
+	sid := s.SocketID()
+	s.Close()
+	if got := s.SocketID(); got != sid {
+		t.Errorf("SocketID() after Close = %d; want %d", got, sid)
+	}
+}
+
+func TestWildcardListenerAcceptedLocalAddr(t *testing.T) {
+	ips := []net.IP{net.IPv4(127, 0, 0, 1)}
+	if ip := nonLoopbackIPv4Addr(); ip != nil {
+		ips = append(ips, ip)
+	}
+	ln, err := ListenSRT("srt4", &SRTAddr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*SRTAddr).Port
+
+	for _, ip := range ips {
+		accepted := make(chan *SRTConn, 1)
+		go func() {
+			c, err := ln.AcceptSRT()
+			if err != nil {
+				t.Error(err)
+			}
+			accepted <- c
+		}()
+		c, err := DialSRT("srt4", nil, &SRTAddr{IP: ip, Port: port})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := <-accepted
+		if s == nil {
+			c.Close()
+			t.FailNow()
+		}
+		la := s.LocalAddr().(*SRTAddr)
+		if !la.IP.Equal(ip) || la.Port != port {
+			t.Errorf("accepted connection to %v has local address %v; want %v", ip, la, &SRTAddr{IP: ip, Port: port})
+		}
+		s.Close()
+		c.Close()
+	}
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("payloadsize", "32"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			var b [32]byte
+			c.Read(b[:])
+		}
+	}()
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := c.(*SRTConn)
+	if n := sc.MaxPayloadSize(); n != 32 {
+		t.Fatalf("got max payload size %d; want 32", n)
+	}
+
+	n, err := sc.Write(make([]byte, 33))
+	if n != 0 {
+		t.Errorf("oversized Write wrote %d bytes; want 0", n)
+	}
+	var serr *MessageSizeError
+	if !errors.As(err, &serr) || serr.Size != 33 || serr.Max != 32 {
+		t.Fatalf("oversized Write: got %v; want MessageSizeError", err)
+	}
+	if !strings.Contains(err.Error(), "32") {
+		t.Errorf("error %q does not mention the limit", err)
+	}
+	if _, err := sc.Write(make([]byte, 32)); err != nil {
+		t.Errorf("Write of a full payload: %v", err)
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	b := make([]byte, 1500)
+	go func() {
+		_, err := s.ReadContext(ctx, b)
+		errc <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("canceled ReadContext: got %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadContext did not return after cancel")
+	}
+
+	if _, err := c.Write([]byte("CONTEXT TEST")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	n, err := s.ReadContext(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != "CONTEXT TEST" {
+		t.Errorf("got %q; want %q", b[:n], "CONTEXT TEST")
+	}
+}
+
+func TestSRTConnSetBlocking(t *testing.T) {
+	// File mode, with buffers small enough to fill quickly.
+	buf := strconv.Itoa(32 * (maxMSS - srtHeaderSize))
+	ctx := WithOptions(context.Background(), Options("transtype", "1", "fc", "32", "sndbuf", buf, "rcvbuf", buf))
+	for _, tt := range []struct {
+		recv, send bool
+	}{
+		{true, true},
+		{false, true},
+		{true, false},
+		{false, false},
+	} {
+		ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			c, _ := ln.Accept()
+			accepted <- c
+		}()
+		var d Dialer
+		cc, err := d.DialContext(ctx, "srt", ln.Addr().String())
+		ln.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc := <-accepted
+		if sc == nil {
+			cc.Close()
+			t.FailNow()
+		}
+		c, s := cc.(*SRTConn), sc.(*SRTConn)
+
+		b := make([]byte, 64<<10)
+		s.SetBlocking(tt.recv, true)
+		s.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		_, err = s.Read(b)
+		if tt.recv {
+			if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+				t.Errorf("blocking Read with nothing to read: got %v; want a timeout", err)
+			}
+		} else if !errors.Is(err, ErrWouldBlock) || !errors.Is(err, syscall.EAGAIN) {
+			t.Errorf("non-blocking Read with nothing to read: got %v; want %v", err, ErrWouldBlock)
+		}
+
+		// Nobody reads, so the buffers fill up.
+		c.SetBlocking(true, tt.send)
+		c.SetWriteDeadline(time.Now().Add(2 * time.Second))
+		for i := 0; i < 1000; i++ {
+			if _, err = c.Write(b); err != nil {
+				break
+			}
+		}
+		if tt.send {
+			if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+				t.Errorf("blocking Write to a full buffer: got %v; want a timeout", err)
+			}
+		} else if !errors.Is(err, ErrWouldBlock) {
+			t.Errorf("non-blocking Write to a full buffer: got %v; want %v", err, ErrWouldBlock)
+		}
+
+		// Back to blocking, reads and writes go through again.
+		s.SetBlocking(true, true)
+		c.SetBlocking(true, true)
+		s.SetReadDeadline(time.Time{})
+		c.SetWriteDeadline(time.Time{})
+		go io.Copy(io.Discard, s)
+		if _, err := c.Write(b); err != nil {
+			t.Errorf("blocking Write after draining: %v", err)
+		}
+		c.Close()
+		s.Close()
+	}
+}
+
+func TestReadAvailable(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	b := make([]byte, 1500)
+	start := time.Now()
+	if n, err := s.ReadAvailable(b); n != 0 || err != nil {
+		t.Fatalf("ReadAvailable with nothing buffered = %d, %v; want 0, nil", n, err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("ReadAvailable with nothing buffered took %v; want it to return at once", d)
+	}
+
+	if _, err := c.Write([]byte("AVAILABLE TEST")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n, err := s.ReadAvailable(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > 0 {
+			if string(b[:n]) != "AVAILABLE TEST" {
+				t.Errorf("got %q; want %q", b[:n], "AVAILABLE TEST")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("message never became available")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// BenchmarkRead1MB reports how many Reads it takes to read 1MB in file
+// mode with a 1MB buffer, and how many cgo calls the process makes
+// meanwhile, those of the writer included.
+func BenchmarkRead1MB(b *testing.B) {
+	ctx := WithOptions(context.Background(), Options("transtype", "1"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		b.FailNow()
+	}
+	defer s.Close()
+
+	const size = 1 << 20
+	go func() {
+		buf := make([]byte, size)
+		for {
+			if _, err := c.Write(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, size)
+	var reads int
+	b.SetBytes(size)
+	b.ResetTimer()
+	calls := runtime.NumCgoCall()
+	for i := 0; i < b.N; i++ {
+		for n := 0; n < size; reads++ {
+			m, err := s.Read(buf[:size-n])
+			if err != nil {
+				b.Fatal(err)
+			}
+			n += m
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(runtime.NumCgoCall()-calls)/float64(b.N), "cgocalls/op")
+	b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+}
+
+func TestAcceptedLocalAddrOnDemand(t *testing.T) {
+	laddr := &SRTAddr{IP: net.IPv4zero, Port: 5000}
+	fd := &netFD{net: "srt", laddr: laddr, raddr: &SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}}
+	if a := fd.localAddr(); a != laddr {
+		t.Fatalf("localAddr() = %v; want the bound address %v", a, laddr)
+	}
+
+	fd.routeLocal = true
+	a, ok := fd.localAddr().(*SRTAddr)
+	if !ok || !a.IP.Equal(net.IPv4(127, 0, 0, 1)) || a.Port != laddr.Port {
+		t.Fatalf("localAddr() = %v; want 127.0.0.1:%d", a, laddr.Port)
+	}
+	if b := fd.localAddr(); b != net.Addr(a) {
+		t.Errorf("second localAddr() = %v; want the address of the first call", b)
+	}
+	if fd.laddr != net.Addr(laddr) {
+		t.Errorf("laddr = %v; want it left as bound", fd.laddr)
+	}
+}
+
+func TestSRTConnISN(t *testing.T) {
+	var version uint32
+	var getErr error
+	oldVersion, oldGet := getVersionFunc, getsockoptIntFunc
+	defer func() { getVersionFunc, getsockoptIntFunc = oldVersion, oldGet }()
+	getVersionFunc = func() uint32 { return version }
+	getsockoptIntFunc = func(fd, level, opt int) (int, error) {
+		if opt == srtapi.OptionIsn {
+			return 0x12345678, getErr
+		}
+		return oldGet(fd, level, opt)
+	}
+
+	c := newSRTConn(&netFD{net: "srt"})
+	version = packVersion(1, 4, 1)
+	if isn, err := c.ISN(); !errors.Is(err, syscall.ENOTSUP) {
+		t.Errorf("libsrt 1.4.1: got %d, %v; want %v", isn, err, syscall.ENOTSUP)
+	}
+
+	version = packVersion(1, 4, 2)
+	if isn, err := c.ISN(); err != nil || isn != 0x12345678 {
+		t.Errorf("got %#x, %v; want 0x12345678", isn, err)
+	}
+	getErr = srtapi.EINVOP
+	if isn, err := c.ISN(); !errors.Is(err, syscall.ENOTSUP) {
+		t.Errorf("option refused: got %d, %v; want %v", isn, err, syscall.ENOTSUP)
+	}
+	getErr = srtapi.ENOCONN
+	if _, err := c.ISN(); !errors.Is(err, srtapi.ENOCONN) {
+		t.Errorf("got %v; want %v", err, srtapi.ENOCONN)
+	}
+}
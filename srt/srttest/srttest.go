@@ -0,0 +1,331 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// Package srttest provides an in-memory SRT connection, for the tests
+// of code written against srt.Conn to run without a network.
+package srttest
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openfresh/gosrt/srt"
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A Config describes the fake network between the two ends of a Pipe.
+// The zero value delivers every message at once.
+type Config struct {
+	// Delay is the one-way delay of the network: a message is
+	// delivered Delay after it is written.
+	Delay time.Duration
+
+	// Loss is the share of messages lost, from 0 to 1. A lost
+	// message is never delivered, and counts in the PktRcvDrop
+	// statistics of the receiver, as when TSBPD gives up on it.
+	Loss float64
+
+	// Seed seeds the choice of the messages lost, so that a test
+	// sees the same losses on every run.
+	Seed int64
+
+	// Latency is the latency NegotiatedRcvLatency and
+	// NegotiatedSndLatency report. It defaults to 120ms, the libsrt
+	// default.
+	Latency time.Duration
+
+	// StreamID is the stream ID both ends report.
+	StreamID string
+}
+
+var socketID uint32 = 1 << 30
+
+// A message is a message on its way to an end of a pipe.
+type message struct {
+	b  []byte
+	at time.Time // of delivery
+}
+
+// pipe is the state both ends of a Pipe share.
+type pipe struct {
+	cfg Config
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// Conn is an end of a Pipe. It implements srt.Conn in message mode:
+// each Write is delivered whole to one Read of the peer, in order,
+// unless lost. Writes never block.
+type Conn struct {
+	p     *pipe
+	peer  *Conn
+	laddr *srt.SRTAddr
+	raddr *srt.SRTAddr
+	id    uint32
+	wake  chan struct{}
+	start time.Time
+
+	// guarded by p.mu
+	inbox         []message
+	closed        bool
+	peerClosed    bool
+	readDeadline  time.Time
+	writeDeadline time.Time
+	stats         *srt.SRTStats // set by SetStats
+
+	// counters, also guarded by p.mu
+	since                time.Time // the counters were last cleared
+	sentBytes, recvBytes int64
+	drops, dropsTotal    int64
+	sentTotal, recvTotal int64
+}
+
+var _ srt.Conn = (*Conn)(nil)
+
+// Pipe returns the two ends of an in-memory SRT connection, the caller
+// first, joined by the fake network cfg describes.
+func Pipe(cfg Config) (*Conn, *Conn) {
+	if cfg.Latency <= 0 {
+		cfg.Latency = 120 * time.Millisecond
+	}
+	p := &pipe{cfg: cfg, rand: rand.New(rand.NewSource(cfg.Seed))}
+	caller := &srt.SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40000}
+	listener := &srt.SRTAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}
+	now := time.Now()
+	a := &Conn{p: p, laddr: caller, raddr: listener, wake: make(chan struct{}, 1), start: now, since: now}
+	b := &Conn{p: p, laddr: listener, raddr: caller, wake: make(chan struct{}, 1), start: now, since: now}
+	a.peer, b.peer = b, a
+	a.id = atomic.AddUint32(&socketID, 1)
+	b.id = atomic.AddUint32(&socketID, 1)
+	return a, b
+}
+
+func (c *Conn) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Conn) opError(op string, err error) error {
+	return &srt.OpError{Op: op, Net: "srt", Source: c.laddr, Addr: c.raddr, Err: err}
+}
+
+// Read reads the next message delivered into b. It returns io.EOF once
+// the peer is closed and all it sent before has been read. A message
+// longer than b fails the read with io.ErrShortBuffer and is kept, to
+// be read into a larger buffer.
+func (c *Conn) Read(b []byte) (int, error) {
+	var t *time.Timer
+	defer func() {
+		if t != nil {
+			t.Stop()
+		}
+	}()
+	for {
+		c.p.mu.Lock()
+		if c.closed {
+			c.p.mu.Unlock()
+			return 0, c.opError("read", net.ErrClosed)
+		}
+		now := time.Now()
+		if len(c.inbox) > 0 && !c.inbox[0].at.After(now) {
+			m := c.inbox[0]
+			if len(m.b) > len(b) {
+				c.p.mu.Unlock()
+				return 0, c.opError("read", io.ErrShortBuffer)
+			}
+			c.inbox = c.inbox[1:]
+			c.recvBytes += int64(len(m.b))
+			c.recvTotal++
+			c.p.mu.Unlock()
+			return copy(b, m.b), nil
+		}
+		if len(c.inbox) == 0 && c.peerClosed {
+			c.p.mu.Unlock()
+			return 0, io.EOF
+		}
+		deadline := c.readDeadline
+		if !deadline.IsZero() && !now.Before(deadline) {
+			c.p.mu.Unlock()
+			return 0, c.opError("read", os.ErrDeadlineExceeded)
+		}
+		var wait time.Duration
+		if len(c.inbox) > 0 {
+			wait = c.inbox[0].at.Sub(now)
+		}
+		if !deadline.IsZero() && (wait == 0 || deadline.Sub(now) < wait) {
+			wait = deadline.Sub(now)
+		}
+		c.p.mu.Unlock()
+
+		if wait == 0 {
+			<-c.wake
+			continue
+		}
+		if t == nil {
+			t = time.NewTimer(wait)
+		} else {
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+			t.Reset(wait)
+		}
+		select {
+		case <-t.C:
+		case <-c.wake:
+		}
+	}
+}
+
+// Write sends b as one message to the peer, which reads it Delay later
+// unless it is lost. It fails with srtapi.ECONNLOST once the peer is
+// closed.
+func (c *Conn) Write(b []byte) (int, error) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	if c.closed {
+		return 0, c.opError("write", net.ErrClosed)
+	}
+	now := time.Now()
+	if !c.writeDeadline.IsZero() && !now.Before(c.writeDeadline) {
+		return 0, c.opError("write", os.ErrDeadlineExceeded)
+	}
+	if c.peerClosed {
+		return 0, c.opError("write", srtapi.ECONNLOST)
+	}
+	c.sentBytes += int64(len(b))
+	c.sentTotal++
+	peer := c.peer
+	if c.p.cfg.Loss > 0 && c.p.rand.Float64() < c.p.cfg.Loss {
+		peer.drops++
+		peer.dropsTotal++
+		return len(b), nil
+	}
+	peer.inbox = append(peer.inbox, message{b: append([]byte(nil), b...), at: now.Add(c.p.cfg.Delay)})
+	peer.signal()
+	return len(b), nil
+}
+
+// Close closes the end. The peer reads what was sent before, then
+// io.EOF.
+func (c *Conn) Close() error {
+	c.p.mu.Lock()
+	if c.closed {
+		c.p.mu.Unlock()
+		return c.opError("close", net.ErrClosed)
+	}
+	c.closed = true
+	c.peer.peerClosed = true
+	c.p.mu.Unlock()
+	c.signal()
+	c.peer.signal()
+	return nil
+}
+
+// LocalAddr returns the local address, 127.0.0.1:40000 for the caller
+// and 127.0.0.1:5000 for the listener.
+func (c *Conn) LocalAddr() net.Addr { return c.laddr }
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr { return c.raddr }
+
+// SetDeadline sets the read and write deadlines.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.p.mu.Lock()
+	c.readDeadline, c.writeDeadline = t, t
+	c.p.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// SetReadDeadline sets the read deadline.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.p.mu.Lock()
+	c.readDeadline = t
+	c.p.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// SetWriteDeadline sets the write deadline.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.p.mu.Lock()
+	c.writeDeadline = t
+	c.p.mu.Unlock()
+	return nil
+}
+
+// StreamID returns the StreamID of the Config.
+func (c *Conn) StreamID() (string, error) { return c.p.cfg.StreamID, nil }
+
+// SocketID returns an ID of the end, unique in the process.
+func (c *Conn) SocketID() uint32 { return c.id }
+
+// NegotiatedRcvLatency returns the Latency of the Config.
+func (c *Conn) NegotiatedRcvLatency() (time.Duration, error) { return c.p.cfg.Latency, nil }
+
+// NegotiatedSndLatency returns the Latency of the Config.
+func (c *Conn) NegotiatedSndLatency() (time.Duration, error) { return c.p.cfg.Latency, nil }
+
+// SetStats sets the statistics SRTStats returns from then on, in place
+// of those of the fake network; nil goes back to the latter.
+func (c *Conn) SetStats(s *srt.SRTStats) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	if s == nil {
+		c.stats = nil
+		return
+	}
+	cp := *s
+	c.stats = &cp
+}
+
+// SRTStats returns the statistics set by SetStats, or else those of
+// the fake network: PktSentTotal and PktRecvTotal count the messages
+// written and read, MsRTT is twice the Delay, PktRcvDrop counts the
+// messages lost on their way to the end, PktRcvBuf those on their way
+// or not read yet, and the rates are those of the messages written
+// and read since the counters were last cleared.
+func (c *Conn) SRTStats(clear bool) (*srt.SRTStats, error) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	if c.closed {
+		return nil, c.opError("stats", net.ErrClosed)
+	}
+	if c.stats != nil {
+		s := *c.stats
+		return &s, nil
+	}
+	now := time.Now()
+	s := &srt.SRTStats{
+		MsTimeStamp:     int64(now.Sub(c.start) / time.Millisecond),
+		PktSentTotal:    c.sentTotal,
+		PktRecvTotal:    c.recvTotal,
+		PktSentACKTotal: c.recvTotal,
+		PktRecvACKTotal: c.sentTotal,
+		MsRTT:           float64(2*c.p.cfg.Delay) / float64(time.Millisecond),
+		PktRcvBuf:       int64(len(c.inbox)),
+		PktRcvDrop:      c.drops,
+		PktRcvDropTotal: c.dropsTotal,
+	}
+	if elapsed := now.Sub(c.since).Seconds(); elapsed > 0 {
+		s.MbpsSendRate = float64(c.sentBytes*8) / elapsed / 1e6
+		s.MbpsRecvRate = float64(c.recvBytes*8) / elapsed / 1e6
+	}
+	if clear {
+		c.since = now
+		c.sentBytes, c.recvBytes = 0, 0
+		c.drops = 0
+	}
+	return s, nil
+}
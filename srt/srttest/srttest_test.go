@@ -0,0 +1,117 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srttest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/srt"
+)
+
+func TestPipe(t *testing.T) {
+	a, b := Pipe(Config{Delay: 20 * time.Millisecond, StreamID: "live/feed"})
+	var c srt.Conn = a
+	if id, _ := c.StreamID(); id != "live/feed" {
+		t.Errorf("got stream ID %q; want live/feed", id)
+	}
+	if a.SocketID() == b.SocketID() {
+		t.Error("both ends have the same socket ID")
+	}
+	if a.LocalAddr().String() != b.RemoteAddr().String() {
+		t.Errorf("addresses do not match: %v, %v", a.LocalAddr(), b.RemoteAddr())
+	}
+
+	start := time.Now()
+	for _, m := range []string{"one", "two"} {
+		if _, err := a.Write([]byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := make([]byte, 1316)
+	for _, want := range []string{"one", "two"} {
+		n, err := b.Read(buf)
+		if err != nil || string(buf[:n]) != want {
+			t.Fatalf("got %q, %v; want %q", buf[:n], err, want)
+		}
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("delivered after %v; want at least the delay", d)
+	}
+
+	a.Write([]byte("long"))
+	time.Sleep(30 * time.Millisecond)
+	if _, err := b.Read(make([]byte, 2)); !errors.Is(err, io.ErrShortBuffer) {
+		t.Errorf("short buffer: got %v; want %v", err, io.ErrShortBuffer)
+	}
+	if n, err := b.Read(buf); err != nil || string(buf[:n]) != "long" {
+		t.Errorf("after a short buffer: got %q, %v", buf[:n], err)
+	}
+
+	b.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := b.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("deadline: got %v; want %v", err, os.ErrDeadlineExceeded)
+	}
+	b.SetReadDeadline(time.Time{})
+
+	a.Write([]byte("last"))
+	a.Close()
+	if n, err := b.Read(buf); err != nil || string(buf[:n]) != "last" {
+		t.Errorf("before EOF: got %q, %v", buf[:n], err)
+	}
+	if _, err := b.Read(buf); err != io.EOF {
+		t.Errorf("got %v; want EOF", err)
+	}
+	if _, err := b.Write(buf); err == nil {
+		t.Error("write to a closed peer succeeded")
+	}
+}
+
+func TestPipeLoss(t *testing.T) {
+	a, b := Pipe(Config{Loss: 0.25, Seed: 1})
+	const n = 1000
+	for i := 0; i < n; i++ {
+		a.Write([]byte{byte(i)})
+	}
+	s, err := b.SRTStats(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PktRcvDropTotal < n/8 || s.PktRcvDropTotal > n*3/8 {
+		t.Errorf("lost %d of %d; want about a quarter", s.PktRcvDropTotal, n)
+	}
+	if s.PktRcvBuf+s.PktRcvDropTotal != n {
+		t.Errorf("%d buffered and %d lost; want %d in all", s.PktRcvBuf, s.PktRcvDropTotal, n)
+	}
+	if s, _ := b.SRTStats(false); s.PktRcvDrop != 0 || s.PktRcvDropTotal == 0 {
+		t.Errorf("after clearing: got %d dropped, %d in all", s.PktRcvDrop, s.PktRcvDropTotal)
+	}
+
+	// The same seed loses the same messages.
+	c, d := Pipe(Config{Loss: 0.25, Seed: 1})
+	for i := 0; i < n; i++ {
+		c.Write([]byte{byte(i)})
+	}
+	if s2, _ := d.SRTStats(false); s2.PktRcvDropTotal != s.PktRcvDropTotal {
+		t.Errorf("seeded twice: lost %d, then %d", s.PktRcvDropTotal, s2.PktRcvDropTotal)
+	}
+}
+
+func TestSetStats(t *testing.T) {
+	a, _ := Pipe(Config{Delay: 50 * time.Millisecond})
+	if s, _ := a.SRTStats(false); s.MsRTT != 100 {
+		t.Errorf("got RTT %vms; want 100ms", s.MsRTT)
+	}
+	a.SetStats(&srt.SRTStats{MsRTT: 250, MbpsBandwidth: 10})
+	if s, _ := a.SRTStats(true); s.MsRTT != 250 || s.MbpsBandwidth != 10 {
+		t.Errorf("got %+v; want the stats set", s)
+	}
+	a.SetStats(nil)
+	if s, _ := a.SRTStats(false); s.MsRTT != 100 {
+		t.Errorf("after SetStats(nil): got RTT %vms; want 100ms", s.MsRTT)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// A SocketState is the state of an SRT socket, as in SRT_SOCKSTATUS.
+type SocketState int
+
+// Socket states.
+const (
+	StateInit       SocketState = srtapi.StatusInit
+	StateOpened     SocketState = srtapi.StatusOpened
+	StateListening  SocketState = srtapi.StatusListening
+	StateConnecting SocketState = srtapi.StatusConnecting
+	StateConnected  SocketState = srtapi.StatusConnected
+	StateBroken     SocketState = srtapi.StatusBroken
+	StateClosing    SocketState = srtapi.StatusClosing
+	StateClosed     SocketState = srtapi.StatusClosed
+	StateNonexist   SocketState = srtapi.StatusNonexist
+)
+
+var socketStateNames = map[SocketState]string{
+	StateInit:       "init",
+	StateOpened:     "opened",
+	StateListening:  "listening",
+	StateConnecting: "connecting",
+	StateConnected:  "connected",
+	StateBroken:     "broken",
+	StateClosing:    "closing",
+	StateClosed:     "closed",
+	StateNonexist:   "nonexist",
+}
+
+func (s SocketState) String() string {
+	if name, ok := socketStateNames[s]; ok {
+		return name
+	}
+	return "SocketState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// State returns the state of the socket of the connection, without
+// any I/O. A connection the peer closed, or that timed out, is
+// StateBroken until closed; a closed one is StateNonexist.
+func (c *conn) State() SocketState {
+	if !c.ok() {
+		return StateNonexist
+	}
+	return SocketState(srtapi.GetSockState(c.fd.pfd.Sysfd))
+}
+
+// Ping reports whether the peer of the connection is still reachable.
+// It sends nothing: SRT already sends keepalives on an idle
+// connection, and breaks it once nothing, keepalives included, has
+// come from the peer for the peer idle timeout (see
+// SetPeerIdleTimeout). Ping only inspects the state of the socket, so
+// it is cheap enough to run on a timer.
+//
+// It returns nil while the connection is established, and once it is
+// broken an error that wraps srtapi.ECONNLOST and matches
+// ErrConnectionBroken. libsrt breaks a connection the same way whether
+// the peer closed it or stopped responding, so Ping does not tell the
+// two apart.
+func (c *SRTConn) Ping() error {
+	if !c.ok() {
+		return srtapi.EINVPARAM
+	}
+	var err error
+	switch c.State() {
+	case StateConnected:
+		return nil
+	case StateInit, StateOpened, StateConnecting:
+		err = ErrNotConnected
+	case StateBroken:
+		err = classifyConnError(srtapi.ECONNLOST)
+	default:
+		err = classifyConnError(net.ErrClosed)
+	}
+	return &OpError{Op: "ping", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+}
+
+// Ready reports whether a Read and a Write on the connection would
+// proceed without waiting, from the readiness libsrt keeps for its
+// epoll, as in SRTO_EVENT. It consumes nothing, and costs no more
+// than a getsockopt, so that a handful of connections can be polled
+// in turn without a goroutine each. In live mode, data only counts
+// once TSBPD delivers it. A Read that returns io.EOF after CloseSend,
+// or a Write that returns ErrSendClosed, proceeds at once as well.
+//
+// Once the connection is broken, both are true, as Read and Write
+// fail at once, and err matches ErrConnectionBroken. A connection
+// made by DialSRTDeferred is not ready before its Handshake.
+func (c *SRTConn) Ready() (readable, writable bool, err error) {
+	if !c.ok() {
+		return false, false, srtapi.EINVPARAM
+	}
+	if err := c.fd.notConnected(); err != nil {
+		return false, false, &OpError{Op: "ready", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	ev, err := getsockoptIntFunc(c.fd.pfd.Sysfd, 0, srtapi.OptionEvent)
+	if err != nil {
+		return false, false, &OpError{Op: "ready", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: classifyConnError(wrapSyscallError("getsockopt", err))}
+	}
+	if ev&srtapi.EpollErr != 0 {
+		return true, true, &OpError{Op: "ready", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: classifyConnError(srtapi.ECONNLOST)}
+	}
+	readable = ev&srtapi.EpollIn != 0 || atomic.LoadInt32(&c.fd.eofRead) != 0
+	writable = ev&srtapi.EpollOut != 0 || atomic.LoadInt32(&c.fd.sendClosed) != 0
+	return readable, writable, nil
+}
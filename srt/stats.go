@@ -0,0 +1,537 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// SRTStats holds performance statistics of an SRT connection, as
+// reported by libsrt. It maps every field of SRT_TRACEBSTATS, under
+// the same names. Fields ending in Total count since the connection
+// was established, other counters since the last call that cleared
+// them. The fields from UsPktSndPeriod on are not counters: some are
+// instantaneous, others smoothed, as set out below. Whatever the C
+// type, every packet, byte and millisecond field is an int64.
+//
+// libsrt does not report sequence numbers in its statistics; the
+// sequence number of each message read is available through
+// ReadMessageCtrl.
+//
+// The fields differ in how noisy they are, which matters to alerting:
+// MsRTT is smoothed, MbpsBandwidth filtered, and the rates averaged
+// over the interval since the counters were last cleared, so they are
+// as steady as the interval is long; UsPktSndPeriod and the buffer
+// levels are instantaneous. libsrt keeps RTT variance internally but
+// does not report it, nor an RTT per direction: a one-way problem
+// shows in the rates and losses of that direction instead.
+type SRTStats struct {
+	// MsTimeStamp is the time since the connection was established,
+	// in milliseconds.
+	MsTimeStamp int64
+
+	// PktSentTotal and PktRecvTotal count the data packets sent and
+	// received, retransmissions included.
+	PktSentTotal int64
+	PktRecvTotal int64
+
+	// PktSndLossTotal counts the packets the peer reported lost, in
+	// NAKs; PktRcvLossTotal those the receiver found missing.
+	PktSndLossTotal int64
+	PktRcvLossTotal int64
+
+	// PktRetransTotal counts the packets the sender retransmitted.
+	PktRetransTotal int64
+
+	// PktSentACKTotal and PktRecvACKTotal count the ACK packets sent
+	// and received. The RTT is only measured once ACKs flow, which
+	// takes data to be sent in either direction.
+	PktSentACKTotal int64
+	PktRecvACKTotal int64
+
+	// PktSentNAKTotal and PktRecvNAKTotal count the NAK packets,
+	// the loss reports, sent and received.
+	PktSentNAKTotal int64
+	PktRecvNAKTotal int64
+
+	// UsSndDurationTotal is the time the sender had data to send,
+	// in microseconds.
+	UsSndDurationTotal int64
+
+	// PktSndDropTotal counts the packets the sender dropped as too
+	// late to be delivered.
+	PktSndDropTotal int64
+
+	// PktRcvDrop counts the packets dropped by the receiver, either
+	// because they arrived too late to be played or because they
+	// were lost and TSBPD skipped them.
+	PktRcvDrop      int64
+	PktRcvDropTotal int64
+
+	// PktRcvUndecrypt counts the packets that could not be
+	// decrypted, as with a wrong passphrase.
+	PktRcvUndecrypt      int64
+	PktRcvUndecryptTotal int64
+
+	// The byte counterparts of the packet counters above. Since
+	// libsrt 1.3 they include 44 bytes of IPv4, UDP and SRT headers
+	// per packet; BytesSent and BytesReceived take them out.
+	ByteSentTotal         int64
+	ByteRecvTotal         int64
+	ByteRcvLossTotal      int64
+	ByteRetransTotal      int64
+	ByteSndDropTotal      int64
+	ByteRcvDropTotal      int64
+	ByteRcvUndecryptTotal int64
+
+	// The counters above since they were last cleared. PktRcvRetrans
+	// counts the retransmitted packets received, and has no total.
+	PktSent          int64
+	PktRecv          int64
+	PktSndLoss       int64
+	PktRcvLoss       int64
+	PktRetrans       int64
+	PktRcvRetrans    int64
+	PktSentACK       int64
+	PktRecvACK       int64
+	PktSentNAK       int64
+	PktRecvNAK       int64
+	UsSndDuration    int64
+	PktSndDrop       int64
+	ByteSent         int64
+	ByteRecv         int64
+	ByteRcvLoss      int64
+	ByteRetrans      int64
+	ByteSndDrop      int64
+	ByteRcvDrop      int64
+	ByteRcvUndecrypt int64
+
+	// MbpsSendRate and MbpsRecvRate are the rates data is sent and
+	// received at, in megabits per second, over the period since
+	// the counters were last cleared. They are plain averages over
+	// that period, not smoothed across periods. RatesDiverge
+	// compares them with the expected input rate.
+	MbpsSendRate float64
+	MbpsRecvRate float64
+
+	// PktReorderDistance is the largest distance, in packets, by
+	// which a packet arrived out of order. SetLossMaxTTL takes it
+	// as a guide.
+	PktReorderDistance int64
+
+	// PktRcvBelated counts the retransmitted packets that arrived
+	// after their loss had already been given up on, and
+	// PktRcvAvgBelatedTime is how late they were on average, in
+	// milliseconds.
+	PktRcvBelated        int64
+	PktRcvAvgBelatedTime float64
+
+	// UsPktSndPeriod is the interval between packets the sender
+	// paces at, in microseconds, as congestion control last set it.
+	// It is instantaneous.
+	UsPktSndPeriod float64
+
+	// PktFlowWindow is the flow control window in effect, in
+	// packets: the space the peer last reported left in its receive
+	// buffer, bounded by its configured window.
+	PktFlowWindow int64
+
+	// PktCongestionWindow is the window congestion control allows,
+	// in packets, and PktFlightSize the packets sent and not yet
+	// acknowledged. The sender stops at the smaller of the two
+	// windows: see WindowLimited. Live congestion control keeps its
+	// window wide and paces by UsPktSndPeriod instead.
+	PktCongestionWindow int64
+	PktFlightSize       int64
+
+	// MsRTT is the smoothed round-trip time, in milliseconds: an
+	// exponentially weighted moving average, each ACK-ACK sample
+	// weighing 1/8. A spike in the path shows in it over several
+	// samples, and a single late ACK barely does. libsrt starts it
+	// at 100ms until the first measurement.
+	MsRTT float64
+
+	// MbpsBandwidth is libsrt's estimate of the link capacity, in
+	// megabits per second, from the spacing of probe packet pairs
+	// at the receiver, filtered through the median of the recent
+	// ones. It only means something once a few hundred
+	// packets have flowed; before that it is a rough guess, often
+	// far off.
+	MbpsBandwidth float64
+
+	// ByteAvailSndBuf and ByteAvailRcvBuf are the space left in the
+	// send and receive buffers, in bytes.
+	ByteAvailSndBuf int64
+	ByteAvailRcvBuf int64
+
+	// MbpsMaxBW is the cap on the send rate in effect, in megabits
+	// per second, as set by SRTO_MAXBW or SetPacing.
+	MbpsMaxBW float64
+
+	// ByteMSS is the MSS of the connection, in bytes.
+	ByteMSS int64
+
+	// PktSndBuf and PktRcvBuf are the number of packets currently
+	// held in the send and receive buffers, ByteSndBuf and
+	// ByteRcvBuf their size in bytes, and MsSndBuf and MsRcvBuf the
+	// time span of their content, in milliseconds.
+	PktSndBuf  int64
+	ByteSndBuf int64
+	MsSndBuf   int64
+	PktRcvBuf  int64
+	ByteRcvBuf int64
+	MsRcvBuf   int64
+
+	// MsSndTsbPdDelay and MsRcvTsbPdDelay are the TSBPD latencies
+	// in effect for sending and receiving, in milliseconds.
+	MsSndTsbPdDelay int64
+	MsRcvTsbPdDelay int64
+
+	// The packet filter counters, as with SetFEC: the extra packets,
+	// such as FEC control packets, sent and received, the packets
+	// the filter rebuilt, and those it could not. They stay zero
+	// without a filter.
+	PktSndFilterExtraTotal  int64
+	PktRcvFilterExtraTotal  int64
+	PktRcvFilterSupplyTotal int64
+	PktRcvFilterLossTotal   int64
+	PktSndFilterExtra       int64
+	PktRcvFilterExtra       int64
+	PktRcvFilterSupply      int64
+	PktRcvFilterLoss        int64
+}
+
+// RTT returns MsRTT as a time.Duration.
+func (s *SRTStats) RTT() time.Duration {
+	return time.Duration(s.MsRTT * float64(time.Millisecond))
+}
+
+// WindowLimited reports whether the sender is held back by a window
+// rather than by its pacing: whether the packets in flight fill the
+// congestion window or the flow window, whichever is smaller. A
+// window-limited sender gains from larger buffers on the peer, or a
+// shorter RTT; one that is not is bound by the bandwidth.
+func (s *SRTStats) WindowLimited() bool {
+	window := s.PktCongestionWindow
+	if s.PktFlowWindow < window {
+		window = s.PktFlowWindow
+	}
+	return window > 0 && s.PktFlightSize >= window
+}
+
+func newSRTStats(mon *srtapi.TraceBStats) *SRTStats {
+	return &SRTStats{
+		MsTimeStamp:           mon.MsTimeStamp,
+		PktSentTotal:          mon.PktSentTotal,
+		PktRecvTotal:          mon.PktRecvTotal,
+		PktSndLossTotal:       int64(mon.PktSndLossTotal),
+		PktRcvLossTotal:       int64(mon.PktRcvLossTotal),
+		PktRetransTotal:       int64(mon.PktRetransTotal),
+		PktSentACKTotal:       int64(mon.PktSentACKTotal),
+		PktRecvACKTotal:       int64(mon.PktRecvACKTotal),
+		PktSentNAKTotal:       int64(mon.PktSentNAKTotal),
+		PktRecvNAKTotal:       int64(mon.PktRecvNAKTotal),
+		UsSndDurationTotal:    mon.UsSndDurationTotal,
+		PktSndDropTotal:       int64(mon.PktSndDropTotal),
+		PktRcvDrop:            int64(mon.PktRcvDrop),
+		PktRcvDropTotal:       int64(mon.PktRcvDropTotal),
+		PktRcvUndecrypt:       int64(mon.PktRcvUndecrypt),
+		PktRcvUndecryptTotal:  int64(mon.PktRcvUndecryptTotal),
+		ByteSentTotal:         int64(mon.ByteSentTotal),
+		ByteRecvTotal:         int64(mon.ByteRecvTotal),
+		ByteRcvLossTotal:      int64(mon.ByteRcvLossTotal),
+		ByteRetransTotal:      int64(mon.ByteRetransTotal),
+		ByteSndDropTotal:      int64(mon.ByteSndDropTotal),
+		ByteRcvDropTotal:      int64(mon.ByteRcvDropTotal),
+		ByteRcvUndecryptTotal: int64(mon.ByteRcvUndecryptTotal),
+
+		PktSent:          mon.PktSent,
+		PktRecv:          mon.PktRecv,
+		PktSndLoss:       int64(mon.PktSndLoss),
+		PktRcvLoss:       int64(mon.PktRcvLoss),
+		PktRetrans:       int64(mon.PktRetrans),
+		PktRcvRetrans:    int64(mon.PktRcvRetrans),
+		PktSentACK:       int64(mon.PktSentACK),
+		PktRecvACK:       int64(mon.PktRecvACK),
+		PktSentNAK:       int64(mon.PktSentNAK),
+		PktRecvNAK:       int64(mon.PktRecvNAK),
+		UsSndDuration:    mon.UsSndDuration,
+		PktSndDrop:       int64(mon.PktSndDrop),
+		ByteSent:         int64(mon.ByteSent),
+		ByteRecv:         int64(mon.ByteRecv),
+		ByteRcvLoss:      int64(mon.ByteRcvLoss),
+		ByteRetrans:      int64(mon.ByteRetrans),
+		ByteSndDrop:      int64(mon.ByteSndDrop),
+		ByteRcvDrop:      int64(mon.ByteRcvDrop),
+		ByteRcvUndecrypt: int64(mon.ByteRcvUndecrypt),
+
+		MbpsSendRate:         mon.MbpsSendRate,
+		MbpsRecvRate:         mon.MbpsRecvRate,
+		PktReorderDistance:   int64(mon.PktReorderDistance),
+		PktRcvBelated:        mon.PktRcvBelated,
+		PktRcvAvgBelatedTime: mon.PktRcvAvgBelatedTime,
+
+		UsPktSndPeriod:      mon.UsPktSndPeriod,
+		PktFlowWindow:       int64(mon.PktFlowWindow),
+		PktCongestionWindow: int64(mon.PktCongestionWindow),
+		PktFlightSize:       int64(mon.PktFlightSize),
+		MsRTT:               mon.MsRTT,
+		MbpsBandwidth:       mon.MbpsBandwidth,
+		ByteAvailSndBuf:     int64(mon.ByteAvailSndBuf),
+		ByteAvailRcvBuf:     int64(mon.ByteAvailRcvBuf),
+		MbpsMaxBW:           mon.MbpsMaxBW,
+		ByteMSS:             int64(mon.ByteMSS),
+		PktSndBuf:           int64(mon.PktSndBuf),
+		ByteSndBuf:          int64(mon.ByteSndBuf),
+		MsSndBuf:            int64(mon.MsSndBuf),
+		PktRcvBuf:           int64(mon.PktRcvBuf),
+		ByteRcvBuf:          int64(mon.ByteRcvBuf),
+		MsRcvBuf:            int64(mon.MsRcvBuf),
+		MsSndTsbPdDelay:     int64(mon.MsSndTsbPdDelay),
+		MsRcvTsbPdDelay:     int64(mon.MsRcvTsbPdDelay),
+
+		PktSndFilterExtraTotal:  int64(mon.PktSndFilterExtraTotal),
+		PktRcvFilterExtraTotal:  int64(mon.PktRcvFilterExtraTotal),
+		PktRcvFilterSupplyTotal: int64(mon.PktRcvFilterSupplyTotal),
+		PktRcvFilterLossTotal:   int64(mon.PktRcvFilterLossTotal),
+		PktSndFilterExtra:       int64(mon.PktSndFilterExtra),
+		PktRcvFilterExtra:       int64(mon.PktRcvFilterExtra),
+		PktRcvFilterSupply:      int64(mon.PktRcvFilterSupply),
+		PktRcvFilterLoss:        int64(mon.PktRcvFilterLoss),
+	}
+}
+
+// RatesDiverge reports whether MbpsSendRate and MbpsRecvRate are off
+// from mbpsInput, the rate the stream is fed at, such as the bitrate
+// of the encoder, by more than factor either way: above mbpsInput *
+// factor, or below mbpsInput / factor. factor must be above 1. A send
+// rate that diverges while the receive rate does not, or the reverse,
+// points at a problem in one direction. A side that only sends, or
+// only receives, should disregard the other result.
+func (s *SRTStats) RatesDiverge(mbpsInput, factor float64) (send, recv bool) {
+	diverges := func(rate float64) bool {
+		return rate > mbpsInput*factor || rate < mbpsInput/factor
+	}
+	return diverges(s.MbpsSendRate), diverges(s.MbpsRecvRate)
+}
+
+// FlowControlWindow returns the flow control window in effect, in
+// packets, as in the PktFlowWindow field of SRTStats.
+func (c *SRTConn) FlowControlWindow() (int, error) {
+	s, err := c.SRTStats(false)
+	if err != nil {
+		return 0, err
+	}
+	return int(s.PktFlowWindow), nil
+}
+
+// SendBufferPackets returns the number of packets in the send buffer,
+// those not yet sent or not yet acknowledged. A producer can poll it,
+// once per frame say, and lower its bitrate when it grows, rather than
+// find out about congestion when Write blocks.
+func (c *SRTConn) SendBufferPackets() (int, error) {
+	return c.bufferPackets(srtapi.OptionSnddata)
+}
+
+// RecvBufferPackets returns the number of packets in the receive
+// buffer, those received but not yet read.
+func (c *SRTConn) RecvBufferPackets() (int, error) {
+	return c.bufferPackets(srtapi.OptionRcvdata)
+}
+
+func (c *SRTConn) bufferPackets(opt int) (int, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	n, err := srtapi.GetsockoptInt(c.fd.pfd.Sysfd, 0, opt)
+	if err != nil {
+		return 0, &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: wrapSyscallError("getsockopt", err)}
+	}
+	return n, nil
+}
+
+// SendBufferBytes returns the number of bytes in the send buffer. It
+// reads the instantaneous statistics, which costs a little more than
+// SendBufferPackets, but does not reset any counter.
+func (c *SRTConn) SendBufferBytes() (int, error) {
+	mon, err := c.bufferStats()
+	if err != nil {
+		return 0, err
+	}
+	return mon.ByteSndBuf, nil
+}
+
+// RecvBufferBytes returns the number of bytes in the receive buffer,
+// like SendBufferBytes.
+func (c *SRTConn) RecvBufferBytes() (int, error) {
+	mon, err := c.bufferStats()
+	if err != nil {
+		return 0, err
+	}
+	return mon.ByteRcvBuf, nil
+}
+
+func (c *SRTConn) bufferStats() (*srtapi.TraceBStats, error) {
+	if !c.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	mon, err := srtapi.Bistats(c.fd.pfd.Sysfd, false, true)
+	if err != nil {
+		return nil, &OpError{Op: "stats", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return mon, nil
+}
+
+// ipv6ExtraHeaderSize is what the IPv6 header adds to the IPv4 one
+// that srtHeaderSize, like the byte counters of libsrt, allows for.
+const ipv6ExtraHeaderSize = 20
+
+// payloadBytes returns the payload in bytes, as libsrt counts bytes
+// since 1.3: with srtHeaderSize of headers per packet, for pkts
+// packets.
+func payloadBytes(bytes uint64, pkts int64) int64 {
+	return int64(bytes) - pkts*srtHeaderSize
+}
+
+// wireBytes returns the bytes that bytes, as libsrt counts them for
+// pkts packets, take on the wire over family.
+func wireBytes(bytes uint64, pkts int64, family int) int64 {
+	if family == syscall.AF_INET6 {
+		return int64(bytes) + pkts*ipv6ExtraHeaderSize
+	}
+	return int64(bytes)
+}
+
+// BytesSent returns the payload bytes sent since the connection was
+// established, counting each byte written once: retransmissions are
+// left out. It is read from the statistics of libsrt, like all the
+// byte counters below, and never decreases while the connection is
+// open. libsrt counts the IPv4, UDP and SRT headers of each packet in
+// its byte counters; BytesSent and BytesReceived take them out.
+func (c *SRTConn) BytesSent() (int64, error) {
+	mon, err := c.bufferStats()
+	if err != nil {
+		return 0, err
+	}
+	return payloadBytes(mon.ByteSentTotal-mon.ByteRetransTotal, mon.PktSentTotal-int64(mon.PktRetransTotal)), nil
+}
+
+// BytesReceived returns the payload bytes received since the
+// connection was established. libsrt counts every data packet that
+// arrives, so a packet received twice, as when a retransmission
+// crosses the original, counts twice.
+func (c *SRTConn) BytesReceived() (int64, error) {
+	mon, err := c.bufferStats()
+	if err != nil {
+		return 0, err
+	}
+	return payloadBytes(mon.ByteRecvTotal, mon.PktRecvTotal), nil
+}
+
+// BytesSentWithOverhead returns the bytes sent on the wire since the
+// connection was established: the payload with retransmissions, plus
+// the SRT, UDP and IP headers of each data packet, the IPv6 one on
+// IPv6 connections. Control packets,
+// such as ACKs and NAKs, are not counted; libsrt does not report their
+// size.
+func (c *SRTConn) BytesSentWithOverhead() (int64, error) {
+	mon, err := c.bufferStats()
+	if err != nil {
+		return 0, err
+	}
+	return wireBytes(mon.ByteSentTotal, mon.PktSentTotal, c.fd.family), nil
+}
+
+// BytesReceivedWithOverhead returns the bytes received on the wire
+// since the connection was established, like BytesSentWithOverhead.
+func (c *SRTConn) BytesReceivedWithOverhead() (int64, error) {
+	mon, err := c.bufferStats()
+	if err != nil {
+		return 0, err
+	}
+	return wireBytes(mon.ByteRecvTotal, mon.PktRecvTotal, c.fd.family), nil
+}
+
+// lossSample holds the cumulative counters LossRate computes rates
+// from.
+type lossSample struct {
+	sent, sndLoss int64
+	recv, rcvLoss int64
+}
+
+// LossRate returns the percentage of packets lost in each direction
+// since the previous call. The first call returns zeros and starts the
+// window.
+//
+// It works from the cumulative counters, the Total fields of SRTStats,
+// so it neither disturbs nor is disturbed by SRTStats calls that clear
+// the interval counters. The send rate is the losses the peer reported
+// over the packets sent; the receive rate the losses detected over the
+// packets expected, those received plus those lost. Retransmissions
+// count as sent packets. A rate is zero when nothing was sent or
+// received in the window.
+//
+// The window is kept per connection, so concurrent callers split it
+// between them: each sees the losses since whichever call came last.
+func (c *SRTConn) LossRate() (sendPct, recvPct float64, err error) {
+	if !c.ok() {
+		return 0, 0, srtapi.EINVPARAM
+	}
+	mon, err := srtapi.Bistats(c.fd.pfd.Sysfd, false, false)
+	if err != nil {
+		return 0, 0, &OpError{Op: "stats", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	cur := &lossSample{
+		sent:    mon.PktSentTotal,
+		sndLoss: int64(mon.PktSndLossTotal),
+		recv:    mon.PktRecvTotal,
+		rcvLoss: int64(mon.PktRcvLossTotal),
+	}
+	c.lossMu.Lock()
+	prev := c.loss
+	c.loss = cur
+	c.lossMu.Unlock()
+	if prev == nil {
+		return 0, 0, nil
+	}
+	sendPct, recvPct = lossRates(prev, cur)
+	return sendPct, recvPct, nil
+}
+
+func lossRates(prev, cur *lossSample) (sendPct, recvPct float64) {
+	if sent := cur.sent - prev.sent; sent > 0 {
+		sendPct = float64(cur.sndLoss-prev.sndLoss) / float64(sent) * 100
+	}
+	lost := cur.rcvLoss - prev.rcvLoss
+	if expected := cur.recv - prev.recv + lost; expected > 0 {
+		recvPct = float64(lost) / float64(expected) * 100
+	}
+	return sendPct, recvPct
+}
+
+// SRTStats returns the statistics of the connection. If clear is
+// true, the counters that are not totals are reset afterwards.
+//
+// The rates and the bandwidth estimate can drive the bitrate of a live
+// encoder, keeping it to a share of the estimated capacity minus the
+// 25% overhead SRT keeps for retransmissions by default:
+//
+//	s, err := c.SRTStats(true)
+//	if err == nil && s.MbpsBandwidth > 0 {
+//		target := s.MbpsBandwidth * 0.8 / 1.25
+//		encoder.SetBitrate(int(target * 1e6))
+//	}
+func (c *SRTConn) SRTStats(clear bool) (*SRTStats, error) {
+	if !c.ok() {
+		return nil, srtapi.EINVPARAM
+	}
+	mon, err := srtapi.Bistats(c.fd.pfd.Sysfd, clear, true)
+	if err != nil {
+		return nil, &OpError{Op: "stats", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return newSRTStats(mon), nil
+}
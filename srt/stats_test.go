@@ -0,0 +1,365 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+func TestNewSRTStats(t *testing.T) {
+	mon := &srtapi.TraceBStats{
+		MsTimeStamp:          1500,
+		PktRetransTotal:      2,
+		MsRTT:                12.5,
+		PktSndBuf:            3,
+		PktRcvBuf:            4,
+		PktRcvDrop:           5,
+		PktRcvDropTotal:      50,
+		PktRcvUndecrypt:      6,
+		PktRcvUndecryptTotal: 60,
+		PktRcvBelated:        7,
+		PktReorderDistance:   8,
+		MbpsSendRate:         4.5,
+		MbpsRecvRate:         0.5,
+		UsPktSndPeriod:       10.5,
+		MbpsBandwidth:        95.2,
+		MbpsMaxBW:            1000,
+	}
+	want := SRTStats{
+		MsTimeStamp:          1500,
+		PktRetransTotal:      2,
+		MsRTT:                12.5,
+		PktSndBuf:            3,
+		PktRcvBuf:            4,
+		PktRcvDrop:           5,
+		PktRcvDropTotal:      50,
+		PktRcvUndecrypt:      6,
+		PktRcvUndecryptTotal: 60,
+		PktRcvBelated:        7,
+		PktReorderDistance:   8,
+		MbpsSendRate:         4.5,
+		MbpsRecvRate:         0.5,
+		UsPktSndPeriod:       10.5,
+		MbpsBandwidth:        95.2,
+		MbpsMaxBW:            1000,
+	}
+	s := newSRTStats(mon)
+	if *s != want {
+		t.Errorf("newSRTStats = %+v; want %+v", *s, want)
+	}
+	if got := s.RTT(); got != 12500*time.Microsecond {
+		t.Errorf("RTT() = %v; want 12.5ms", got)
+	}
+}
+
+// TestNewSRTStatsComplete checks that every field of the libsrt
+// statistics is carried over, under the same name.
+func TestNewSRTStatsComplete(t *testing.T) {
+	var mon srtapi.TraceBStats
+	mv := reflect.ValueOf(&mon).Elem()
+	for i := 0; i < mv.NumField(); i++ {
+		switch f := mv.Field(i); f.Kind() {
+		case reflect.Int, reflect.Int64:
+			f.SetInt(int64(i + 1))
+		case reflect.Uint64:
+			f.SetUint(uint64(i + 1))
+		case reflect.Float64:
+			f.SetFloat(float64(i+1) + 0.5)
+		default:
+			t.Fatalf("%s: unexpected kind %v", mv.Type().Field(i).Name, f.Kind())
+		}
+	}
+	sv := reflect.ValueOf(newSRTStats(&mon)).Elem()
+	if n, m := sv.NumField(), mv.NumField(); n != m {
+		t.Errorf("SRTStats has %d fields; TraceBStats has %d", n, m)
+	}
+	for i := 0; i < mv.NumField(); i++ {
+		name := mv.Type().Field(i).Name
+		f := sv.FieldByName(name)
+		if !f.IsValid() {
+			t.Errorf("SRTStats has no field %s", name)
+			continue
+		}
+		var got float64
+		switch f.Kind() {
+		case reflect.Int, reflect.Int64:
+			got = float64(f.Int())
+		case reflect.Uint64:
+			got = float64(f.Uint())
+		case reflect.Float64:
+			got = f.Float()
+		}
+		want := float64(i + 1)
+		if mv.Field(i).Kind() == reflect.Float64 {
+			want += 0.5
+		}
+		if got != want {
+			t.Errorf("%s = %v; want %v", name, got, want)
+		}
+	}
+}
+
+func TestWindowLimited(t *testing.T) {
+	for _, tt := range []struct {
+		cwnd, flow, flight int64
+		want               bool
+	}{
+		{0, 0, 0, false},
+		{16, 8192, 15, false},
+		{16, 8192, 16, true},
+		{1000, 25, 25, true},
+		{1000, 25, 24, false},
+	} {
+		s := SRTStats{PktCongestionWindow: tt.cwnd, PktFlowWindow: tt.flow, PktFlightSize: tt.flight}
+		if got := s.WindowLimited(); got != tt.want {
+			t.Errorf("cwnd %d, flow %d, flight %d: WindowLimited() = %v; want %v", tt.cwnd, tt.flow, tt.flight, got, tt.want)
+		}
+	}
+}
+
+func TestSRTStatsConsistent(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	const msgs = 100
+	b := make([]byte, 1000)
+	for i := 0; i < msgs; i++ {
+		if _, err := c.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < msgs; i++ {
+		if _, err := s.Read(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, conn := range []*SRTConn{c, s} {
+		st, err := conn.SRTStats(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := reflect.ValueOf(st).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if (f.Kind() == reflect.Int || f.Kind() == reflect.Int64) && f.Int() < 0 ||
+				f.Kind() == reflect.Float64 && f.Float() < 0 {
+				t.Errorf("%s = %v; want it non-negative", v.Type().Field(i).Name, f)
+			}
+		}
+		// Nothing was cleared, so the interval counters match the
+		// totals.
+		if st.PktSent != st.PktSentTotal || st.ByteSent != st.ByteSentTotal ||
+			st.PktRecv != st.PktRecvTotal || st.ByteRecv != st.ByteRecvTotal {
+			t.Errorf("interval counters %d/%d sent, %d/%d received differ from totals %d/%d, %d/%d",
+				st.PktSent, st.ByteSent, st.PktRecv, st.ByteRecv,
+				st.PktSentTotal, st.ByteSentTotal, st.PktRecvTotal, st.ByteRecvTotal)
+		}
+		if st.PktRetransTotal > st.PktSentTotal {
+			t.Errorf("PktRetransTotal %d above PktSentTotal %d", st.PktRetransTotal, st.PktSentTotal)
+		}
+		if st.ByteMSS != 1500 {
+			t.Errorf("ByteMSS = %d; want 1500", st.ByteMSS)
+		}
+		if st.MsSndTsbPdDelay != 120 || st.MsRcvTsbPdDelay != 120 {
+			t.Errorf("TSBPD delays %d, %d; want the default of 120", st.MsSndTsbPdDelay, st.MsRcvTsbPdDelay)
+		}
+		if st.ByteAvailSndBuf == 0 || st.ByteAvailRcvBuf == 0 {
+			t.Errorf("no buffer space left on an idle connection: %d, %d", st.ByteAvailSndBuf, st.ByteAvailRcvBuf)
+		}
+	}
+	if st, err := c.SRTStats(false); err != nil {
+		t.Fatal(err)
+	} else if st.PktSentTotal < msgs {
+		t.Errorf("PktSentTotal = %d; want at least %d", st.PktSentTotal, msgs)
+	}
+}
+
+func TestRatesDiverge(t *testing.T) {
+	for _, tt := range []struct {
+		send, recv         float64
+		wantSend, wantRecv bool
+	}{
+		{4, 4, false, false},
+		{7.9, 2.1, false, false},
+		{8.1, 4, true, false},
+		{4, 1.9, false, true},
+		{0, 0, true, true},
+	} {
+		s := SRTStats{MbpsSendRate: tt.send, MbpsRecvRate: tt.recv}
+		send, recv := s.RatesDiverge(4, 2)
+		if send != tt.wantSend || recv != tt.wantRecv {
+			t.Errorf("send %v, recv %v: RatesDiverge(4, 2) = %v, %v; want %v, %v", tt.send, tt.recv, send, recv, tt.wantSend, tt.wantRecv)
+		}
+	}
+}
+
+func TestBufferLevels(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("transtype", "1", "messageapi", "false"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+	sc, rc := c.(*SRTConn), s.(*SRTConn)
+
+	if n, err := sc.SendBufferPackets(); err != nil || n != 0 {
+		t.Errorf("idle SendBufferPackets = %d, %v; want 0", n, err)
+	}
+
+	// The peer does not read, so what is written piles up in the
+	// buffers until the write times out.
+	sc.SetWriteDeadline(time.Now().Add(time.Second))
+	sc.Write(make([]byte, 64<<20))
+
+	for _, tt := range []struct {
+		name string
+		fn   func() (int, error)
+	}{
+		{"SendBufferPackets", sc.SendBufferPackets},
+		{"SendBufferBytes", sc.SendBufferBytes},
+		{"RecvBufferPackets", rc.RecvBufferPackets},
+		{"RecvBufferBytes", rc.RecvBufferBytes},
+	} {
+		n, err := tt.fn()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if n <= 0 {
+			t.Errorf("%s = %d against a peer that does not read; want more than 0", tt.name, n)
+		}
+	}
+}
+
+func TestLossRates(t *testing.T) {
+	for _, tt := range []struct {
+		prev, cur  lossSample
+		send, recv float64
+	}{
+		{lossSample{}, lossSample{}, 0, 0},
+		{lossSample{sent: 100, sndLoss: 1}, lossSample{sent: 300, sndLoss: 5}, 2, 0},
+		{lossSample{recv: 1000, rcvLoss: 10}, lossSample{recv: 1090, rcvLoss: 20}, 0, 10},
+		{lossSample{sent: 50, recv: 50}, lossSample{sent: 50, recv: 50}, 0, 0},
+	} {
+		send, recv := lossRates(&tt.prev, &tt.cur)
+		if send != tt.send || recv != tt.recv {
+			t.Errorf("lossRates(%+v, %+v) = %v, %v; want %v, %v", tt.prev, tt.cur, send, recv, tt.send, tt.recv)
+		}
+	}
+}
+
+func TestLossRate(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	if send, recv, err := c.LossRate(); err != nil || send != 0 || recv != 0 {
+		t.Fatalf("first LossRate = %v, %v, %v; want zeros", send, recv, err)
+	}
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 16)
+	s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := s.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing is lost on loopback.
+	if send, recv, err := c.LossRate(); err != nil || send != 0 || recv != 0 {
+		t.Errorf("LossRate = %v, %v, %v; want zeros", send, recv, err)
+	}
+}
+
+func TestWireBytes(t *testing.T) {
+	for _, tt := range []struct {
+		payload uint64
+		pkts    int64
+		family  int
+		want    int64
+	}{
+		{0, 0, syscall.AF_INET, 0},
+		{1316 + 44, 1, syscall.AF_INET, 1316 + 44},
+		{13160 + 440, 10, syscall.AF_INET, 13160 + 440},
+		{1316 + 44, 1, syscall.AF_INET6, 1316 + 64},
+	} {
+		if n := wireBytes(tt.payload, tt.pkts, tt.family); n != tt.want {
+			t.Errorf("wireBytes(%d, %d, %d) = %d; want %d", tt.payload, tt.pkts, tt.family, n, tt.want)
+		}
+	}
+}
+
+func TestPayloadBytes(t *testing.T) {
+	if n := payloadBytes(20*(1000+44), 20); n != 20*1000 {
+		t.Errorf("payloadBytes = %d; want %d", n, 20*1000)
+	}
+	if n := payloadBytes(0, 0); n != 0 {
+		t.Errorf("payloadBytes(0, 0) = %d; want 0", n)
+	}
+}
+
+func TestBytesSentReceived(t *testing.T) {
+	c, s := newSRTPair(t)
+	defer c.Close()
+	defer s.Close()
+
+	const msgs, size = 20, 1000
+	b := make([]byte, size)
+	for i := 0; i < msgs; i++ {
+		if _, err := c.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < msgs; i++ {
+		if _, err := s.Read(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing is lost nor retransmitted on loopback, so the payload
+	// counters match what was written, and each message of less than
+	// the payload size takes one packet, with its headers on the wire.
+	const want = msgs * size
+	if n, err := c.BytesSent(); err != nil || n != want {
+		t.Errorf("BytesSent = %d, %v; want %d", n, err, want)
+	}
+	if n, err := s.BytesReceived(); err != nil || n != want {
+		t.Errorf("BytesReceived = %d, %v; want %d", n, err, want)
+	}
+	const wire = msgs * (size + srtHeaderSize)
+	if n, err := c.BytesSentWithOverhead(); err != nil || n != wire {
+		t.Errorf("BytesSentWithOverhead = %d, %v; want %d", n, err, wire)
+	}
+	if n, err := s.BytesReceivedWithOverhead(); err != nil || n != wire {
+		t.Errorf("BytesReceivedWithOverhead = %d, %v; want %d", n, err, wire)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/openfresh/gosrt/internal/poll"
+)
+
+// errNoSwap is the error of a Swap with no connection prepared.
+var errNoSwap = errors.New("no connection prepared to swap to")
+
+// SwappableConn is a caller connection that can move to a new
+// connection, dialed with other options, without a gap in the data:
+// make before break. PrepareSwap dials the replacement while the
+// active connection keeps flowing, and Swap cuts Writes over to it
+// between two of them, then closes the old connection once it has
+// delivered what was written to it.
+//
+// From PrepareSwap to Swap both connections are open, so the peer
+// sees two callers, and a listener fed a stream per caller sees two
+// streams for that while. What the peer sent on the old connection
+// and Read has not returned yet is lost when Swap closes it.
+type SwappableConn struct {
+	network string
+	address string
+
+	wmu sync.Mutex // held by Write, so that Swap falls between Writes
+
+	mu        sync.Mutex
+	c         *SRTConn // active
+	next      *SRTConn // prepared by PrepareSwap, or nil
+	closed    bool
+	rdeadline time.Time
+	wdeadline time.Time
+}
+
+// NewSwappableConn returns a SwappableConn whose active connection is
+// c. Replacements are dialed to the remote address of c.
+func NewSwappableConn(c *SRTConn) *SwappableConn {
+	return &SwappableConn{
+		network: c.fd.net,
+		address: c.RemoteAddr().String(),
+		c:       c,
+	}
+}
+
+// PrepareSwap dials the connection Swap is to move to, with d, which
+// holds the new options. The active connection is not affected. A
+// connection prepared earlier, and not swapped to, is closed.
+func (sc *SwappableConn) PrepareSwap(ctx context.Context, d *Dialer) error {
+	c, err := d.DialContext(ctx, sc.network, sc.address)
+	if err != nil {
+		return err
+	}
+	next := c.(*SRTConn)
+	sc.mu.Lock()
+	if sc.closed {
+		sc.mu.Unlock()
+		next.Close()
+		return sc.opError("dial", poll.ErrNetClosing)
+	}
+	old := sc.next
+	sc.next = next
+	sc.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Swap makes the connection of PrepareSwap the active one, and closes
+// the one that was. It waits for a Write in progress to complete, so
+// that no Write is split across the two; a Read in progress goes on
+// with the new connection.
+//
+// Before closing the old connection, Swap waits until the peer has
+// acknowledged everything written to it, while Writes go on with the
+// new one: in live mode libsrt closes a connection without lingering,
+// and would drop what is left in the send buffer. The wait ends at the
+// write deadline, if any; what the old connection has not delivered by
+// then is lost, and Swap returns the timeout.
+func (sc *SwappableConn) Swap() error {
+	sc.wmu.Lock()
+	sc.mu.Lock()
+	if sc.closed {
+		sc.mu.Unlock()
+		sc.wmu.Unlock()
+		return sc.opError("swap", poll.ErrNetClosing)
+	}
+	if sc.next == nil {
+		sc.mu.Unlock()
+		sc.wmu.Unlock()
+		return sc.opError("swap", errNoSwap)
+	}
+	old := sc.c
+	sc.c, sc.next = sc.next, nil
+	if !sc.rdeadline.IsZero() {
+		sc.c.SetReadDeadline(sc.rdeadline)
+	}
+	if !sc.wdeadline.IsZero() {
+		sc.c.SetWriteDeadline(sc.wdeadline)
+	}
+	sc.mu.Unlock()
+	sc.wmu.Unlock()
+
+	derr := old.fd.drain()
+	if err := old.Close(); err != nil {
+		return err
+	}
+	if derr != nil {
+		return &OpError{Op: "swap", Net: sc.network, Source: old.LocalAddr(), Addr: old.RemoteAddr(), Err: derr}
+	}
+	return nil
+}
+
+// Conn returns the active connection.
+func (sc *SwappableConn) Conn() *SRTConn {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c
+}
+
+// Read implements the Conn Read method. It reads from the active
+// connection, and goes on with the new one if a Swap closes it
+// meanwhile.
+func (sc *SwappableConn) Read(b []byte) (int, error) {
+	for {
+		c := sc.Conn()
+		n, err := c.Read(b)
+		if err == nil || sc.Conn() == c {
+			return n, err
+		}
+	}
+}
+
+// Write implements the Conn Write method. It writes to the active
+// connection.
+func (sc *SwappableConn) Write(b []byte) (int, error) {
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	return sc.Conn().Write(b)
+}
+
+// Close closes the active connection, and the prepared one, if any.
+func (sc *SwappableConn) Close() error {
+	sc.mu.Lock()
+	if sc.closed {
+		sc.mu.Unlock()
+		return sc.opError("close", poll.ErrNetClosing)
+	}
+	sc.closed = true
+	c, next := sc.c, sc.next
+	sc.next = nil
+	sc.mu.Unlock()
+	if next != nil {
+		next.Close()
+	}
+	return c.Close()
+}
+
+func (sc *SwappableConn) opError(op string, err error) error {
+	c := sc.Conn()
+	return &OpError{Op: op, Net: sc.network, Source: c.LocalAddr(), Addr: c.RemoteAddr(), Err: err}
+}
+
+// LocalAddr returns the local network address of the active
+// connection.
+func (sc *SwappableConn) LocalAddr() net.Addr {
+	return sc.Conn().LocalAddr()
+}
+
+// RemoteAddr returns the remote network address of the active
+// connection.
+func (sc *SwappableConn) RemoteAddr() net.Addr {
+	return sc.Conn().RemoteAddr()
+}
+
+// SetDeadline implements the Conn SetDeadline method. Deadlines carry
+// over to the connection swapped to.
+func (sc *SwappableConn) SetDeadline(t time.Time) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.rdeadline, sc.wdeadline = t, t
+	return sc.c.SetDeadline(t)
+}
+
+// SetReadDeadline implements the Conn SetReadDeadline method.
+func (sc *SwappableConn) SetReadDeadline(t time.Time) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.rdeadline = t
+	return sc.c.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements the Conn SetWriteDeadline method.
+func (sc *SwappableConn) SetWriteDeadline(t time.Time) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.wdeadline = t
+	return sc.c.SetWriteDeadline(t)
+}
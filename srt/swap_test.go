@@ -0,0 +1,109 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+var _ net.Conn = &SwappableConn{}
+
+func TestSwappableConn(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan *SRTConn, 2)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c.(*SRTConn)
+		}
+	}()
+	recv := func(c *SRTConn, want string) {
+		t.Helper()
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 64)
+		n, err := c.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b[:n]) != want {
+			t.Errorf("got %q; want %q", b[:n], want)
+		}
+	}
+
+	var d1 Dialer
+	d1.SetOption("streamid", "v1")
+	c, err := d1.DialContext(context.Background(), "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewSwappableConn(c.(*SRTConn))
+	defer sc.Close()
+	s1 := <-accepted
+	defer s1.Close()
+
+	if err := sc.Swap(); !errors.Is(err, errNoSwap) {
+		t.Errorf("Swap before PrepareSwap: got %v; want %v", err, errNoSwap)
+	}
+	if _, err := sc.Write([]byte("ONE")); err != nil {
+		t.Fatal(err)
+	}
+	recv(s1, "ONE")
+
+	var d2 Dialer
+	d2.SetOption("streamid", "v2")
+	if err := sc.PrepareSwap(context.Background(), &d2); err != nil {
+		t.Fatal(err)
+	}
+	s2 := <-accepted
+	defer s2.Close()
+	if sid, _ := s2.StreamID(); sid != "v2" {
+		t.Fatalf("prepared connection has stream ID %q; want v2", sid)
+	}
+	// Writes go to the old connection until Swap.
+	if _, err := sc.Write([]byte("STILL ONE")); err != nil {
+		t.Fatal(err)
+	}
+	recv(s1, "STILL ONE")
+
+	// A Read in progress goes on with the new connection.
+	readc := make(chan string, 1)
+	go func() {
+		b := make([]byte, 64)
+		n, err := sc.Read(b)
+		if err != nil {
+			t.Error(err)
+		}
+		readc <- string(b[:n])
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := sc.Swap(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.Write([]byte("TWO")); err != nil {
+		t.Fatal(err)
+	}
+	recv(s2, "TWO")
+	if _, err := s2.Write([]byte("BACK")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-readc:
+		if got != "BACK" {
+			t.Errorf("Read across Swap got %q; want BACK", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read did not move to the new connection")
+	}
+}
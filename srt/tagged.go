@@ -0,0 +1,101 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ConnMeta describes an accepted connection, as captured once when it
+// was accepted.
+type ConnMeta struct {
+	// ID numbers the connections accepted by every TaggedListener
+	// of the process, from 1, so that it is unique among them.
+	ID uint64
+
+	// SocketID is the libsrt socket ID, as in SRTConn.SocketID.
+	SocketID uint32
+
+	StreamID   string
+	RemoteAddr *SRTAddr
+
+	// LocalAddr is the address the listener is bound to, which may be
+	// a wildcard; SRTConn.LocalAddr resolves the address the
+	// connection arrived on.
+	LocalAddr *SRTAddr
+
+	AcceptTime time.Time
+
+	// RouteKey is the key the OnAccept function of the ListenConfig
+	// returned, if it had one.
+	RouteKey string
+}
+
+// taggedConnID is the last ConnMeta.ID handed out.
+var taggedConnID uint64
+
+// A TaggedListener wraps an SRTListener so that the connections it
+// accepts carry a ConnMeta, for logging and tracing to annotate their
+// records with. It is an optional layer: the SRTListener alone
+// captures nothing.
+type TaggedListener struct {
+	*SRTListener
+}
+
+// NewTaggedListener returns a TaggedListener accepting on ln.
+func NewTaggedListener(ln *SRTListener) *TaggedListener {
+	return &TaggedListener{SRTListener: ln}
+}
+
+// A TaggedConn is a connection accepted by a TaggedListener.
+type TaggedConn struct {
+	*SRTConn
+	meta ConnMeta
+}
+
+// Meta returns the metadata of the connection. It does not change
+// over the life of the connection, and takes no call to libsrt. The
+// addresses are shared by all calls, so do not modify them.
+func (c *TaggedConn) Meta() ConnMeta {
+	return c.meta
+}
+
+// AcceptTagged accepts the next incoming call like AcceptSRT, and
+// returns the connection with its metadata.
+func (l *TaggedListener) AcceptTagged() (*TaggedConn, error) {
+	c, err := l.SRTListener.AcceptSRT()
+	if err != nil {
+		return nil, err
+	}
+	return newTaggedConn(c), nil
+}
+
+// Accept implements the Accept method in the Listener interface; it
+// returns a *TaggedConn.
+func (l *TaggedListener) Accept() (net.Conn, error) {
+	c, err := l.AcceptTagged()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newTaggedConn(c *SRTConn) *TaggedConn {
+	raddr, _ := c.fd.raddr.(*SRTAddr)
+	laddr, _ := c.fd.laddr.(*SRTAddr)
+	return &TaggedConn{
+		SRTConn: c,
+		meta: ConnMeta{
+			ID:         atomic.AddUint64(&taggedConnID, 1),
+			SocketID:   c.fd.socketID,
+			StreamID:   c.fd.streamID,
+			RemoteAddr: raddr,
+			LocalAddr:  laddr,
+			AcceptTime: time.Now(),
+			RouteKey:   c.routeKey,
+		},
+	}
+}
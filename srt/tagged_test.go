@@ -0,0 +1,75 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+var _ net.Listener = &TaggedListener{}
+
+func TestTaggedListener(t *testing.T) {
+	ln, err := Listen("srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := NewTaggedListener(ln.(*SRTListener))
+	defer tl.Close()
+
+	var metas []ConnMeta
+	for _, streamID := range []string{"tagged-1", "tagged-2"} {
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			c, err := tl.Accept()
+			if err != nil {
+				t.Error(err)
+			}
+			accepted <- c
+		}()
+		var d Dialer
+		d.SetOption("streamid", streamID)
+		before := time.Now()
+		c, err := d.DialContext(context.Background(), "srt", tl.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		s := <-accepted
+		if s == nil {
+			t.FailNow()
+		}
+		defer s.Close()
+
+		tc, ok := s.(*TaggedConn)
+		if !ok {
+			t.Fatalf("Accept returned %T; want *TaggedConn", s)
+		}
+		m := tc.Meta()
+		if m.StreamID != streamID {
+			t.Errorf("StreamID = %q; want %q", m.StreamID, streamID)
+		}
+		if m.SocketID != tc.SocketID() {
+			t.Errorf("SocketID = %d; want %d", m.SocketID, tc.SocketID())
+		}
+		if m.RemoteAddr == nil || m.RemoteAddr.String() != c.LocalAddr().String() {
+			t.Errorf("RemoteAddr = %v; want %v", m.RemoteAddr, c.LocalAddr())
+		}
+		if m.LocalAddr == nil || m.LocalAddr.String() != tl.Addr().String() {
+			t.Errorf("LocalAddr = %v; want %v", m.LocalAddr, tl.Addr())
+		}
+		if m.AcceptTime.Before(before) || m.AcceptTime.After(time.Now()) {
+			t.Errorf("AcceptTime = %v; want after %v", m.AcceptTime, before)
+		}
+		if tc.Meta() != m {
+			t.Error("Meta changed between calls")
+		}
+		metas = append(metas, m)
+	}
+	if metas[0].ID == 0 || metas[1].ID <= metas[0].ID {
+		t.Errorf("IDs %d, %d; want increasing from 1 on", metas[0].ID, metas[1].ID)
+	}
+}
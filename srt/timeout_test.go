@@ -8,8 +8,13 @@
 package srt
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"runtime"
 	"sync"
 	"testing"
@@ -39,6 +44,335 @@ var dialTimeoutTests = []struct {
 	{50 * time.Millisecond, 5 * time.Second, 100 * time.Millisecond, time.Second}, // timeout over deadline
 }
 
+func TestReadTimeoutPartialData(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("transtype", "1", "messageapi", "false"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	wb := make([]byte, 64<<10)
+	for i := range wb {
+		wb[i] = byte(i)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		_, err = c.Write(wb)
+		errc <- err
+		time.Sleep(time.Second) // keep the connection up while reading
+	}()
+
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	var rb []byte
+	b := make([]byte, 1000)
+	for {
+		c.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := c.Read(b)
+		rb = append(rb, b[:n]...)
+		if err == nil {
+			continue
+		}
+		if n != 0 {
+			t.Errorf("Read = %d, %v; want 0 bytes along with the timeout", n, err)
+		}
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("Read error = %v; want os.ErrDeadlineExceeded", err)
+		}
+		if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+			t.Fatalf("Read error = %v; want a timeout", err)
+		}
+		break
+	}
+	if !bytes.Equal(rb, wb) {
+		t.Fatalf("read %d bytes, want %d bytes unchanged", len(rb), len(wb))
+	}
+}
+
+func TestReadTimeoutPartialMessage(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("transtype", "0"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A large message of the payload size, and TS packets of 188
+	// bytes, smaller than the buffer but far below the payload size.
+	msgs := [][]byte{make([]byte, defaultLivePayloadSize)}
+	for i := 0; i < 3; i++ {
+		msgs = append(msgs, make([]byte, 188))
+	}
+	for i, m := range msgs {
+		for j := range m {
+			m[j] = byte(i + j)
+		}
+	}
+	start := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		<-start
+		for _, m := range msgs {
+			if _, err := c.Write(m); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- nil
+		time.Sleep(time.Second) // keep the connection up while reading
+	}()
+
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// With nothing available, the deadline returns nothing.
+	b := make([]byte, defaultLivePayloadSize)
+	c.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := c.Read(b); n != 0 || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read = %d, %v; want 0 bytes along with the timeout", n, err)
+	}
+
+	close(start)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	// The messages survive the timeout, each whole, and the deadline
+	// passes again once they are read.
+	for i, m := range msgs {
+		if i > 0 {
+			b = make([]byte, 1000)
+		}
+		c.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := c.Read(b)
+		if err != nil || !bytes.Equal(b[:n], m) {
+			t.Fatalf("Read #%d = %d, %v; want the whole message of %d bytes", i, n, err, len(m))
+		}
+	}
+	if n, err := c.Read(b); n != 0 || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read = %d, %v; want 0 bytes along with the timeout", n, err)
+	}
+}
+
+func TestWriteTimeoutPartialData(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("transtype", "1", "messageapi", "false"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+
+	// The peer does not read until the write times out, so the
+	// send buffer fills up long before all of wb is accepted.
+	wb := make([]byte, 64<<20)
+	for i := range wb {
+		wb[i] = byte(i % 251)
+	}
+	c.SetWriteDeadline(time.Now().Add(time.Second))
+	n, err := c.Write(wb)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Write error = %v; want os.ErrDeadlineExceeded", err)
+	}
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("Write error = %v; want a timeout", err)
+	}
+	if n <= 0 || n >= len(wb) {
+		t.Fatalf("Write = %d bytes; want a partial write", n)
+	}
+
+	// Clearing the deadline lets writes through again, and the peer
+	// gets exactly the n bytes reported, followed by the next write.
+	c.SetWriteDeadline(time.Time{})
+	readc := make(chan []byte, 1)
+	go func() {
+		rb := make([]byte, n+len("end"))
+		s.SetReadDeadline(time.Now().Add(30 * time.Second))
+		m, err := io.ReadFull(s, rb)
+		if err != nil {
+			t.Errorf("read %d of %d bytes: %v", m, len(rb), err)
+		}
+		readc <- rb[:m]
+	}()
+	if _, err := c.Write([]byte("end")); err != nil {
+		t.Fatal(err)
+	}
+	rb := <-readc
+	if len(rb) != n+len("end") {
+		t.FailNow()
+	}
+	if !bytes.Equal(rb[:n], wb[:n]) || string(rb[n:]) != "end" {
+		t.Fatalf("peer did not receive the %d bytes accepted followed by the next write", n)
+	}
+}
+
+// TestReadDeadlinePreempts checks that a deadline set while a Read is
+// blocked holds that Read to it, whether it is moved closer or into
+// the past.
+func TestReadDeadlinePreempts(t *testing.T) {
+	client, server := newSRTPair(t)
+	defer client.Close()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name     string
+		deadline time.Duration // from the time it is set
+	}{
+		{"past", -time.Second},
+		{"near", 50 * time.Millisecond},
+	} {
+		client.SetReadDeadline(time.Now().Add(time.Hour))
+		errc := make(chan error, 1)
+		go func() {
+			_, err := client.Read(make([]byte, 1500))
+			errc <- err
+		}()
+		time.Sleep(100 * time.Millisecond) // for the Read to block
+		start := time.Now()
+		client.SetReadDeadline(start.Add(tt.deadline))
+		select {
+		case err := <-errc:
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Errorf("%s: Read error = %v; want os.ErrDeadlineExceeded", tt.name, err)
+			}
+			if d := time.Since(start); d > tt.deadline+500*time.Millisecond {
+				t.Errorf("%s: Read returned %v after the deadline was set", tt.name, d)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: Read still blocked", tt.name)
+		}
+	}
+}
+
+// TestSetReadDeadlineKeepsWriteDeadline checks that setting the read
+// deadline leaves a pending write deadline to expire when it was due.
+func TestSetReadDeadlineKeepsWriteDeadline(t *testing.T) {
+	ctx := WithOptions(context.Background(), Options("transtype", "1", "messageapi", "false"))
+	ln, err := ListenContext(ctx, "srt", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	var d Dialer
+	c, err := d.DialContext(ctx, "srt", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s := <-accepted
+	if s == nil {
+		t.FailNow()
+	}
+	defer s.Close()
+
+	// The peer does not read, so the write blocks until its deadline.
+	start := time.Now()
+	c.SetWriteDeadline(start.Add(500 * time.Millisecond))
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		c.SetReadDeadline(time.Now().Add(time.Hour))
+	}()
+	_, err = c.Write(make([]byte, 64<<20))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Write error = %v; want os.ErrDeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 750*time.Millisecond {
+		t.Errorf("Write timed out after %v; want about 500ms", d)
+	}
+}
+
+func TestWithDefaultReadTimeout(t *testing.T) {
+	client, server := newSRTPair(t)
+	defer server.Close()
+	tc := WithDefaultReadTimeout(client, 200*time.Millisecond)
+	defer tc.Close()
+	if tc.Unwrap() != client {
+		t.Fatal("Unwrap does not return the wrapped connection")
+	}
+
+	// Data arriving more often than the timeout keeps Read going for
+	// longer than the timeout in total.
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(100 * time.Millisecond)
+			if _, err := server.Write([]byte("tick")); err != nil {
+				return
+			}
+		}
+	}()
+	b := make([]byte, 16)
+	for i := 0; i < 5; i++ {
+		if _, err := tc.Read(b); err != nil {
+			t.Fatalf("Read #%d: %v", i, err)
+		}
+	}
+
+	// A silent peer makes Read time out.
+	start := time.Now()
+	_, err := tc.Read(b)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read error = %v; want os.ErrDeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Read timed out after %v; want about 200ms", elapsed)
+	}
+
+	// Write is not bounded without a write timeout.
+	if _, err := tc.Write([]byte("still writable")); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDialTimeout(t *testing.T) {
 	if testing.Short() {
 		t.Skipf("skipping test: not supported yet")
@@ -96,6 +430,36 @@ func TestDialTimeout(t *testing.T) {
 	}
 }
 
+func TestDialTimeoutCoversHandshake(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	// Nothing answers the handshake, which libsrt alone would give
+	// up on after 3s.
+	ln, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const timeout = defaultConnTimeout + 1500*time.Millisecond
+	start := time.Now()
+	c, err := DialTimeout("srt", ln.LocalAddr().String(), timeout)
+	if err == nil {
+		c.Close()
+		t.Fatal("dial to a silent peer succeeded")
+	}
+	if elapsed := time.Since(start); elapsed < timeout-500*time.Millisecond {
+		t.Errorf("dial gave up after %v; want about %v", elapsed, timeout)
+	}
+	if perr := parseDialError(err); perr != nil {
+		t.Error(perr)
+	}
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Errorf("got %v; want a timeout", err)
+	}
+}
+
 var dialTimeoutMaxDurationTests = []struct {
 	timeout time.Duration
 	delta   time.Duration // for deadline
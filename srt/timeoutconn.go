@@ -0,0 +1,62 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"time"
+)
+
+// A TimeoutConn is an io.ReadWriteCloser over an SRTConn that sets a
+// deadline before every Read, and every Write if WriteTimeout is set,
+// so that a dead peer cannot block it forever. The deadline rolls: it
+// is always the timeout from the start of the call, so a stream that
+// keeps delivering data never times out, however long it lasts.
+//
+// The deadlines it sets replace any set on the underlying connection.
+type TimeoutConn struct {
+	c *SRTConn
+
+	// ReadTimeout bounds each Read. Zero means no timeout.
+	ReadTimeout time.Duration
+
+	// WriteTimeout bounds each Write. Zero, the default, means no
+	// timeout.
+	WriteTimeout time.Duration
+}
+
+// WithDefaultReadTimeout returns a TimeoutConn over conn whose Read
+// calls time out after d. Set WriteTimeout for Write calls to time
+// out too.
+func WithDefaultReadTimeout(conn *SRTConn, d time.Duration) *TimeoutConn {
+	return &TimeoutConn{c: conn, ReadTimeout: d}
+}
+
+// Read reads from the connection, failing with an error matching
+// os.ErrDeadlineExceeded if nothing arrives within ReadTimeout.
+func (c *TimeoutConn) Read(b []byte) (int, error) {
+	if c.ReadTimeout > 0 {
+		if err := c.c.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.c.Read(b)
+}
+
+// Write writes to the connection, failing with an error matching
+// os.ErrDeadlineExceeded if b is not all accepted within
+// WriteTimeout.
+func (c *TimeoutConn) Write(b []byte) (int, error) {
+	if c.WriteTimeout > 0 {
+		if err := c.c.SetWriteDeadline(time.Now().Add(c.WriteTimeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.c.Write(b)
+}
+
+// Close closes the underlying connection.
+func (c *TimeoutConn) Close() error { return c.c.Close() }
+
+// Unwrap returns the underlying connection.
+func (c *TimeoutConn) Unwrap() *SRTConn { return c.c }
@@ -0,0 +1,32 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+// +build !windows
+
+package srt
+
+import (
+	"os"
+	"syscall"
+)
+
+// probeUDPBuffer sets the socket buffer opt of a throwaway UDP socket
+// to bytes, and returns the size the kernel reports for it then, which
+// a UDP socket of libsrt gets too.
+func probeUDPBuffer(opt, bytes int) (int, error) {
+	s, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		if s, err = syscall.Socket(syscall.AF_INET6, syscall.SOCK_DGRAM, 0); err != nil {
+			return 0, os.NewSyscallError("socket", err)
+		}
+	}
+	defer syscall.Close(s)
+	if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, opt, bytes); err != nil {
+		return 0, os.NewSyscallError("setsockopt", err)
+	}
+	n, err := syscall.GetsockoptInt(s, syscall.SOL_SOCKET, opt)
+	if err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+	return n, nil
+}
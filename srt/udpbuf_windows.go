@@ -0,0 +1,10 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+// probeUDPBuffer returns bytes: Windows grants socket buffers as
+// asked.
+func probeUDPBuffer(opt, bytes int) (int, error) {
+	return bytes, nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// sharedUDP is a duplicate of the descriptor of a *net.UDPConn, to be
+// handed over to libsrt in place of a UDP socket of its own.
+type sharedUDP struct {
+	fd    int
+	taken bool // libsrt owns fd, and closes it
+}
+
+// sharedUDPContextKey is the type of contextKeys used for the UDP
+// socket of ListenSRTOnConn and DialSRTOnConn.
+type sharedUDPContextKey struct{}
+
+func sharedUDPValue(ctx context.Context) *sharedUDP {
+	u, _ := ctx.Value(sharedUDPContextKey{}).(*sharedUDP)
+	return u
+}
+
+// ListenSRTOnConn announces for SRT callers on the UDP socket of c,
+// instead of binding a UDP socket of its own, so that SRT can run on
+// a port that was opened beforehand, for example one that was
+// mapped through a NAT with STUN, or passed down by a supervisor.
+//
+// The descriptor of c is duplicated, and libsrt takes the duplicate
+// over: it is closed along with the listener and the last of the
+// connections it accepted. The caller still owns c, and can close it
+// at any time without affecting SRT. But libsrt reads every datagram
+// arriving on the socket from then on, and drops those that are not
+// SRT, so the caller must stop reading from c; writing to it is fine.
+//
+// The options of cfg are applied as with ListenConfig.Listen. Its
+// InterfaceName and IPv6Only do not apply, as c is bound already.
+func ListenSRTOnConn(c *net.UDPConn, cfg ListenConfig) (*SRTListener, error) {
+	u, network, laddr, err := shareUDPConn(c)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: "srt", Source: nil, Addr: nil, Err: err}
+	}
+	ctx := cfg.context(context.Background())
+	if cfg.Backlog > 0 {
+		ctx = context.WithValue(ctx, backlogContextKey{}, cfg.Backlog)
+	}
+	if cfg.OnAccept != nil {
+		ctx = context.WithValue(ctx, onAcceptContextKey{}, cfg.OnAccept)
+	}
+	if cfg.RecvDropLimit != (DropLimit{}) {
+		if err := cfg.RecvDropLimit.validate(); err != nil {
+			u.release()
+			return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: laddr.opAddr(), Err: err}
+		}
+		ctx = context.WithValue(ctx, recvDropLimitContextKey{}, cfg.RecvDropLimit)
+	}
+	ctx = context.WithValue(ctx, sharedUDPContextKey{}, u)
+	ln, err := listenSRT(ctx, network, laddr)
+	if err != nil {
+		u.release()
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: laddr.opAddr(), Err: err}
+	}
+	return ln, nil
+}
+
+// DialSRTOnConn connects to raddr through the UDP socket of c, the
+// way ListenSRTOnConn listens on it. The ownership of the descriptor
+// is the same: libsrt closes its duplicate along with the connection,
+// the caller keeps c, and must stop reading from it.
+//
+// This also suits NAT mappings that must be made from the socket SRT
+// then uses, as through STUN. It does not suit a TURN allocation:
+// TURN frames every datagram, which libsrt does not do, so it needs
+// a local forwarder instead.
+//
+// The options and Timeout of d are applied as with Dialer.DialContext;
+// its LocalAddr and InterfaceName are ignored.
+func DialSRTOnConn(ctx context.Context, c *net.UDPConn, raddr *SRTAddr, d Dialer) (*SRTConn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	if raddr == nil {
+		return nil, &OpError{Op: "dial", Net: "srt", Source: nil, Addr: nil, Err: errMissingAddress}
+	}
+	u, network, laddr, err := shareUDPConn(c)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: "srt", Source: nil, Addr: raddr, Err: err}
+	}
+	ctx = d.context(ctx)
+	if deadline := d.deadline(ctx, time.Now()); !deadline.IsZero() {
+		subCtx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		ctx = subCtx
+	}
+	ctx = context.WithValue(ctx, sharedUDPContextKey{}, u)
+	conn, err := doDialSRT(ctx, network, nil, raddr)
+	if err != nil {
+		u.release()
+		return nil, &OpError{Op: "dial", Net: network, Source: laddr, Addr: raddr, Err: err}
+	}
+	return conn, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// An SRTURI is a parsed srt:// URI, the endpoint notation used by
+// srt-live-transmit, ffmpeg and OBS, such as
+//
+//	srt://host:port?latency=200&passphrase=...&streamid=...&mode=caller
+type SRTURI struct {
+	// Address is the host:port part of the URI, suitable for Dial
+	// and Listen.
+	Address string
+
+	// Addr is Address resolved.
+	Addr *SRTAddr
+
+	// Mode is "caller", "listener" or "rendezvous". It defaults to
+	// "caller", or to "listener" when the URI has no host.
+	Mode string
+
+	// LocalAddr is the local address given by the adapter and port
+	// parameters, or nil. In rendezvous mode it defaults to the
+	// port of Addr.
+	LocalAddr *SRTAddr
+
+	// Options holds the socket options of the remaining parameters.
+	Options OptionSet
+}
+
+// A URIParser parses srt:// URIs.
+type URIParser struct {
+	// IgnoreUnknown makes Parse skip query parameters that name no
+	// known option instead of failing.
+	IgnoreUnknown bool
+}
+
+// ParseSRTURI parses rawuri into an SRTURI, rejecting query
+// parameters that name no known option.
+func ParseSRTURI(rawuri string) (*SRTURI, error) {
+	var p URIParser
+	return p.Parse(rawuri)
+}
+
+// Parse parses rawuri into an SRTURI.
+//
+// Query parameters are socket option names as listed in the options
+// table, plus mode, adapter and port. Values are percent-decoded, but
+// unlike in HTML forms a '+' is kept as is, so passphrases and stream
+// IDs need no escaping beyond '%', '&' and '#' characters. A '#' does
+// not start a fragment, since stream IDs such as "#!::r=live" commonly
+// contain it unescaped. The transtype option also accepts "live" and
+// "file".
+func (p *URIParser) Parse(rawuri string) (*SRTURI, error) {
+	rest := strings.TrimPrefix(rawuri, "srt://")
+	if rest == rawuri {
+		return nil, &url.Error{Op: "parse", URL: rawuri, Err: errors.New("missing srt:// scheme")}
+	}
+	var query string
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		rest, query = rest[:i], rest[i+1:]
+	}
+	u := &SRTURI{Address: strings.TrimSuffix(rest, "/")}
+	host, port, err := net.SplitHostPort(u.Address)
+	if err != nil {
+		return nil, &url.Error{Op: "parse", URL: rawuri, Err: err}
+	}
+	if host == "" {
+		u.Mode = "listener"
+	} else {
+		u.Mode = "caller"
+	}
+
+	var adapter, localPort string
+	for _, kv := range strings.Split(query, "&") {
+		if kv == "" {
+			continue
+		}
+		k, v := kv, ""
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			k, v = kv[:i], kv[i+1:]
+		}
+		if v, err = url.PathUnescape(v); err != nil {
+			return nil, &url.Error{Op: "parse", URL: rawuri, Err: err}
+		}
+		switch k {
+		case "mode":
+			switch v {
+			case "caller", "client":
+				u.Mode = "caller"
+			case "listener", "server":
+				u.Mode = "listener"
+			case "rendezvous":
+				u.Mode = "rendezvous"
+			default:
+				return nil, &url.Error{Op: "parse", URL: rawuri, Err: errors.New("invalid mode " + strconv.Quote(v))}
+			}
+			continue
+		case "adapter":
+			adapter = v
+			continue
+		case "port":
+			localPort = v
+			continue
+		case "transtype":
+			v = transtypeValue(v)
+		}
+		o := lookupOption(k)
+		if o == nil {
+			if p.IgnoreUnknown {
+				continue
+			}
+			return nil, &url.Error{Op: "parse", URL: rawuri, Err: errors.New("unknown option " + strconv.Quote(k))}
+		}
+		if _, err := o.extract(v); err != nil {
+			return nil, &url.Error{Op: "parse", URL: rawuri, Err: errors.New("invalid value " + strconv.Quote(v) + " for option " + k)}
+		}
+		u.Options.list = append(u.Options.list, option{key: k, value: v})
+	}
+
+	if u.Addr, err = ResolveSRTAddr("srt", u.Address); err != nil {
+		return nil, err
+	}
+	if u.Mode == "rendezvous" {
+		u.Options.list = append(u.Options.list, option{key: "rendezvous", value: "true"})
+		if localPort == "" {
+			localPort = port
+		}
+	}
+	if adapter != "" || localPort != "" {
+		if localPort == "" {
+			localPort = "0"
+		}
+		if u.LocalAddr, err = ResolveSRTAddr("srt", net.JoinHostPort(adapter, localPort)); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// Context returns a copy of ctx carrying the options of u.
+func (u *SRTURI) Context(ctx context.Context) context.Context {
+	return WithOptions(ctx, u.Options)
+}
+
+// Dialer returns a Dialer set up for the local address of u.
+func (u *SRTURI) Dialer() *Dialer {
+	d := &Dialer{}
+	if u.LocalAddr != nil {
+		d.LocalAddr = u.LocalAddr
+	}
+	return d
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+)
+
+var parseSRTURITests = []struct {
+	uri     string
+	address string
+	mode    string
+	laddr   *SRTAddr
+	options map[string]string
+}{
+	{"srt://127.0.0.1:5000", "127.0.0.1:5000", "caller", nil, map[string]string{}},
+	{"srt://:5000", ":5000", "listener", nil, map[string]string{}},
+	{
+		"srt://127.0.0.1:5000?latency=200&passphrase=very%26long+pass&mode=caller",
+		"127.0.0.1:5000", "caller", nil,
+		map[string]string{"latency": "200", "passphrase": "very&long+pass"},
+	},
+	{
+		"srt://127.0.0.1:5000?streamid=#!::u=user,r=results.csv&transtype=file",
+		"127.0.0.1:5000", "caller", nil,
+		map[string]string{"streamid": "#!::u=user,r=results.csv", "transtype": "1"},
+	},
+	{"srt://0.0.0.0:5000?mode=server", "0.0.0.0:5000", "listener", nil, map[string]string{}},
+	{
+		"srt://127.0.0.1:5000?mode=rendezvous",
+		"127.0.0.1:5000", "rendezvous", &SRTAddr{Port: 5000},
+		map[string]string{"rendezvous": "true"},
+	},
+	{
+		"srt://127.0.0.1:5000?adapter=127.0.0.2&port=6000",
+		"127.0.0.1:5000", "caller", &SRTAddr{IP: net.IPv4(127, 0, 0, 2), Port: 6000},
+		map[string]string{},
+	},
+}
+
+func TestParseSRTURI(t *testing.T) {
+	for _, tt := range parseSRTURITests {
+		u, err := ParseSRTURI(tt.uri)
+		if err != nil {
+			t.Errorf("ParseSRTURI(%q) failed: %v", tt.uri, err)
+			continue
+		}
+		if u.Address != tt.address || u.Mode != tt.mode || !reflect.DeepEqual(u.LocalAddr, tt.laddr) {
+			t.Errorf("ParseSRTURI(%q) = %q, %q, %v; want %q, %q, %v", tt.uri, u.Address, u.Mode, u.LocalAddr, tt.address, tt.mode, tt.laddr)
+		}
+		options := map[string]string{}
+		for k, v := range optionValue(u.Context(context.Background())) {
+			options[k] = v
+		}
+		if !reflect.DeepEqual(options, tt.options) {
+			t.Errorf("ParseSRTURI(%q) options = %v; want %v", tt.uri, options, tt.options)
+		}
+	}
+}
+
+func TestParseSRTURIError(t *testing.T) {
+	for _, uri := range []string{
+		"udp://127.0.0.1:5000",
+		"srt://127.0.0.1",
+		"srt://127.0.0.1:5000?mode=publisher",
+		"srt://127.0.0.1:5000?latency=fast",
+		"srt://127.0.0.1:5000?bogus=1",
+		"srt://127.0.0.1:5000?passphrase=%zz",
+	} {
+		if u, err := ParseSRTURI(uri); err == nil {
+			t.Errorf("ParseSRTURI(%q) = %+v; want error", uri, u)
+		}
+	}
+
+	p := URIParser{IgnoreUnknown: true}
+	u, err := p.Parse("srt://127.0.0.1:5000?bogus=1&latency=120")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := Option(u.Context(context.Background()), "latency"); v != "120" {
+		t.Errorf("got latency %q; want 120", v)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"strconv"
+
+	"github.com/openfresh/gosrt/logging"
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+// The oldest libsrt the package supports.
+const (
+	minVersionMajor = 1
+	minVersionMinor = 4
+	minVersionPatch = 1
+)
+
+func init() {
+	checkVersion()
+}
+
+// Version returns the version of the linked libsrt, which may differ
+// from the one the package was built against when libsrt is linked
+// dynamically.
+func Version() (major, minor, patch int, str string) {
+	v := getVersionFunc()
+	major, minor, patch = splitVersion(v)
+	return major, minor, patch, versionString(v)
+}
+
+// VersionAtLeast reports whether the linked libsrt is of version
+// major.minor.patch or later. Options and features added by later
+// versions of libsrt can be gated on it.
+func VersionAtLeast(major, minor, patch int) bool {
+	return getVersionFunc() >= packVersion(major, minor, patch)
+}
+
+// splitVersion decodes a libsrt version, packed into an integer as
+// 0x00MMmmpp, the way srt_getversion and SRTO_PEERVERSION return it.
+func splitVersion(v uint32) (major, minor, patch int) {
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)
+}
+
+func packVersion(major, minor, patch int) uint32 {
+	return uint32(major<<16 | minor<<8 | patch)
+}
+
+// PeerVersion returns the version of libsrt on the other end of the
+// connection, as it reported it in the handshake. It is 0.0.0 before
+// the connection is established.
+func (c *SRTConn) PeerVersion() (major, minor, patch int, err error) {
+	v, err := c.peerVersion()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, minor, patch = splitVersion(v)
+	return major, minor, patch, nil
+}
+
+// PeerVersionAtLeast reports whether the peer of the connection runs
+// libsrt of version major.minor.patch or later, so that features it
+// must support too, such as packet filters, can be gated on it.
+func (c *SRTConn) PeerVersionAtLeast(major, minor, patch int) (bool, error) {
+	v, err := c.peerVersion()
+	if err != nil {
+		return false, err
+	}
+	return v >= packVersion(major, minor, patch), nil
+}
+
+func (c *SRTConn) peerVersion() (uint32, error) {
+	if !c.ok() {
+		return 0, srtapi.EINVPARAM
+	}
+	v, err := getsockoptIntFunc(c.fd.pfd.Sysfd, 0, srtapi.OptionPeerversion)
+	if err != nil {
+		return 0, c.infoError(err)
+	}
+	return uint32(v), nil
+}
+
+// versionString formats a libsrt version as returned by
+// srtapi.GetVersion.
+func versionString(v uint32) string {
+	major, minor, patch := splitVersion(v)
+	return strconv.Itoa(major) + "." + strconv.Itoa(minor) + "." + strconv.Itoa(patch)
+}
+
+// checkVersion logs a warning if the linked libsrt is older than the
+// package supports, since some options then fail in ways that do not
+// point at the version.
+func checkVersion() {
+	if VersionAtLeast(minVersionMajor, minVersionMinor, minVersionPatch) {
+		return
+	}
+	_, _, _, v := Version()
+	logging.Log(srtapi.LogWarning, "gosrt", "libsrt "+v+" is older than 1.4.1, the oldest version supported; some options may fail")
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018 CyberAgent, Inc. All rights reserved.
+// https://github.com/openfresh/gosrt
+
+package srt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openfresh/gosrt/srtapi"
+)
+
+var peerVersionTests = []struct {
+	packed              int
+	major, minor, patch int
+	str                 string
+	atLeast140          bool
+}{
+	{0x000000, 0, 0, 0, "0.0.0", false},
+	{0x010302, 1, 3, 2, "1.3.2", false},
+	{0x010400, 1, 4, 0, "1.4.0", true},
+	{0x010402, 1, 4, 2, "1.4.2", true},
+	{0x010501, 1, 5, 1, "1.5.1", true},
+	{0x020000, 2, 0, 0, "2.0.0", true},
+}
+
+func TestPeerVersion(t *testing.T) {
+	var packed int
+	var getErr error
+	old := getsockoptIntFunc
+	defer func() { getsockoptIntFunc = old }()
+	getsockoptIntFunc = func(fd, level, opt int) (int, error) {
+		if opt == srtapi.OptionPeerversion {
+			return packed, getErr
+		}
+		return old(fd, level, opt)
+	}
+
+	c := newSRTConn(&netFD{net: "srt"})
+	for _, tt := range peerVersionTests {
+		packed = tt.packed
+		major, minor, patch, err := c.PeerVersion()
+		if err != nil || major != tt.major || minor != tt.minor || patch != tt.patch {
+			t.Errorf("%#06x: got %d.%d.%d, %v; want %d.%d.%d", tt.packed, major, minor, patch, err, tt.major, tt.minor, tt.patch)
+		}
+		if s := versionString(uint32(tt.packed)); s != tt.str {
+			t.Errorf("%#06x: got %q; want %q", tt.packed, s, tt.str)
+		}
+		if ok, err := c.PeerVersionAtLeast(1, 4, 0); err != nil || ok != tt.atLeast140 {
+			t.Errorf("%#06x: PeerVersionAtLeast(1, 4, 0) = %v, %v; want %v", tt.packed, ok, err, tt.atLeast140)
+		}
+	}
+
+	getErr = srtapi.ENOCONN
+	if _, _, _, err := c.PeerVersion(); !errors.Is(err, srtapi.ENOCONN) {
+		t.Errorf("got %v; want %v", err, srtapi.ENOCONN)
+	}
+}
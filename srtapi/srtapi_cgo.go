@@ -194,7 +194,17 @@ func bind(s int, addr unsafe.Pointer, addrlen _Socklen) (err error) {
 	defer runtime.UnlockOSThread()
 	stat := C.srt_bind(C.SRTSOCKET(s), (*C.struct_sockaddr)(unsafe.Pointer(addr)), C.int(addrlen))
 	if stat == APIError {
-		err = getLastError()
+		err = getLastSysError()
+	}
+	return
+}
+
+func bindPeerOf(s int, udpsock int) (err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	stat := C.srt_bind_peerof(C.SRTSOCKET(s), C.SYSSOCKET(udpsock))
+	if stat == APIError {
+		err = getLastSysError()
 	}
 	return
 }
@@ -330,6 +340,29 @@ func read(fd int, p []byte) (n int, err error) {
 	return
 }
 
+func recvmsg2(fd int, p []byte, mc *MsgCtrl) (n int, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var _p0 unsafe.Pointer
+	if len(p) > 0 {
+		_p0 = unsafe.Pointer(&p[0])
+	} else {
+		_p0 = unsafe.Pointer(&_zero)
+	}
+	var mctrl C.SRT_MSGCTRL
+	C.srt_msgctrl_init(&mctrl)
+	r0 := C.srt_recvmsg2(C.SRTSOCKET(fd), (*C.char)(_p0), C.int(len(p)), &mctrl)
+	n = int(r0)
+	if r0 == APIError {
+		err = getLastError()
+		return
+	}
+	mc.SrcTime = int64(mctrl.srctime)
+	mc.PktSeq = int32(mctrl.pktseq)
+	mc.MsgNo = int32(mctrl.msgno)
+	return
+}
+
 func sendfile(outfd int, r io.Reader, offset *int64, count int) (written int, err error) {
 	f, ok := r.(*os.File)
 	if !ok {
@@ -364,10 +397,32 @@ func write(fd int, p []byte) (n int, err error) {
 	return
 }
 
+func getversion() uint32 {
+	return uint32(C.srt_getversion())
+}
+
+func getsockstate(fd int) int {
+	return int(C.srt_getsockstate(C.SRTSOCKET(fd)))
+}
+
+func getrejectreason(fd int) int {
+	return int(C.srt_getrejectreason(C.SRTSOCKET(fd)))
+}
+
+func rejectreasonstr(reason int) string {
+	return C.GoString(C.srt_rejectreason_str(C.int(reason)))
+}
+
 func getlasterror() int {
 	return int(C.srt_getlasterror(nil))
 }
 
+func getlasterrorErrno() (code int, errno int) {
+	var e C.int
+	code = int(C.srt_getlasterror(&e))
+	return code, int(e)
+}
+
 func strerror(code int, errnoval int) string {
 	return C.GoString(C.srt_strerror(C.int(code), C.int(errnoval)))
 }
@@ -440,3 +495,174 @@ func GetStats(fd int, clear bool) map[string]interface{} {
 
 	return output
 }
+
+// TraceBStats holds the performance counters of a socket, as in
+// SRT_TRACEBSTATS. Fields ending in Total count since the connection
+// was established, the others since the counters were last cleared.
+type TraceBStats struct {
+	MsTimeStamp             int64
+	PktSentTotal            int64
+	PktRecvTotal            int64
+	PktSndLossTotal         int
+	PktRcvLossTotal         int
+	PktRetransTotal         int
+	PktSentACKTotal         int
+	PktRecvACKTotal         int
+	PktSentNAKTotal         int
+	PktRecvNAKTotal         int
+	UsSndDurationTotal      int64
+	PktSndDropTotal         int
+	PktRcvDropTotal         int
+	PktRcvUndecryptTotal    int
+	ByteSentTotal           uint64
+	ByteRecvTotal           uint64
+	ByteRcvLossTotal        uint64
+	ByteRetransTotal        uint64
+	ByteSndDropTotal        uint64
+	ByteRcvDropTotal        uint64
+	ByteRcvUndecryptTotal   uint64
+	PktSent                 int64
+	PktRecv                 int64
+	PktSndLoss              int
+	PktRcvLoss              int
+	PktRetrans              int
+	PktRcvRetrans           int
+	PktSentACK              int
+	PktRecvACK              int
+	PktSentNAK              int
+	PktRecvNAK              int
+	MbpsSendRate            float64
+	MbpsRecvRate            float64
+	UsSndDuration           int64
+	PktReorderDistance      int
+	PktRcvAvgBelatedTime    float64
+	PktRcvBelated           int64
+	PktSndDrop              int
+	PktRcvDrop              int
+	PktRcvUndecrypt         int
+	ByteSent                uint64
+	ByteRecv                uint64
+	ByteRcvLoss             uint64
+	ByteRetrans             uint64
+	ByteSndDrop             uint64
+	ByteRcvDrop             uint64
+	ByteRcvUndecrypt        uint64
+	UsPktSndPeriod          float64
+	PktFlowWindow           int
+	PktCongestionWindow     int
+	PktFlightSize           int
+	MsRTT                   float64
+	MbpsBandwidth           float64
+	ByteAvailSndBuf         int
+	ByteAvailRcvBuf         int
+	MbpsMaxBW               float64
+	ByteMSS                 int
+	PktSndBuf               int
+	ByteSndBuf              int
+	MsSndBuf                int
+	MsSndTsbPdDelay         int
+	PktRcvBuf               int
+	ByteRcvBuf              int
+	MsRcvBuf                int
+	MsRcvTsbPdDelay         int
+	PktSndFilterExtraTotal  int
+	PktRcvFilterExtraTotal  int
+	PktRcvFilterSupplyTotal int
+	PktRcvFilterLossTotal   int
+	PktSndFilterExtra       int
+	PktRcvFilterExtra       int
+	PktRcvFilterSupply      int
+	PktRcvFilterLoss        int
+}
+
+// Bistats call srt_bistats
+func Bistats(fd int, clear, instantaneous bool) (*TraceBStats, error) {
+	var mon C.SRT_TRACEBSTATS
+	var cclear, cinstantaneous C.int
+	if clear {
+		cclear = 1
+	}
+	if instantaneous {
+		cinstantaneous = 1
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if C.srt_bistats(C.SRTSOCKET(fd), &mon, cclear, cinstantaneous) == APIError {
+		return nil, getLastError()
+	}
+	return &TraceBStats{
+		MsTimeStamp:             int64(mon.msTimeStamp),
+		PktSentTotal:            int64(mon.pktSentTotal),
+		PktRecvTotal:            int64(mon.pktRecvTotal),
+		PktSndLossTotal:         int(mon.pktSndLossTotal),
+		PktRcvLossTotal:         int(mon.pktRcvLossTotal),
+		PktRetransTotal:         int(mon.pktRetransTotal),
+		PktSentACKTotal:         int(mon.pktSentACKTotal),
+		PktRecvACKTotal:         int(mon.pktRecvACKTotal),
+		PktSentNAKTotal:         int(mon.pktSentNAKTotal),
+		PktRecvNAKTotal:         int(mon.pktRecvNAKTotal),
+		UsSndDurationTotal:      int64(mon.usSndDurationTotal),
+		PktSndDropTotal:         int(mon.pktSndDropTotal),
+		PktRcvDropTotal:         int(mon.pktRcvDropTotal),
+		PktRcvUndecryptTotal:    int(mon.pktRcvUndecryptTotal),
+		ByteSentTotal:           uint64(mon.byteSentTotal),
+		ByteRecvTotal:           uint64(mon.byteRecvTotal),
+		ByteRcvLossTotal:        uint64(mon.byteRcvLossTotal),
+		ByteRetransTotal:        uint64(mon.byteRetransTotal),
+		ByteSndDropTotal:        uint64(mon.byteSndDropTotal),
+		ByteRcvDropTotal:        uint64(mon.byteRcvDropTotal),
+		ByteRcvUndecryptTotal:   uint64(mon.byteRcvUndecryptTotal),
+		PktSent:                 int64(mon.pktSent),
+		PktRecv:                 int64(mon.pktRecv),
+		PktSndLoss:              int(mon.pktSndLoss),
+		PktRcvLoss:              int(mon.pktRcvLoss),
+		PktRetrans:              int(mon.pktRetrans),
+		PktRcvRetrans:           int(mon.pktRcvRetrans),
+		PktSentACK:              int(mon.pktSentACK),
+		PktRecvACK:              int(mon.pktRecvACK),
+		PktSentNAK:              int(mon.pktSentNAK),
+		PktRecvNAK:              int(mon.pktRecvNAK),
+		MbpsSendRate:            float64(mon.mbpsSendRate),
+		MbpsRecvRate:            float64(mon.mbpsRecvRate),
+		UsSndDuration:           int64(mon.usSndDuration),
+		PktReorderDistance:      int(mon.pktReorderDistance),
+		PktRcvAvgBelatedTime:    float64(mon.pktRcvAvgBelatedTime),
+		PktRcvBelated:           int64(mon.pktRcvBelated),
+		PktSndDrop:              int(mon.pktSndDrop),
+		PktRcvDrop:              int(mon.pktRcvDrop),
+		PktRcvUndecrypt:         int(mon.pktRcvUndecrypt),
+		ByteSent:                uint64(mon.byteSent),
+		ByteRecv:                uint64(mon.byteRecv),
+		ByteRcvLoss:             uint64(mon.byteRcvLoss),
+		ByteRetrans:             uint64(mon.byteRetrans),
+		ByteSndDrop:             uint64(mon.byteSndDrop),
+		ByteRcvDrop:             uint64(mon.byteRcvDrop),
+		ByteRcvUndecrypt:        uint64(mon.byteRcvUndecrypt),
+		UsPktSndPeriod:          float64(mon.usPktSndPeriod),
+		PktFlowWindow:           int(mon.pktFlowWindow),
+		PktCongestionWindow:     int(mon.pktCongestionWindow),
+		PktFlightSize:           int(mon.pktFlightSize),
+		MsRTT:                   float64(mon.msRTT),
+		MbpsBandwidth:           float64(mon.mbpsBandwidth),
+		ByteAvailSndBuf:         int(mon.byteAvailSndBuf),
+		ByteAvailRcvBuf:         int(mon.byteAvailRcvBuf),
+		MbpsMaxBW:               float64(mon.mbpsMaxBW),
+		ByteMSS:                 int(mon.byteMSS),
+		PktSndBuf:               int(mon.pktSndBuf),
+		ByteSndBuf:              int(mon.byteSndBuf),
+		MsSndBuf:                int(mon.msSndBuf),
+		MsSndTsbPdDelay:         int(mon.msSndTsbPdDelay),
+		PktRcvBuf:               int(mon.pktRcvBuf),
+		ByteRcvBuf:              int(mon.byteRcvBuf),
+		MsRcvBuf:                int(mon.msRcvBuf),
+		MsRcvTsbPdDelay:         int(mon.msRcvTsbPdDelay),
+		PktSndFilterExtraTotal:  int(mon.pktSndFilterExtraTotal),
+		PktRcvFilterExtraTotal:  int(mon.pktRcvFilterExtraTotal),
+		PktRcvFilterSupplyTotal: int(mon.pktRcvFilterSupplyTotal),
+		PktRcvFilterLossTotal:   int(mon.pktRcvFilterLossTotal),
+		PktSndFilterExtra:       int(mon.pktSndFilterExtra),
+		PktRcvFilterExtra:       int(mon.pktRcvFilterExtra),
+		PktRcvFilterSupply:      int(mon.pktRcvFilterSupply),
+		PktRcvFilterLoss:        int(mon.pktRcvFilterLoss),
+	}, nil
+}
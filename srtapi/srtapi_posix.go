@@ -31,13 +31,61 @@ func (e Errno) Timeout() bool {
 	return e == EASYNCFAIL || e == EASYNCSND || e == EASYNCRCV || e == ETIMEOUT || e == ECONGEST
 }
 
+// A SysError is a socket failure, ESOCKFAIL, along with the system
+// error behind it, such as syscall.EADDRINUSE for a bind to a port in
+// use. errors.Is matches it with either.
+type SysError struct {
+	Errno syscall.Errno
+}
+
+func (e *SysError) Error() string {
+	return strerror(int(ESOCKFAIL), int(e.Errno))
+}
+
+// Unwrap returns the system error.
+func (e *SysError) Unwrap() error { return e.Errno }
+
+// Is reports whether target is ESOCKFAIL.
+func (e *SysError) Is(target error) bool { return target == ESOCKFAIL }
+
 // Read call srt_recv
+//
+// p is handed to libsrt as is, without an intermediate C buffer: srt_recv
+// copies the payload out of its receive buffer into p before returning
+// and keeps no reference to it afterwards, as the cgo pointer passing
+// rules require. Read therefore does not allocate.
 func Read(fd int, p []byte) (n int, err error) {
 	n, err = read(fd, p)
 	return
 }
 
+// MsgCtrl holds the control data of a message, as in SRT_MSGCTRL.
+type MsgCtrl struct {
+	// SrcTime is the time the message was sent, in microseconds on
+	// the libsrt clock. Received messages carry it translated to
+	// the local clock by TSBPD.
+	SrcTime int64
+
+	// PktSeq is the sequence number of the first packet of the
+	// message.
+	PktSeq int32
+
+	// MsgNo is the message number.
+	MsgNo int32
+}
+
+// ReadMsg2 call srt_recvmsg2
+//
+// Like Read, p is passed to libsrt directly.
+func ReadMsg2(fd int, p []byte, mc *MsgCtrl) (n int, err error) {
+	n, err = recvmsg2(fd, p, mc)
+	return
+}
+
 // Write call srt_send
+//
+// Like Read, p is passed to libsrt directly; srt_send copies it into the
+// send buffer before returning.
 func Write(fd int, p []byte) (n int, err error) {
 	n, err = write(fd, p)
 	return
@@ -52,6 +100,35 @@ func Bind(fd int, sa syscall.Sockaddr) (err error) {
 	return bind(fd, ptr, n)
 }
 
+// BindPeerOf call srt_bind_peerof
+//
+// The SRT socket is bound to the address the UDP socket udpsock is
+// bound to, and sends and receives through it. libsrt takes it over
+// and closes it along with the SRT socket.
+func BindPeerOf(fd int, udpsock int) (err error) {
+	return bindPeerOf(fd, udpsock)
+}
+
+// GetVersion call srt_getversion
+func GetVersion() uint32 {
+	return getversion()
+}
+
+// GetSockState call srt_getsockstate
+func GetSockState(fd int) int {
+	return getsockstate(fd)
+}
+
+// GetRejectReason call srt_getrejectreason
+func GetRejectReason(fd int) int {
+	return getrejectreason(fd)
+}
+
+// RejectReasonStr call srt_rejectreason_str
+func RejectReasonStr(reason int) string {
+	return rejectreasonstr(reason)
+}
+
 // Connect call srt_connect
 func Connect(fd int, sa syscall.Sockaddr) (err error) {
 	ptr, n, err := sockaddr(sa)
@@ -79,6 +156,13 @@ func GetsockoptInt(fd, level, opt int) (value int, err error) {
 	return int(n), err
 }
 
+// GetsockoptInt64 call srt_getsockopt
+func GetsockoptInt64(fd, level, opt int) (value int64, err error) {
+	vallen := _Socklen(8)
+	err = getsockopt(fd, level, opt, unsafe.Pointer(&value), &vallen)
+	return value, err
+}
+
 // GetsockoptString returns the string value of the socket option opt for the
 // socket associated with fd at the given socket level.
 func GetsockoptString(fd, level, opt int) (string, error) {
@@ -282,6 +366,36 @@ func anyToSockaddr(rsa *syscall.RawSockaddrAny) (syscall.Sockaddr, error) {
 	return nil, syscall.EAFNOSUPPORT
 }
 
+// Do the interface allocations only once for common
+// Errno values.
+var (
+	errEASYNCRCV error = EASYNCRCV
+	errEASYNCSND error = EASYNCSND
+)
+
+// errnoErr returns common boxed Errno values, to prevent
+// allocations at runtime.
+func errnoErr(e Errno) error {
+	switch e {
+	case EASYNCRCV:
+		return errEASYNCRCV
+	case EASYNCSND:
+		return errEASYNCSND
+	}
+	return e
+}
+
 func getLastError() error {
-	return Errno(getlasterror())
+	return errnoErr(Errno(getlasterror()))
+}
+
+// getLastSysError returns the last error like getLastError, except that
+// a socket failure comes back as a *SysError, with the system error
+// behind it, if any.
+func getLastSysError() error {
+	code, errno := getlasterrorErrno()
+	if Errno(code) == ESOCKFAIL && errno != 0 {
+		return &SysError{Errno: syscall.Errno(errno)}
+	}
+	return errnoErr(Errno(code))
 }
@@ -101,6 +101,45 @@ const (
 	OptionIpv60only     = C.SRTO_IPV6ONLY
 	OptionPeeridletimeo = C.SRTO_PEERIDLETIMEO
 	OptionPacketfilter = C.SRTO_PACKETFILTER
+	// SRTO_DRIFTTRACER, added in libsrt 1.4.2, which the 1.4.1
+	// headers lack.
+	OptionDrifttracer = 37
+	// SRTO_RETRANSMITALGO, added in libsrt 1.4.2, which the 1.4.1
+	// headers lack.
+	OptionRetransmitalgo = 61
+	// SRTO_CRYPTOMODE, added in libsrt 1.5.2.
+	OptionCryptomode = 62
+)
+
+// SRT key material state
+const (
+	KMStateUnsecured = C.SRT_KM_S_UNSECURED
+	KMStateSecuring  = C.SRT_KM_S_SECURING
+	KMStateSecured   = C.SRT_KM_S_SECURED
+	KMStateNosecret  = C.SRT_KM_S_NOSECRET
+	KMStateBadsecret = C.SRT_KM_S_BADSECRET
+)
+
+// SRT reject reasons
+const (
+	RejectUnknown    = C.SRT_REJ_UNKNOWN
+	RejectSystem     = C.SRT_REJ_SYSTEM
+	RejectPeer       = C.SRT_REJ_PEER
+	RejectResource   = C.SRT_REJ_RESOURCE
+	RejectRogue      = C.SRT_REJ_ROGUE
+	RejectBacklog    = C.SRT_REJ_BACKLOG
+	RejectIPE        = C.SRT_REJ_IPE
+	RejectClose      = C.SRT_REJ_CLOSE
+	RejectVersion    = C.SRT_REJ_VERSION
+	RejectRdvCookie  = C.SRT_REJ_RDVCOOKIE
+	RejectBadSecret  = C.SRT_REJ_BADSECRET
+	RejectUnsecure   = C.SRT_REJ_UNSECURE
+	RejectMessageAPI = C.SRT_REJ_MESSAGEAPI
+	RejectCongestion = C.SRT_REJ_CONGESTION
+	RejectFilter     = C.SRT_REJ_FILTER
+	// SRT_REJ_CRYPTO, added in libsrt 1.5.2, which the 1.4.1 headers
+	// lack.
+	RejectCrypto = 17
 )
 
 // SRT trans type